@@ -1,32 +1,254 @@
 package server
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/rbehzadan/flexstore/internal/app"
+	"github.com/rbehzadan/flexstore/internal/certs"
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/logging"
 	"github.com/rbehzadan/flexstore/pkg/config"
 )
 
-// Run starts the server with the provided configuration
-func Run(cfg *config.Config) {
+// Run starts the server with the provided configuration and serves until SIGINT or SIGTERM.
+// It then stops accepting connections, waits for requests in flight, stops the background
+// workers and closes the database, all within the shutdown timeout. It returns the exit code.
+func Run(cfg *config.Config) int {
+	setupLogging(cfg)
+
+	// Client certificates are only presented over TLS
+	useTLS := cfg.TLSCertFile != "" || cfg.TLSKeyFile != ""
+	if !useTLS && cfg.TLSClientCAFile != "" {
+		slog.Error("Client certificates need TLS; set -tls-cert and -tls-key")
+		return 1
+	}
+
 	app, err := app.NewApp(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize application: %v", err)
+		slog.Error("Failed to initialize application", "error", err)
+		return 1
 	}
 
 	// Create server with reasonable timeouts
 	server := &http.Server{
 		Addr:         cfg.Addr,
-		Handler:      app.Router,
+		Handler:      app.Handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Load the certificate and keep it up to date
+	if useTLS {
+		reloader, err := newCertReloader(cfg)
+		if err != nil {
+			slog.Error("Failed to initialize TLS", "error", err)
+			app.Close()
+			return 1
+		}
+		server.TLSConfig = reloader.TLSConfig()
+		watchCertificates(reloader, cfg.TLSReloadInterval)
+	}
+
+	app.Start()
+
 	// Start server
-	fmt.Printf("Starting FlexStore API Server v%s on %s\n", cfg.Version, cfg.Addr)
-	log.Fatal(server.ListenAndServe())
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting FlexStore API Server", "version", cfg.Version, "addr", cfg.Addr, "tls", useTLS)
+		if useTLS {
+			serveErr <- server.ListenAndServeTLS("", "")
+		} else {
+			serveErr <- server.ListenAndServe()
+		}
+	}()
+
+	// Serve until signalled or the listener fails
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	// The whole shutdown shares one budget
+	shutdownCtx := context.Background()
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		slog.Error("Server stopped", "error", err)
+		exitCode = 1
+	case <-signals.Done():
+		// A second signal terminates the process immediately
+		stopSignals()
+		slog.Info("Shutting down", "timeout", cfg.ShutdownTimeout)
+
+		if cfg.ShutdownTimeout > 0 {
+			var cancel context.CancelFunc
+			shutdownCtx, cancel = context.WithTimeout(shutdownCtx, cfg.ShutdownTimeout)
+			defer cancel()
+		}
+		if err := shutdown(shutdownCtx, server); err != nil {
+			slog.Error("Failed to drain requests", "error", err)
+			exitCode = 1
+		}
+	}
+
+	if err := app.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Failed to shut down cleanly", "error", err)
+		exitCode = 1
+	}
+	if exitCode == 0 {
+		slog.Info("Server stopped")
+	}
+	return exitCode
+}
+
+// shutdown stops accepting connections and waits until ctx is done for requests in flight,
+// closing the connections that are still active after it
+func shutdown(ctx context.Context, server *http.Server) error {
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+		return err
+	}
+	return nil
+}
+
+// setupLogging makes the logger described by the configuration the default, which the
+// standard log package writes through as well
+func setupLogging(cfg *config.Config) {
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logging: %v\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+}
+
+// newCertReloader loads the TLS files named by the configuration
+func newCertReloader(cfg *config.Config) (*certs.Reloader, error) {
+	minVersion, err := certs.ParseTLSVersion(cfg.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	return certs.NewReloader(certs.Config{
+		CertFile:          cfg.TLSCertFile,
+		KeyFile:           cfg.TLSKeyFile,
+		ClientCAFile:      cfg.TLSClientCAFile,
+		RequireClientCert: cfg.TLSRequireClientCert,
+		MinVersion:        minVersion,
+	})
+}
+
+// watchCertificates reloads the TLS files on SIGHUP and, with a positive interval, whenever
+// they change. A failed reload is logged and the previous certificate stays in use.
+func watchCertificates(reloader *certs.Reloader, interval time.Duration) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	var changes <-chan time.Time
+	if interval > 0 {
+		changes = time.NewTicker(interval).C
+	}
+
+	go func() {
+		for {
+			select {
+			case <-hangup:
+				if err := reloader.Reload(); err != nil {
+					slog.Error("Failed to reload TLS certificate", "error", err)
+				} else {
+					slog.Info("Reloaded TLS certificate")
+				}
+			case <-changes:
+				if changed, err := reloader.ReloadIfChanged(); err != nil {
+					slog.Error("Failed to reload TLS certificate", "error", err)
+				} else if changed {
+					slog.Info("Reloaded changed TLS certificate")
+				}
+			}
+		}
+	}()
+}
+
+// VerifyAudit checks the hash chain of the audit log, prints the result and returns the exit code
+func VerifyAudit(cfg *config.Config) int {
+	setupLogging(cfg)
+
+	database, err := db.New(app.DatabaseConfig(cfg))
+	if err != nil {
+		slog.Error("Failed to open database", "error", err)
+		return 2
+	}
+	defer database.Close()
+
+	result, err := db.NewAuditRepository(database).Verify(context.Background())
+	if err != nil {
+		slog.Error("Failed to verify audit log", "error", err)
+		return 2
+	}
+
+	if !result.Valid {
+		fmt.Printf("Audit log is INVALID at entry %d: %s (%d entries verified before it)\n", result.BadSeq, result.Error, result.Entries)
+		return 1
+	}
+	fmt.Printf("Audit log is valid: %d entries, last entry %d\n", result.Entries, result.LastSeq)
+	return 0
+}
+
+// Migrate shows the status of the schema migrations or migrates the database up to the latest
+// version or down by steps migrations, and returns the exit code
+func Migrate(cfg *config.Config, action string, steps int) int {
+	setupLogging(cfg)
+
+	database, err := db.Open(app.DatabaseConfig(cfg))
+	if err != nil {
+		slog.Error("Failed to open database", "error", err)
+		return 2
+	}
+	defer database.Close()
+
+	switch action {
+	case "up":
+		applied, err := database.Migrate()
+		if err != nil {
+			slog.Error("Failed to migrate database", "error", err)
+			return 1
+		}
+		fmt.Printf("Applied %d migrations\n", applied)
+	case "down":
+		reverted, err := database.MigrateDown(steps)
+		if err != nil {
+			slog.Error("Failed to migrate database", "error", err)
+			return 1
+		}
+		fmt.Printf("Reverted %d migrations\n", reverted)
+	}
+
+	status, err := database.MigrationStatus()
+	if err != nil {
+		slog.Error("Failed to get migration status", "error", err)
+		return 2
+	}
+	unknown := false
+	for _, state := range status {
+		applied := "pending"
+		if state.AppliedAt != nil {
+			applied = "applied " + state.AppliedAt.Format(time.RFC3339)
+		}
+		if state.Unknown {
+			applied += " (unknown to this version)"
+			unknown = true
+		}
+		fmt.Printf("%04d %-30s %s\n", state.Version, state.Name, applied)
+	}
+
+	// A database of a newer version cannot be served by this one
+	if unknown {
+		return 1
+	}
+	return 0
 }
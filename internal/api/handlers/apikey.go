@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/service"
+)
+
+// APIKeyHandlers contains handlers for API key management
+type APIKeyHandlers struct {
+	apiKeyService *service.APIKeyService
+}
+
+// NewAPIKeyHandlers creates new API key handlers
+func NewAPIKeyHandlers(apiKeyService *service.APIKeyService) *APIKeyHandlers {
+	return &APIKeyHandlers{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey issues a new API key
+func (h *APIKeyHandlers) CreateAPIKey() http.HandlerFunc {
+	type request struct {
+		Name        string     `json:"name"`
+		Scopes      []string   `json:"scopes"`
+		Collections []string   `json:"collections"`
+		ExpiresAt   *time.Time `json:"expires_at"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse request body
+		var req request
+		if !decodeBody(w, r, &req, "INVALID_REQUEST", "Invalid request body") {
+			return
+		}
+
+		// Create key
+		key, err := h.apiKeyService.Create(r.Context(), req.Name, req.Scopes, req.Collections, req.ExpiresAt)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "CREATE_API_KEY_ERROR", err.Error())
+			return
+		}
+
+		// Respond with the secret, which is not shown again
+		api.RespondWithJSON(w, http.StatusCreated, key)
+	}
+}
+
+// ListAPIKeys lists all API keys
+func (h *APIKeyHandlers) ListAPIKeys() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get keys
+		keys, err := h.apiKeyService.List(r.Context())
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "LIST_API_KEYS_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, keys)
+	}
+}
+
+// GetAPIKey gets an API key by ID
+func (h *APIKeyHandlers) GetAPIKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get key ID from URL
+		id := mux.Vars(r)["id"]
+
+		// Get key
+		key, err := h.apiKeyService.GetByID(r.Context(), id)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "API_KEY_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, key)
+	}
+}
+
+// RevokeAPIKey revokes an API key
+func (h *APIKeyHandlers) RevokeAPIKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get key ID from URL
+		id := mux.Vars(r)["id"]
+
+		// Revoke key
+		key, err := h.apiKeyService.Revoke(r.Context(), id)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "API_KEY_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, key)
+	}
+}
+
+// RotateAPIKey replaces the secret of an API key
+func (h *APIKeyHandlers) RotateAPIKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get key ID from URL
+		id := mux.Vars(r)["id"]
+
+		// Rotate key
+		key, err := h.apiKeyService.Rotate(r.Context(), id)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "API_KEY_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond with the new secret, which is not shown again
+		api.RespondWithJSON(w, http.StatusOK, key)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/models"
+	"github.com/rbehzadan/flexstore/internal/service"
+)
+
+// AuditHandlers contains handlers for audit log operations
+type AuditHandlers struct {
+	auditService *service.AuditService
+}
+
+// NewAuditHandlers creates new audit handlers
+func NewAuditHandlers(auditService *service.AuditService) *AuditHandlers {
+	return &AuditHandlers{
+		auditService: auditService,
+	}
+}
+
+// ListEntries lists audit log entries, newest first
+func (h *AuditHandlers) ListEntries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse query parameters
+		query, err := parseAuditQuery(r)
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+			return
+		}
+
+		// Get limit parameter
+		limitStr := r.URL.Query().Get("limit")
+		if limitStr != "" {
+			limit, err := strconv.Atoi(limitStr)
+			if err == nil && limit > 0 {
+				query.Limit = limit
+			}
+		}
+
+		// Get offset parameter
+		offsetStr := r.URL.Query().Get("offset")
+		if offsetStr != "" {
+			offset, err := strconv.Atoi(offsetStr)
+			if err == nil && offset >= 0 {
+				query.Offset = offset
+			}
+		}
+
+		// Get entries
+		entries, err := h.auditService.List(r.Context(), query)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "LIST_AUDIT_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, entries)
+	}
+}
+
+// ExportEntries streams every matching audit log entry as JSON Lines, oldest first
+func (h *AuditHandlers) ExportEntries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse query parameters
+		query, err := parseAuditQuery(r)
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+			return
+		}
+
+		// Stream entries; once the response has started an error can only be logged
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+		encoder := json.NewEncoder(w)
+		err = h.auditService.Export(r.Context(), query, func(entries []models.AuditEntry) error {
+			if err := extendWriteDeadline(w); err != nil {
+				return err
+			}
+			for i := range entries {
+				if err := encoder.Encode(&entries[i]); err != nil {
+					return err
+				}
+			}
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to export audit log", "error", err)
+		}
+	}
+}
+
+// parseAuditQuery reads the audit log filters from the query string
+func parseAuditQuery(r *http.Request) (*models.AuditQuery, error) {
+	values := r.URL.Query()
+	query := models.NewAuditQuery()
+	query.Principal = values.Get("principal")
+	query.Operation = values.Get("op")
+	query.CollectionName = values.Get("collection")
+	query.DocumentID = values.Get("document_id")
+
+	for name, target := range map[string]**time.Time{"since": &query.Since, "until": &query.Until} {
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an RFC 3339 timestamp such as 2026-09-01T00:00:00Z", name)
+		}
+		*target = &t
+	}
+
+	return query, nil
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// authorize checks that the caller may perform an action on a collection and responds
+// with 403 Forbidden if not
+func authorize(w http.ResponseWriter, r *http.Request, collection string, permission models.Permission) bool {
+	if canAccess(r, collection, permission) {
+		return true
+	}
+	message := fmt.Sprintf("Permission '%s' on collection '%s' is required", permission, collection)
+	api.RespondWithError(w, http.StatusForbidden, "FORBIDDEN", message)
+	return false
+}
+
+// canAccess reports whether the caller may perform an action on a collection. Callers
+// without a principal may only when authentication is disabled or the auth policy let them
+// through anonymously on this route.
+func canAccess(r *http.Request, collection string, permission models.Permission) bool {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return principal.Can(collection, permission)
+	}
+	return auth.AnonymousFrom(r.Context()) != auth.NotAnonymous
+}
+
+// documentAccess returns the document access of the caller in a collection, or nil when
+// authentication is disabled. Anonymous callers own nothing and are never elevated.
+func documentAccess(r *http.Request, collection string) *models.DocumentAccess {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return &models.DocumentAccess{Subject: principal.Subject(), Elevated: principal.IsCollectionAdmin(collection)}
+	}
+	if auth.AnonymousFrom(r.Context()) == auth.AnonymousOpen {
+		return nil
+	}
+	return &models.DocumentAccess{}
+}
+
+// authorizeAllDocuments checks that the caller may act on every document of a collection,
+// which needs an elevated role when the collection has ownership enabled, and responds with
+// 403 Forbidden if not. A missing collection is left for the operation itself to report.
+func authorizeAllDocuments(w http.ResponseWriter, r *http.Request, collection string, restrict func(context.Context, string, *models.DocumentAccess) (*models.DocumentAccess, error)) bool {
+	access, err := restrict(r.Context(), collection, documentAccess(r, collection))
+	if err != nil || access == nil {
+		return true
+	}
+	message := fmt.Sprintf("Permission '%s' on collection '%s' is required, as its documents have owners", models.PermissionAdmin, collection)
+	api.RespondWithError(w, http.StatusForbidden, "FORBIDDEN", message)
+	return false
+}
@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/models"
 	"github.com/rbehzadan/flexstore/internal/service"
 )
 
@@ -24,14 +24,14 @@ func NewCollectionHandlers(collectionService *service.CollectionService) *Collec
 // CreateCollection creates a new collection
 func (h *CollectionHandlers) CreateCollection() http.HandlerFunc {
 	type request struct {
-		Name string `json:"name"`
+		Name    string                   `json:"name"`
+		Options models.CollectionOptions `json:"options"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Parse request body
 		var req request
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			api.RespondWithError(w, http.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+		if !decodeBody(w, r, &req, "INVALID_REQUEST", "Invalid request body") {
 			return
 		}
 
@@ -41,8 +41,22 @@ func (h *CollectionHandlers) CreateCollection() http.HandlerFunc {
 			return
 		}
 
+		// Check permission
+		if !authorize(w, r, req.Name, models.PermissionWrite) {
+			return
+		}
+
+		// Validate options
+		if err := req.Options.Validate(); err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_OPTIONS", err.Error())
+			return
+		}
+
 		// Create collection
-		collection, err := h.collectionService.Create(req.Name)
+		collection, err := h.collectionService.Create(r.Context(), req.Name, req.Options, requestActor(r))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusInternalServerError, "CREATE_COLLECTION_ERROR", err.Error())
 			return
@@ -60,8 +74,16 @@ func (h *CollectionHandlers) GetCollection() http.HandlerFunc {
 		vars := mux.Vars(r)
 		name := vars["name"]
 
+		// Check permission
+		if !authorize(w, r, name, models.PermissionRead) {
+			return
+		}
+
 		// Get collection
-		collection, err := h.collectionService.GetByName(name)
+		collection, err := h.collectionService.GetByName(r.Context(), name)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusNotFound, "COLLECTION_NOT_FOUND", err.Error())
 			return
@@ -72,6 +94,55 @@ func (h *CollectionHandlers) GetCollection() http.HandlerFunc {
 	}
 }
 
+// UpdateCollectionOptions replaces the options of a collection
+func (h *CollectionHandlers) UpdateCollectionOptions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name from URL
+		vars := mux.Vars(r)
+		name := vars["name"]
+
+		// Check permission
+		if !authorize(w, r, name, models.PermissionAdmin) {
+			return
+		}
+
+		// Parse request body
+		var options models.CollectionOptions
+		if !decodeBody(w, r, &options, "INVALID_REQUEST", "Invalid request body") {
+			return
+		}
+
+		// Validate options
+		if err := options.Validate(); err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_OPTIONS", err.Error())
+			return
+		}
+
+		// Check if collection exists
+		_, err := h.collectionService.GetByName(r.Context(), name)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "COLLECTION_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Update options
+		collection, err := h.collectionService.UpdateOptions(r.Context(), name, options, requestActor(r))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "UPDATE_COLLECTION_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, collection)
+	}
+}
+
 // DeleteCollection deletes a collection
 func (h *CollectionHandlers) DeleteCollection() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -79,8 +150,17 @@ func (h *CollectionHandlers) DeleteCollection() http.HandlerFunc {
 		vars := mux.Vars(r)
 		name := vars["name"]
 
+		// Check permission
+		if !authorize(w, r, name, models.PermissionDelete) ||
+			!authorizeAllDocuments(w, r, name, h.collectionService.RestrictAccess) {
+			return
+		}
+
 		// Delete collection
-		err := h.collectionService.Delete(name)
+		err := h.collectionService.Delete(r.Context(), name, requestActor(r))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusNotFound, "COLLECTION_NOT_FOUND", err.Error())
 			return
@@ -91,16 +171,29 @@ func (h *CollectionHandlers) DeleteCollection() http.HandlerFunc {
 	}
 }
 
-// ListCollections lists all collections
+// ListCollections lists the collections the caller may read
 func (h *CollectionHandlers) ListCollections() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get collections
-		collections, err := h.collectionService.List()
+		collections, err := h.collectionService.List(r.Context())
+		if api.RespondWithContextError(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusInternalServerError, "LIST_COLLECTIONS_ERROR", err.Error())
 			return
 		}
 
+		// Hide collections the caller may not read
+		visible := collections.Collections[:0]
+		for _, collection := range collections.Collections {
+			if canAccess(r, collection.Name, models.PermissionRead) {
+				visible = append(visible, collection)
+			}
+		}
+		collections.Collections = visible
+		collections.Total = len(visible)
+
 		// Respond
 		api.RespondWithJSON(w, http.StatusOK, collections)
 	}
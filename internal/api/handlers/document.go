@@ -2,11 +2,18 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/metrics"
 	"github.com/rbehzadan/flexstore/internal/models"
 	"github.com/rbehzadan/flexstore/internal/service"
 )
@@ -14,12 +21,15 @@ import (
 // DocumentHandlers contains handlers for document operations
 type DocumentHandlers struct {
 	documentService *service.DocumentService
+	bulkDocuments   *metrics.CounterVec
 }
 
-// NewDocumentHandlers creates new document handlers
-func NewDocumentHandlers(documentService *service.DocumentService) *DocumentHandlers {
+// NewDocumentHandlers creates new document handlers. Documents inserted by bulk inserts and
+// uploads are counted per collection in bulkDocuments.
+func NewDocumentHandlers(documentService *service.DocumentService, bulkDocuments *metrics.CounterVec) *DocumentHandlers {
 	return &DocumentHandlers{
 		documentService: documentService,
+		bulkDocuments:   bulkDocuments,
 	}
 }
 
@@ -30,15 +40,34 @@ func (h *DocumentHandlers) CreateDocument() http.HandlerFunc {
 		vars := mux.Vars(r)
 		collectionName := vars["name"]
 
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionWrite) {
+			return
+		}
+
+		// Limit the request body
+		if !h.limitBody(w, r, collectionName) {
+			return
+		}
+
 		// Read request body
 		var data json.RawMessage
-		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			api.RespondWithError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON data")
+		if !decodeBody(w, r, &data, "INVALID_JSON", "Invalid JSON data") {
+			return
+		}
+
+		// Get expiry parameters
+		expiresAt, err := parseExpiry(r)
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_EXPIRY", err.Error())
 			return
 		}
 
 		// Create document
-		document, err := h.documentService.Create(collectionName, data)
+		document, err := h.documentService.Create(r.Context(), collectionName, data, expiresAt, documentAccess(r, collectionName), requestActor(r))
+		if respondWriteError(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusInternalServerError, "CREATE_DOCUMENT_ERROR", err.Error())
 			return
@@ -57,8 +86,37 @@ func (h *DocumentHandlers) GetDocument() http.HandlerFunc {
 		collectionName := vars["name"]
 		id := vars["id"]
 
-		// Get document
-		document, err := h.documentService.GetByID(id, collectionName)
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionRead) {
+			return
+		}
+
+		// Get as_of parameter
+		asOf, err := parseAsOf(r)
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_AS_OF", err.Error())
+			return
+		}
+
+		// Get document, optionally as it was at a past time
+		var document *models.Document
+		access := documentAccess(r, collectionName)
+		if asOf != nil {
+			document, err = h.documentService.GetByIDAsOf(r.Context(), id, collectionName, *asOf, access)
+		} else {
+			document, err = h.documentService.GetByID(r.Context(), id, collectionName, access)
+		}
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if errors.Is(err, models.ErrHistoryNotEnabled) {
+			api.RespondWithError(w, http.StatusBadRequest, "HISTORY_NOT_ENABLED", err.Error())
+			return
+		}
+		if errors.Is(err, models.ErrHistoryPruned) {
+			api.RespondWithError(w, http.StatusGone, "HISTORY_PRUNED", err.Error())
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusNotFound, "DOCUMENT_NOT_FOUND", err.Error())
 			return
@@ -77,15 +135,34 @@ func (h *DocumentHandlers) UpdateDocument() http.HandlerFunc {
 		collectionName := vars["name"]
 		id := vars["id"]
 
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionWrite) {
+			return
+		}
+
+		// Limit the request body
+		if !h.limitBody(w, r, collectionName) {
+			return
+		}
+
 		// Read request body
 		var data json.RawMessage
-		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-			api.RespondWithError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON data")
+		if !decodeBody(w, r, &data, "INVALID_JSON", "Invalid JSON data") {
+			return
+		}
+
+		// Get expiry parameters
+		expiresAt, err := parseExpiry(r)
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_EXPIRY", err.Error())
 			return
 		}
 
 		// Update document
-		document, err := h.documentService.Update(id, collectionName, data)
+		document, err := h.documentService.Update(r.Context(), id, collectionName, data, expiresAt, documentAccess(r, collectionName), requestActor(r))
+		if respondWriteError(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusInternalServerError, "UPDATE_DOCUMENT_ERROR", err.Error())
 			return
@@ -104,8 +181,16 @@ func (h *DocumentHandlers) DeleteDocument() http.HandlerFunc {
 		collectionName := vars["name"]
 		id := vars["id"]
 
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionDelete) {
+			return
+		}
+
 		// Delete document
-		err := h.documentService.Delete(id, collectionName)
+		err := h.documentService.Delete(r.Context(), id, collectionName, documentAccess(r, collectionName), requestActor(r))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusNotFound, "DOCUMENT_NOT_FOUND", err.Error())
 			return
@@ -123,6 +208,11 @@ func (h *DocumentHandlers) ListDocuments() http.HandlerFunc {
 		vars := mux.Vars(r)
 		collectionName := vars["name"]
 
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionRead) {
+			return
+		}
+
 		// Parse query parameters
 		query := models.NewDocumentQuery()
 
@@ -144,8 +234,28 @@ func (h *DocumentHandlers) ListDocuments() http.HandlerFunc {
 			}
 		}
 
+		// Get as_of parameter
+		asOf, err := parseAsOf(r)
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_AS_OF", err.Error())
+			return
+		}
+		query.AsOf = asOf
+		query.Access = documentAccess(r, collectionName)
+
 		// Get documents
-		documents, err := h.documentService.List(collectionName, query)
+		documents, err := h.documentService.List(r.Context(), collectionName, query)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if errors.Is(err, models.ErrHistoryNotEnabled) {
+			api.RespondWithError(w, http.StatusBadRequest, "HISTORY_NOT_ENABLED", err.Error())
+			return
+		}
+		if errors.Is(err, models.ErrHistoryPruned) {
+			api.RespondWithError(w, http.StatusGone, "HISTORY_PRUNED", err.Error())
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusInternalServerError, "LIST_DOCUMENTS_ERROR", err.Error())
 			return
@@ -163,20 +273,35 @@ func (h *DocumentHandlers) BulkCreateDocuments() http.HandlerFunc {
 		vars := mux.Vars(r)
 		collectionName := vars["name"]
 
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionWrite) {
+			return
+		}
+
+		// Limit the request body
+		if !h.limitBody(w, r, collectionName) {
+			return
+		}
+
 		// Read request body
 		var dataItems []json.RawMessage
-		if err := json.NewDecoder(r.Body).Decode(&dataItems); err != nil {
-			api.RespondWithError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid JSON array")
+		if !decodeBody(w, r, &dataItems, "INVALID_JSON", "Invalid JSON array") {
 			return
 		}
 
 		// Create documents
-		documents, err := h.documentService.BulkCreate(collectionName, dataItems)
+		documents, err := h.documentService.BulkCreate(r.Context(), collectionName, dataItems, documentAccess(r, collectionName), requestActor(r))
+		if respondWriteError(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusInternalServerError, "BULK_CREATE_ERROR", err.Error())
 			return
 		}
 
+		// Count the inserted documents
+		h.bulkDocuments.Add(float64(len(documents)), collectionName)
+
 		// Respond
 		api.RespondWithJSON(w, http.StatusCreated, map[string]interface{}{
 			"message":   "Documents created successfully",
@@ -193,8 +318,21 @@ func (h *DocumentHandlers) UploadJSONFile() http.HandlerFunc {
 		vars := mux.Vars(r)
 		collectionName := vars["name"]
 
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionWrite) {
+			return
+		}
+
+		// Limit the request body
+		if !h.limitBody(w, r, collectionName) {
+			return
+		}
+
 		// Parse form
 		err := r.ParseMultipartForm(10 << 20) // 10 MB max
+		if respondBodyTooLarge(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusBadRequest, "INVALID_FORM", "Could not parse form")
 			return
@@ -209,12 +347,18 @@ func (h *DocumentHandlers) UploadJSONFile() http.HandlerFunc {
 		defer file.Close()
 
 		// Process file
-		documents, err := h.documentService.ProcessJSONFile(collectionName, file)
+		documents, err := h.documentService.ProcessJSONFile(r.Context(), collectionName, file, documentAccess(r, collectionName), requestActor(r))
+		if respondWriteError(w, err) {
+			return
+		}
 		if err != nil {
 			api.RespondWithError(w, http.StatusInternalServerError, "PROCESS_FILE_ERROR", err.Error())
 			return
 		}
 
+		// Count the inserted documents
+		h.bulkDocuments.Add(float64(len(documents)), collectionName)
+
 		// Respond
 		api.RespondWithJSON(w, http.StatusCreated, map[string]interface{}{
 			"message":   "File processed successfully",
@@ -223,3 +367,114 @@ func (h *DocumentHandlers) UploadJSONFile() http.HandlerFunc {
 		})
 	}
 }
+
+// ShareDocument replaces the subjects a document is shared with
+func (h *DocumentHandlers) ShareDocument() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name and document ID from URL
+		vars := mux.Vars(r)
+		collectionName := vars["name"]
+		id := vars["id"]
+
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionWrite) {
+			return
+		}
+
+		// Read request body
+		var request struct {
+			SharedWith []string `json:"shared_with"`
+		}
+		if !decodeBody(w, r, &request, "INVALID_JSON", "Invalid JSON data") {
+			return
+		}
+		for _, subject := range request.SharedWith {
+			if err := models.ValidateSubject(subject); err != nil {
+				api.RespondWithError(w, http.StatusBadRequest, "INVALID_SUBJECT", err.Error())
+				return
+			}
+		}
+
+		// Share document
+		document, err := h.documentService.Share(r.Context(), id, collectionName, request.SharedWith, documentAccess(r, collectionName), requestActor(r))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "DOCUMENT_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, document)
+	}
+}
+
+// requestAuthor identifies who made a request, used to attribute document revisions.
+// Requests without a principal are anonymous, whatever credentials they carry, as nothing
+// verified them.
+func requestAuthor(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return principal.Name
+	}
+	return "anonymous"
+}
+
+// requestActor identifies who made a request and where it came from, used to attribute
+// document revisions and audit log entries
+func requestActor(r *http.Request) models.Actor {
+	sourceIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		sourceIP = r.RemoteAddr
+	}
+	return models.Actor{
+		Name:      requestAuthor(r),
+		SourceIP:  sourceIP,
+		RequestID: middleware.RequestID(r.Context()),
+	}
+}
+
+// parseAsOf reads the optional as_of query parameter as an RFC 3339 timestamp
+func parseAsOf(r *http.Request) (*time.Time, error) {
+	value := r.URL.Query().Get("as_of")
+	if value == "" {
+		return nil, nil
+	}
+
+	asOf, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("as_of must be an RFC 3339 timestamp such as 2026-09-01T00:00:00Z")
+	}
+	return &asOf, nil
+}
+
+// parseExpiry reads the optional expires_at (RFC 3339 timestamp) or ttl (duration) query parameter
+func parseExpiry(r *http.Request) (*time.Time, error) {
+	expiresAtStr := r.URL.Query().Get("expires_at")
+	ttlStr := r.URL.Query().Get("ttl")
+
+	var expiresAt time.Time
+	switch {
+	case expiresAtStr != "" && ttlStr != "":
+		return nil, fmt.Errorf("expires_at and ttl cannot be used together")
+	case expiresAtStr != "":
+		value, err := time.Parse(time.RFC3339Nano, expiresAtStr)
+		if err != nil {
+			return nil, fmt.Errorf("expires_at must be an RFC 3339 timestamp such as 2026-09-01T00:00:00Z")
+		}
+		expiresAt = value
+	case ttlStr != "":
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("ttl must be a positive duration such as 30m or 24h")
+		}
+		expiresAt = time.Now().Add(ttl)
+	default:
+		return nil, nil
+	}
+
+	if !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expiry must be in the future")
+	}
+	return &expiresAt, nil
+}
@@ -0,0 +1,50 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/app"
+	"github.com/rbehzadan/flexstore/internal/models"
+	"github.com/rbehzadan/flexstore/pkg/config"
+)
+
+func TestAnonymousWriteIgnoresCredentials(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.SqlitePath = filepath.Join(t.TempDir(), "db.sqlite")
+	cfg.AuthAnonymousMethods = []string{"GET", "POST"}
+
+	a, err := app.NewApp(cfg)
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	defer a.Close()
+
+	// Anonymous writes skip authentication, so the credentials are never checked
+	req := httptest.NewRequest("POST", "/api/collections/notes/documents", strings.NewReader(`{"text":"hello"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth("admin", "forged")
+	rr := httptest.NewRecorder()
+	a.Handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("wrong status: got %v want %v: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	query := models.NewAuditQuery()
+	query.CollectionName = "notes"
+	list, err := a.AuditService.List(t.Context(), query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Entries) == 0 {
+		t.Fatal("write not audited")
+	}
+	for _, entry := range list.Entries {
+		if entry.Principal != "anonymous" {
+			t.Errorf("wrong principal: got %v want %v", entry.Principal, "anonymous")
+		}
+	}
+}
@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
 	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+	"github.com/rbehzadan/flexstore/internal/models"
 	"github.com/rbehzadan/flexstore/pkg/config"
 )
 
+// ReplicationStatusFunc reports the replication status, returning nil when not replicating
+type ReplicationStatusFunc func() *models.ReplicationStatus
+
 // HealthHandler returns a handler function for health checks
-func HealthHandler(cfg *config.Config) http.HandlerFunc {
+func HealthHandler(cfg *config.Config, replicationStatus ReplicationStatusFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		healthInfo := api.HealthResponse{
 			Status:  "ok",
@@ -17,6 +23,11 @@ func HealthHandler(cfg *config.Config) http.HandlerFunc {
 			Uptime:  cfg.GetUptime(),
 		}
 
+		// Include replication lag when running as a follower
+		if replicationStatus != nil {
+			healthInfo.Replication = replicationStatus()
+		}
+
 		resp := api.Response{
 			Status: "success",
 			Data:   healthInfo,
@@ -27,3 +38,30 @@ func HealthHandler(cfg *config.Config) http.HandlerFunc {
 		json.NewEncoder(w).Encode(resp)
 	}
 }
+
+// ReadinessFunc runs the readiness checks
+type ReadinessFunc func(ctx context.Context) models.ReadinessReport
+
+// ReadinessHandler returns a handler function that reports the result of every readiness
+// check. It answers 503 Service Unavailable if a check failed, so load balancers stop
+// routing to the instance.
+func ReadinessHandler(readiness ReadinessFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := readiness(r.Context())
+
+		// Respond
+		if !report.Ready() {
+			api.RespondWithJSON(w, http.StatusServiceUnavailable, api.Response{
+				Status: "error",
+				Data:   report,
+				Error: &api.ErrorInfo{
+					Code:      "NOT_READY",
+					Message:   "One or more readiness checks failed",
+					RequestID: middleware.RequestID(r.Context()),
+				},
+			})
+			return
+		}
+		api.RespondWithJSON(w, http.StatusOK, report)
+	}
+}
@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/rbehzadan/flexstore/internal/api"
 	"github.com/rbehzadan/flexstore/internal/api/handlers"
+	"github.com/rbehzadan/flexstore/internal/models"
 	"github.com/rbehzadan/flexstore/pkg/config"
 )
 
@@ -26,7 +28,7 @@ func TestHealthHandler(t *testing.T) {
 
 	// Create a ResponseRecorder to record the response
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.HealthHandler(cfg))
+	handler := http.HandlerFunc(handlers.HealthHandler(cfg, nil))
 
 	// Serve the request to the handler
 	handler.ServeHTTP(rr, req)
@@ -80,3 +82,43 @@ func TestHealthHandler(t *testing.T) {
 		t.Errorf("uptime is missing or empty: %v", uptime)
 	}
 }
+
+func TestReadinessHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		want   int
+	}{
+		{"ready", models.HealthOK, http.StatusOK},
+		{"degraded", models.HealthDegraded, http.StatusOK},
+		{"not ready", models.HealthFailed, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readiness := func(ctx context.Context) models.ReadinessReport {
+				return models.ReadinessReport{
+					Status: tt.status,
+					Checks: []models.HealthCheckResult{{Name: "database", Status: tt.status}},
+				}
+			}
+
+			rr := httptest.NewRecorder()
+			handlers.ReadinessHandler(readiness)(rr, httptest.NewRequest("GET", "/health/ready", nil))
+
+			if rr.Code != tt.want {
+				t.Errorf("got status %v want %v", rr.Code, tt.want)
+			}
+
+			var response struct {
+				Data models.ReadinessReport `json:"data"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Error unmarshaling response: %v", err)
+			}
+			if response.Data.Status != tt.status || len(response.Data.Checks) != 1 {
+				t.Errorf("got report %+v", response.Data)
+			}
+		})
+	}
+}
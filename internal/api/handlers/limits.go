@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// decodeBody decodes a JSON request body into v and responds with 400 Bad Request if it is
+// invalid, or 413 Request Entity Too Large if it exceeds the request size limit
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}, errorCode, message string) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+	if !respondBodyTooLarge(w, err) {
+		api.RespondWithError(w, http.StatusBadRequest, errorCode, message)
+	}
+	return false
+}
+
+// respondBodyTooLarge responds with 413 Request Entity Too Large if err comes from reading
+// a request body beyond its size limit
+func respondBodyTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	message := fmt.Sprintf("Request body exceeds the limit of %d bytes", tooLarge.Limit)
+	api.RespondWithError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE", message)
+	return true
+}
+
+// limitBody caps the request body at the request size limit of a collection
+func (h *DocumentHandlers) limitBody(w http.ResponseWriter, r *http.Request, collectionName string) bool {
+	limits, err := h.documentService.Limits(r.Context(), collectionName)
+	if api.RespondWithContextError(w, err) {
+		return false
+	}
+	if err != nil {
+		api.RespondWithError(w, http.StatusInternalServerError, "GET_LIMITS_ERROR", err.Error())
+		return false
+	}
+	if limits.MaxRequestBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limits.MaxRequestBytes)
+	}
+	return true
+}
+
+// respondWriteError responds to a document write rejected for breaking the limits or the
+// quota of its collection or for taking the ID of a trashed document, or cut short by its
+// context, and reports whether it did
+func respondWriteError(w http.ResponseWriter, err error) bool {
+	if api.RespondWithContextError(w, err) {
+		return true
+	}
+	switch {
+	case errors.Is(err, models.ErrDocumentInTrash):
+		api.RespondWithError(w, http.StatusConflict, "DOCUMENT_IN_TRASH", err.Error())
+	case errors.Is(err, models.ErrQuotaExceeded):
+		api.RespondWithError(w, http.StatusInsufficientStorage, "QUOTA_EXCEEDED", err.Error())
+	case errors.Is(err, models.ErrDocumentTooLarge):
+		api.RespondWithError(w, http.StatusRequestEntityTooLarge, "DOCUMENT_TOO_LARGE", err.Error())
+	case errors.Is(err, models.ErrDocumentTooDeep):
+		api.RespondWithError(w, http.StatusUnprocessableEntity, "DOCUMENT_TOO_DEEP", err.Error())
+	case errors.Is(err, models.ErrArrayTooLong):
+		api.RespondWithError(w, http.StatusUnprocessableEntity, "ARRAY_TOO_LONG", err.Error())
+	case errors.Is(err, models.ErrTooManyKeys):
+		api.RespondWithError(w, http.StatusUnprocessableEntity, "TOO_MANY_KEYS", err.Error())
+	default:
+		return false
+	}
+	return true
+}
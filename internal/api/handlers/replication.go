@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/models"
+	"github.com/rbehzadan/flexstore/internal/service"
+)
+
+// Default and maximum number of changes returned per request
+const (
+	defaultChangesLimit = 500
+	maxChangesLimit     = 5000
+)
+
+// ReplicationHandlers contains handlers for replication operations
+type ReplicationHandlers struct {
+	replicationService *service.ReplicationService
+}
+
+// NewReplicationHandlers creates new replication handlers
+func NewReplicationHandlers(replicationService *service.ReplicationService) *ReplicationHandlers {
+	return &ReplicationHandlers{
+		replicationService: replicationService,
+	}
+}
+
+// ListChanges lists change log entries after a given sequence number
+func (h *ReplicationHandlers) ListChanges() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get since parameter
+		var since int64
+		sinceStr := r.URL.Query().Get("since")
+		if sinceStr != "" {
+			value, err := strconv.ParseInt(sinceStr, 10, 64)
+			if err != nil || value < 0 {
+				api.RespondWithError(w, http.StatusBadRequest, "INVALID_SINCE", "since must be a non-negative integer")
+				return
+			}
+			since = value
+		}
+
+		// Get limit parameter
+		limit := defaultChangesLimit
+		limitStr := r.URL.Query().Get("limit")
+		if limitStr != "" {
+			value, err := strconv.Atoi(limitStr)
+			if err == nil && value > 0 {
+				limit = value
+			}
+		}
+		if limit > maxChangesLimit {
+			limit = maxChangesLimit
+		}
+
+		// Get changes
+		changes, err := h.replicationService.ListChanges(r.Context(), since, limit)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if errors.Is(err, models.ErrSnapshotRequired) {
+			api.RespondWithError(w, http.StatusGone, "SNAPSHOT_REQUIRED", "Changes after this sequence number have been pruned; load a snapshot from /api/replication/snapshot")
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "LIST_CHANGES_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, changes)
+	}
+}
+
+// Snapshot streams every collection and document as newline-delimited JSON, preceded by the
+// position in the change log they reflect, for followers to start replicating from
+func (h *ReplicationHandlers) Snapshot() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Stream entries; once the response has started an error can only be logged
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		err := h.replicationService.Snapshot(r.Context(), func(entry *models.SnapshotEntry) error {
+			if err := extendWriteDeadline(w); err != nil {
+				return err
+			}
+			return encoder.Encode(entry)
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Failed to stream snapshot", "error", err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// ListRevisions lists the revision history of a document
+func (h *DocumentHandlers) ListRevisions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name and document ID from URL
+		vars := mux.Vars(r)
+		collectionName := vars["name"]
+		id := vars["id"]
+
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionRead) {
+			return
+		}
+
+		// Get revisions
+		revisions, err := h.documentService.ListRevisions(r.Context(), id, collectionName, documentAccess(r, collectionName))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "DOCUMENT_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, revisions)
+	}
+}
+
+// GetRevision gets a single revision of a document
+func (h *DocumentHandlers) GetRevision() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name, document ID and revision from URL
+		vars := mux.Vars(r)
+		collectionName := vars["name"]
+		id := vars["id"]
+
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionRead) {
+			return
+		}
+
+		revision, err := strconv.Atoi(vars["rev"])
+		if err != nil || revision < 1 {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_REVISION", "Revision must be a positive integer")
+			return
+		}
+
+		// Get revision
+		result, err := h.documentService.GetRevision(r.Context(), id, collectionName, revision, documentAccess(r, collectionName))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "REVISION_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, result)
+	}
+}
+
+// RestoreRevision restores a document to a previous revision
+func (h *DocumentHandlers) RestoreRevision() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name, document ID and revision from URL
+		vars := mux.Vars(r)
+		collectionName := vars["name"]
+		id := vars["id"]
+
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionWrite) {
+			return
+		}
+
+		revision, err := strconv.Atoi(vars["rev"])
+		if err != nil || revision < 1 {
+			api.RespondWithError(w, http.StatusBadRequest, "INVALID_REVISION", "Revision must be a positive integer")
+			return
+		}
+
+		// Check the revision can be restored
+		source, err := h.documentService.GetRevision(r.Context(), id, collectionName, revision, documentAccess(r, collectionName))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "REVISION_NOT_FOUND", err.Error())
+			return
+		}
+		if source.Operation == models.RevisionDelete {
+			api.RespondWithError(w, http.StatusConflict, "REVISION_NOT_RESTORABLE", "Cannot restore a deletion revision")
+			return
+		}
+
+		// Restore revision
+		document, err := h.documentService.RestoreRevision(r.Context(), id, collectionName, revision, documentAccess(r, collectionName), requestActor(r))
+		if respondWriteError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "RESTORE_REVISION_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, document)
+	}
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/models"
+	"github.com/rbehzadan/flexstore/internal/service"
+)
+
+// RoleHandlers contains handlers for role management
+type RoleHandlers struct {
+	roleService *service.RoleService
+}
+
+// NewRoleHandlers creates new role handlers
+func NewRoleHandlers(roleService *service.RoleService) *RoleHandlers {
+	return &RoleHandlers{
+		roleService: roleService,
+	}
+}
+
+// CreateRole defines a new role
+func (h *RoleHandlers) CreateRole() http.HandlerFunc {
+	type request struct {
+		Name   string         `json:"name"`
+		Grants []models.Grant `json:"grants"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse request body
+		var req request
+		if !decodeBody(w, r, &req, "INVALID_REQUEST", "Invalid request body") {
+			return
+		}
+
+		// Create role
+		role, err := h.roleService.Create(r.Context(), req.Name, req.Grants)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "CREATE_ROLE_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusCreated, role)
+	}
+}
+
+// ListRoles lists the built-in and defined roles
+func (h *RoleHandlers) ListRoles() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get roles
+		roles, err := h.roleService.List(r.Context())
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "LIST_ROLES_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, roles)
+	}
+}
+
+// GetRole gets a role by name
+func (h *RoleHandlers) GetRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get role name from URL
+		name := mux.Vars(r)["role"]
+
+		// Get role
+		role, err := h.roleService.GetByName(r.Context(), name)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "ROLE_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, role)
+	}
+}
+
+// UpdateRole replaces the grants of a role
+func (h *RoleHandlers) UpdateRole() http.HandlerFunc {
+	type request struct {
+		Grants []models.Grant `json:"grants"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get role name from URL
+		name := mux.Vars(r)["role"]
+
+		// Parse request body
+		var req request
+		if !decodeBody(w, r, &req, "INVALID_REQUEST", "Invalid request body") {
+			return
+		}
+
+		// Update role
+		role, err := h.roleService.Update(r.Context(), name, req.Grants)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "UPDATE_ROLE_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, role)
+	}
+}
+
+// DeleteRole deletes a role and its assignments
+func (h *RoleHandlers) DeleteRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get role name from URL
+		name := mux.Vars(r)["role"]
+
+		// Delete role
+		err := h.roleService.Delete(r.Context(), name)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "DELETE_ROLE_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Role deleted successfully"})
+	}
+}
+
+// ListAssignments lists the subjects a role is assigned to
+func (h *RoleHandlers) ListAssignments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get role name from URL
+		name := mux.Vars(r)["role"]
+
+		// Get assignments
+		assignments, err := h.roleService.Assignments(r.Context(), name)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "ROLE_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, assignments)
+	}
+}
+
+// AssignRole assigns a role to a user or API key
+func (h *RoleHandlers) AssignRole() http.HandlerFunc {
+	type request struct {
+		Subject string `json:"subject"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get role name from URL
+		name := mux.Vars(r)["role"]
+
+		// Parse request body
+		var req request
+		if !decodeBody(w, r, &req, "INVALID_REQUEST", "Invalid request body") {
+			return
+		}
+
+		// Assign role
+		err := h.roleService.Assign(r.Context(), name, req.Subject)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "ASSIGN_ROLE_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Role assigned successfully"})
+	}
+}
+
+// UnassignRole removes a role from a user or API key
+func (h *RoleHandlers) UnassignRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get role name and subject from URL
+		vars := mux.Vars(r)
+		name := vars["role"]
+		subject := vars["subject"]
+
+		// Unassign role
+		err := h.roleService.Unassign(r.Context(), name, subject)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "ASSIGNMENT_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Role unassigned successfully"})
+	}
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// streamWriteTimeout is how long writing each part of a streamed response may take. It
+// replaces the server's write timeout, which would cut off a stream that takes longer as a whole.
+const streamWriteTimeout = 15 * time.Second
+
+// extendWriteDeadline gives a streamed response another streamWriteTimeout to write its next
+// part. Writers without a deadline, such as recorders in tests, need no extension.
+func extendWriteDeadline(w http.ResponseWriter) error {
+	err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/models"
+	"github.com/rbehzadan/flexstore/internal/service"
+)
+
+// TrashHandlers contains handlers for trash operations
+type TrashHandlers struct {
+	trashService *service.TrashService
+}
+
+// NewTrashHandlers creates new trash handlers
+func NewTrashHandlers(trashService *service.TrashService) *TrashHandlers {
+	return &TrashHandlers{
+		trashService: trashService,
+	}
+}
+
+// ListCollections lists collections in the trash
+func (h *TrashHandlers) ListCollections() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collections
+		collections, err := h.trashService.ListCollections(r.Context())
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "LIST_TRASH_ERROR", err.Error())
+			return
+		}
+
+		// Hide collections the caller may not read
+		visible := collections.Collections[:0]
+		for _, collection := range collections.Collections {
+			if canAccess(r, collection.Name, models.PermissionRead) {
+				visible = append(visible, collection)
+			}
+		}
+		collections.Collections = visible
+		collections.Total = len(visible)
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, collections)
+	}
+}
+
+// RestoreCollection restores a collection from the trash
+func (h *TrashHandlers) RestoreCollection() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name from URL
+		vars := mux.Vars(r)
+		name := vars["name"]
+
+		// Check permission
+		if !authorize(w, r, name, models.PermissionWrite) {
+			return
+		}
+
+		// Restore collection
+		err := h.trashService.RestoreCollection(r.Context(), name, requestActor(r))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "COLLECTION_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Collection restored successfully"})
+	}
+}
+
+// PurgeCollection permanently deletes a collection from the trash
+func (h *TrashHandlers) PurgeCollection() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name from URL
+		vars := mux.Vars(r)
+		name := vars["name"]
+
+		// Check permission
+		if !authorize(w, r, name, models.PermissionDelete) ||
+			!authorizeAllDocuments(w, r, name, h.trashService.RestrictAccess) {
+			return
+		}
+
+		// Purge collection
+		err := h.trashService.PurgeCollection(r.Context(), name, requestActor(r))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "COLLECTION_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Collection purged successfully"})
+	}
+}
+
+// ListDocuments lists trashed documents of a collection
+func (h *TrashHandlers) ListDocuments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name from URL
+		vars := mux.Vars(r)
+		collectionName := vars["name"]
+
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionRead) {
+			return
+		}
+
+		// Parse query parameters
+		query := models.NewDocumentQuery()
+
+		// Get limit parameter
+		limitStr := r.URL.Query().Get("limit")
+		if limitStr != "" {
+			limit, err := strconv.Atoi(limitStr)
+			if err == nil && limit > 0 {
+				query.Limit = limit
+			}
+		}
+
+		// Get offset parameter
+		offsetStr := r.URL.Query().Get("offset")
+		if offsetStr != "" {
+			offset, err := strconv.Atoi(offsetStr)
+			if err == nil && offset >= 0 {
+				query.Offset = offset
+			}
+		}
+
+		query.Access = documentAccess(r, collectionName)
+
+		// Get documents
+		documents, err := h.trashService.ListDocuments(r.Context(), collectionName, query)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "COLLECTION_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, documents)
+	}
+}
+
+// RestoreDocument restores a document from the trash
+func (h *TrashHandlers) RestoreDocument() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name and document ID from URL
+		vars := mux.Vars(r)
+		collectionName := vars["name"]
+		id := vars["id"]
+
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionWrite) {
+			return
+		}
+
+		// Restore document
+		document, err := h.trashService.RestoreDocument(r.Context(), id, collectionName, documentAccess(r, collectionName), requestActor(r))
+		if respondWriteError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "DOCUMENT_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, document)
+	}
+}
+
+// PurgeDocument permanently deletes a document from the trash
+func (h *TrashHandlers) PurgeDocument() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get collection name and document ID from URL
+		vars := mux.Vars(r)
+		collectionName := vars["name"]
+		id := vars["id"]
+
+		// Check permission
+		if !authorize(w, r, collectionName, models.PermissionDelete) {
+			return
+		}
+
+		// Purge document
+		err := h.trashService.PurgeDocument(r.Context(), id, collectionName, documentAccess(r, collectionName), requestActor(r))
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "DOCUMENT_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "Document purged successfully"})
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/service"
+)
+
+// UserHandlers contains handlers for user account management
+type UserHandlers struct {
+	userService *service.UserService
+}
+
+// NewUserHandlers creates new user handlers
+func NewUserHandlers(userService *service.UserService) *UserHandlers {
+	return &UserHandlers{
+		userService: userService,
+	}
+}
+
+// CreateUser creates a user account
+func (h *UserHandlers) CreateUser() http.HandlerFunc {
+	type request struct {
+		Username string   `json:"username"`
+		Password string   `json:"password"`
+		Roles    []string `json:"roles"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Parse request body
+		var req request
+		if !decodeBody(w, r, &req, "INVALID_REQUEST", "Invalid request body") {
+			return
+		}
+
+		// Create user
+		user, err := h.userService.Create(r.Context(), req.Username, req.Password, req.Roles)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "CREATE_USER_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusCreated, user)
+	}
+}
+
+// ListUsers lists all user accounts
+func (h *UserHandlers) ListUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get users
+		users, err := h.userService.List(r.Context())
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusInternalServerError, "LIST_USERS_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, users)
+	}
+}
+
+// GetUser gets a user account by username
+func (h *UserHandlers) GetUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get username from URL
+		username := mux.Vars(r)["username"]
+
+		// Get user
+		user, err := h.userService.GetByUsername(r.Context(), username)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "USER_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, user)
+	}
+}
+
+// ResetPassword sets a new password for a user account
+func (h *UserHandlers) ResetPassword() http.HandlerFunc {
+	type request struct {
+		Password string `json:"password"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get username from URL
+		username := mux.Vars(r)["username"]
+
+		// Parse request body
+		var req request
+		if !decodeBody(w, r, &req, "INVALID_REQUEST", "Invalid request body") {
+			return
+		}
+
+		// Reset password
+		user, err := h.userService.ResetPassword(r.Context(), username, req.Password)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusBadRequest, "RESET_PASSWORD_ERROR", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, user)
+	}
+}
+
+// DisableUser prevents a user account from authenticating
+func (h *UserHandlers) DisableUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get username from URL
+		username := mux.Vars(r)["username"]
+
+		// Refuse to lock out the caller
+		if principal, ok := auth.FromContext(r.Context()); ok && principal.Method == auth.MethodBasic && principal.ID == username {
+			api.RespondWithError(w, http.StatusBadRequest, "DISABLE_USER_ERROR", "You cannot disable your own account")
+			return
+		}
+
+		// Disable user
+		user, err := h.userService.Disable(r.Context(), username)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "USER_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, user)
+	}
+}
+
+// EnableUser allows a disabled user account to authenticate again
+func (h *UserHandlers) EnableUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Get username from URL
+		username := mux.Vars(r)["username"]
+
+		// Enable user
+		user, err := h.userService.Enable(r.Context(), username)
+		if api.RespondWithContextError(w, err) {
+			return
+		}
+		if err != nil {
+			api.RespondWithError(w, http.StatusNotFound, "USER_NOT_FOUND", err.Error())
+			return
+		}
+
+		// Respond
+		api.RespondWithJSON(w, http.StatusOK, user)
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// APIKeyAuthenticator resolves API key secrets to their keys
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, secret string) (*models.APIKey, error)
+}
+
+// APIKeyMiddleware authenticates requests carrying an API key in the X-API-Key header
+// or as a bearer token, and hands every other request to fallback
+func APIKeyMiddleware(keys APIKeyAuthenticator, fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fallbackHandler := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret := requestAPIKey(r)
+			if secret == "" {
+				fallbackHandler.ServeHTTP(w, r)
+				return
+			}
+
+			// Check the key
+			key, err := keys.Authenticate(r.Context(), secret)
+			if api.RespondWithContextError(w, err) {
+				return
+			}
+			if err != nil {
+				api.RespondWithError(w, http.StatusUnauthorized, "INVALID_API_KEY", err.Error())
+				return
+			}
+
+			// Authentication successful, proceed with the key's scopes
+			principal := &auth.Principal{
+				ID:          key.ID,
+				Name:        "key:" + key.Name,
+				Method:      auth.MethodAPIKey,
+				Scopes:      key.Scopes,
+				Collections: key.Collections,
+			}
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	}
+}
+
+// requestAPIKey extracts an API key from the request headers
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok && auth.IsAPIKey(token) {
+		return token
+	}
+
+	return ""
+}
@@ -0,0 +1,85 @@
+package middleware_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// fakeKeys authenticates a fixed set of API keys
+type fakeKeys map[string]*models.APIKey
+
+func (k fakeKeys) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	if key, ok := k[secret]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("invalid API key")
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	keys := fakeKeys{
+		"fsk_reader": {Name: "reader", Scopes: []string{models.ScopeDocumentsRead}},
+		"fsk_orders": {Name: "orders", Scopes: []string{models.ScopeDocumentsWrite}, Collections: []string{"orders"}},
+	}
+
+	// Documents routes need documents scopes for the collection in the URL
+	requirement := func(r *http.Request) middleware.Requirement {
+		scope := models.ScopeDocumentsWrite
+		if r.Method == http.MethodGet {
+			scope = models.ScopeDocumentsRead
+		}
+		return middleware.Requirement{Scope: scope, Collection: mux.Vars(r)["name"]}
+	}
+
+	router := mux.NewRouter()
+	router.Use(middleware.APIKeyMiddleware(keys, middleware.BasicAuthMiddleware(fakeUsers{"admin": "secret"})))
+	router.Use(middleware.AuthorizeMiddleware(requirement))
+	router.Handle("/api/collections/{name}/documents", testHandler())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no credentials", "GET", "/api/collections/users/documents", "", "", http.StatusUnauthorized},
+		{"unknown key", "GET", "/api/collections/users/documents", "X-API-Key", "fsk_unknown", http.StatusUnauthorized},
+		{"key header", "GET", "/api/collections/users/documents", "X-API-Key", "fsk_reader", http.StatusOK},
+		{"bearer token", "GET", "/api/collections/users/documents", "Authorization", "Bearer fsk_reader", http.StatusOK},
+		{"missing scope", "POST", "/api/collections/users/documents", "X-API-Key", "fsk_reader", http.StatusForbidden},
+		{"allowed collection", "POST", "/api/collections/orders/documents", "X-API-Key", "fsk_orders", http.StatusOK},
+		{"other collection", "POST", "/api/collections/users/documents", "X-API-Key", "fsk_orders", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+		})
+	}
+
+	// Basic auth still works alongside API keys and grants full access
+	req := httptest.NewRequest("POST", "/api/collections/users/documents", nil)
+	req.SetBasicAuth("admin", "secret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("basic auth returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
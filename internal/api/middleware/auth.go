@@ -1,16 +1,23 @@
 package middleware
 
 import (
-	"crypto/subtle"
+	"context"
 	"encoding/base64"
 	"net/http"
 	"strings"
 
 	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/models"
 )
 
-// BasicAuthMiddleware provides HTTP Basic Authentication
-func BasicAuthMiddleware(username, password string) func(http.Handler) http.Handler {
+// UserAuthenticator checks the username and password of a user account
+type UserAuthenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+}
+
+// BasicAuthMiddleware provides HTTP Basic Authentication against user accounts
+func BasicAuthMiddleware(users UserAuthenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get Authorization header
@@ -39,18 +46,23 @@ func BasicAuthMiddleware(username, password string) func(http.Handler) http.Hand
 				return
 			}
 
-			providedUser := pair[0]
-			providedPass := pair[1]
-
-			// Check credentials using constant-time comparison to prevent timing attacks
-			if subtle.ConstantTimeCompare([]byte(providedUser), []byte(username)) != 1 ||
-				subtle.ConstantTimeCompare([]byte(providedPass), []byte(password)) != 1 {
+			// Check credentials
+			user, err := users.Authenticate(r.Context(), pair[0], pair[1])
+			if api.RespondWithContextError(w, err) {
+				return
+			}
+			if err != nil {
 				unauthorized(w)
 				return
 			}
 
-			// Authentication successful, proceed to the next handler
-			next.ServeHTTP(w, r)
+			// Authentication successful, proceed with the permissions of the user's roles
+			principal := &auth.Principal{
+				ID:     user.Username,
+				Name:   user.Username,
+				Method: auth.MethodBasic,
+			}
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
 		})
 	}
 }
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// Requirement describes what a request needs from the authenticated principal
+type Requirement struct {
+	// Scope is the scope needed; empty allows any authenticated principal
+	Scope string
+
+	// Collection is the collection the request operates on, if any
+	Collection string
+}
+
+// AuthorizeMiddleware checks the scopes and collection restrictions of the authenticated
+// principal against the requirement of each request. Requests without a principal are only
+// passed through when the route needs no scope or the auth policy let them through: public
+// paths always, anonymous methods never on server routes. Principals whose credential carries
+// no scopes are limited by their roles, which handlers check per collection; server routes
+// need the admin role.
+func AuthorizeMiddleware(require func(*http.Request) Requirement) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requirement := require(r)
+			principal, ok := auth.FromContext(r.Context())
+			if !ok {
+				if requirement.Scope == "" || anonymousAllowed(r, requirement) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				unauthorized(w)
+				return
+			}
+
+			if requirement.Scope == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Check roles of unscoped principals on server routes
+			if !principal.Scoped() {
+				if serverScope(requirement.Scope) && !principal.IsAdmin() {
+					api.RespondWithError(w, http.StatusForbidden, "FORBIDDEN", "Administrator access is required")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Check scope
+			if !principal.HasScope(requirement.Scope) {
+				message := fmt.Sprintf("Missing required scope '%s'", requirement.Scope)
+				api.RespondWithError(w, http.StatusForbidden, "FORBIDDEN", message)
+				return
+			}
+
+			// Check collection restrictions; restricted principals can only use routes of their collections
+			if principal.Restricted() && (requirement.Collection == "" || !principal.CanAccessCollection(requirement.Collection)) {
+				api.RespondWithError(w, http.StatusForbidden, "FORBIDDEN", "Access to this collection is not allowed")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// anonymousAllowed reports whether a request without a principal may use a route that needs
+// a scope
+func anonymousAllowed(r *http.Request, requirement Requirement) bool {
+	switch auth.AnonymousFrom(r.Context()) {
+	case auth.AnonymousOpen, auth.AnonymousPath:
+		return true
+	case auth.AnonymousMethod:
+		return !serverScope(requirement.Scope)
+	default:
+		return false
+	}
+}
+
+// serverScope reports whether a scope covers the server as a whole rather than collections
+func serverScope(scope string) bool {
+	return scope == models.ScopeAdmin || scope == models.ScopeMetricsRead
+}
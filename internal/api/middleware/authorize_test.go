@@ -0,0 +1,48 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestAuthorizeMiddlewareWithoutPrincipal(t *testing.T) {
+	tests := []struct {
+		name      string
+		anonymous auth.Anonymous
+		scope     string
+		want      int
+	}{
+		{"no scope needed", auth.NotAnonymous, "", http.StatusOK},
+		{"scope without principal", auth.NotAnonymous, models.ScopeDocumentsRead, http.StatusUnauthorized},
+		{"admin without principal", auth.NotAnonymous, models.ScopeAdmin, http.StatusUnauthorized},
+		{"anonymous method on collection route", auth.AnonymousMethod, models.ScopeDocumentsRead, http.StatusOK},
+		{"anonymous method on admin route", auth.AnonymousMethod, models.ScopeAdmin, http.StatusUnauthorized},
+		{"anonymous method on metrics route", auth.AnonymousMethod, models.ScopeMetricsRead, http.StatusUnauthorized},
+		{"public path on admin route", auth.AnonymousPath, models.ScopeAdmin, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := func(*http.Request) middleware.Requirement {
+				return middleware.Requirement{Scope: tt.scope}
+			}
+			handler := middleware.AuthorizeMiddleware(require)(testHandler())
+
+			req := httptest.NewRequest("GET", "/api/collections/notes/documents", nil)
+			if tt.anonymous != auth.NotAnonymous {
+				req = req.WithContext(auth.WithAnonymous(req.Context(), tt.anonymous))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+		})
+	}
+}
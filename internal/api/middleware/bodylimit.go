@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// BodyLimitMiddleware caps every request body at maxBytes. Reading beyond the limit fails
+// with *http.MaxBytesError, which handlers report as 413 Request Entity Too Large.
+func BodyLimitMiddleware(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rbehzadan/flexstore/internal/auth"
+)
+
+// ClientCertMiddleware authenticates requests made over a connection with a verified client
+// certificate by the certificate's common name, and hands every other request to fallback.
+// The roles assigned to cert:<common name> apply.
+func ClientCertMiddleware(fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fallbackHandler := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Only chains verified against the client CA bundle count
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				fallbackHandler.ServeHTTP(w, r)
+				return
+			}
+			name := r.TLS.VerifiedChains[0][0].Subject.CommonName
+			if name == "" {
+				fallbackHandler.ServeHTTP(w, r)
+				return
+			}
+
+			// Authentication successful, proceed with the permissions of the certificate's roles
+			principal := &auth.Principal{
+				ID:     name,
+				Name:   name,
+				Method: auth.MethodCert,
+			}
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+	"github.com/rbehzadan/flexstore/internal/auth"
+)
+
+func TestClientCertMiddleware(t *testing.T) {
+	fallback := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "fallback", http.StatusUnauthorized)
+		})
+	}
+	var got *auth.Principal
+	handler := middleware.ClientCertMiddleware(fallback)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = auth.FromContext(r.Context())
+	}))
+
+	verified := func(commonName string) *tls.ConnectionState {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}}
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+
+	tests := []struct {
+		name     string
+		state    *tls.ConnectionState
+		want     int
+		wantUser string
+	}{
+		{"verified certificate", verified("alice"), http.StatusOK, "alice"},
+		{"plain connection", nil, http.StatusUnauthorized, ""},
+		{"unverified certificate", &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}, http.StatusUnauthorized, ""},
+		{"no common name", verified(""), http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest("GET", "/api/collections", nil)
+			req.TLS = tt.state
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+			if tt.wantUser != "" && (got == nil || got.Subject() != "cert:"+tt.wantUser || got.Method != auth.MethodCert) {
+				t.Errorf("wrong principal: got %+v want user %v", got, tt.wantUser)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy decides which cross-origin requests browsers may make
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins such as https://app.example.com or patterns with a
+	// single * such as https://*.example.com; "*" allows every origin
+	AllowedOrigins []string
+
+	AllowedMethods []string
+
+	// AllowedHeaders are request headers browsers may send; "*" allows every header
+	AllowedHeaders []string
+
+	// ExposedHeaders are response headers scripts may read
+	ExposedHeaders []string
+
+	// AllowCredentials lets browsers send cookies and authorization headers
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// AllowsOrigin reports whether requests from an origin are allowed
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	for _, pattern := range p.AllowedOrigins {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		prefix, suffix, ok := strings.Cut(pattern, "*")
+		if ok && len(origin) > len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsMethod reports whether a method may be used cross-origin
+func (p CORSPolicy) allowsMethod(method string) bool {
+	return slices.ContainsFunc(p.AllowedMethods, func(allowed string) bool {
+		return strings.EqualFold(allowed, method)
+	})
+}
+
+// allowsHeaders reports whether every header in a comma-separated list may be sent
+func (p CORSPolicy) allowsHeaders(headers string) bool {
+	if slices.Contains(p.AllowedHeaders, "*") {
+		return true
+	}
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		allowed := slices.ContainsFunc(p.AllowedHeaders, func(allowed string) bool {
+			return strings.EqualFold(allowed, header)
+		})
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// CORSMiddleware adds CORS headers to responses for allowed origins and answers preflight
+// requests itself. It wraps the router rather than being registered on it, because the
+// router rejects OPTIONS requests to routes registered for other methods.
+func CORSMiddleware(policy CORSPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+
+			// Answer preflight requests, leaving out the CORS headers if the request is not allowed
+			requestMethod := r.Header.Get("Access-Control-Request-Method")
+			if r.Method == http.MethodOptions && requestMethod != "" {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+				if origin != "" && policy.AllowsOrigin(origin) && policy.allowsMethod(requestMethod) && policy.allowsHeaders(requestHeaders) {
+					setAllowOrigin(w, policy, origin)
+					w.Header().Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+					if requestHeaders != "" {
+						w.Header().Set("Access-Control-Allow-Headers", requestHeaders)
+					}
+					if policy.MaxAge > 0 {
+						w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			// Let scripts of allowed origins read the response
+			if origin != "" && policy.AllowsOrigin(origin) {
+				setAllowOrigin(w, policy, origin)
+				if len(policy.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setAllowOrigin allows an origin to read the response. The origin is echoed rather than
+// answered with *, which browsers do not accept together with credentials.
+func setAllowOrigin(w http.ResponseWriter, policy CORSPolicy, origin string) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if policy.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
@@ -0,0 +1,118 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	policy := middleware.CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		ExposedHeaders:   []string{"RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	// The router serves GET only, so it would reject preflight requests on its own
+	router := mux.NewRouter()
+	router.Handle("/api/collections", testHandler()).Methods("GET")
+	handler := middleware.CORSMiddleware(policy)(router)
+
+	tests := []struct {
+		name           string
+		method         string
+		origin         string
+		requestMethod  string
+		requestHeaders string
+		want           int
+		wantOrigin     string
+	}{
+		{"simple request", "GET", "https://app.example.com", "", "", http.StatusOK, "https://app.example.com"},
+		{"wildcard origin", "GET", "https://eu.example.org", "", "", http.StatusOK, "https://eu.example.org"},
+		{"wildcard needs a subdomain", "GET", "https://.example.org", "", "", http.StatusOK, ""},
+		{"other origin", "GET", "https://evil.example.com", "", "", http.StatusOK, ""},
+		{"same origin", "GET", "", "", "", http.StatusOK, ""},
+		{"preflight", "OPTIONS", "https://app.example.com", "POST", "content-type, authorization", http.StatusNoContent, "https://app.example.com"},
+		{"preflight for other origin", "OPTIONS", "https://evil.example.com", "POST", "", http.StatusNoContent, ""},
+		{"preflight for other method", "OPTIONS", "https://app.example.com", "DELETE", "", http.StatusNoContent, ""},
+		{"preflight for other header", "OPTIONS", "https://app.example.com", "POST", "X-Custom", http.StatusNoContent, ""},
+		{"plain options", "OPTIONS", "https://app.example.com", "", "", http.StatusMethodNotAllowed, "https://app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/collections", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			if tt.requestHeaders != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.requestHeaders)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("wrong Access-Control-Allow-Origin: got %q want %q", got, tt.wantOrigin)
+			}
+		})
+	}
+}
+
+func TestCORSHeaders(t *testing.T) {
+	policy := middleware.CORSPolicy{
+		AllowedOrigins:   []string{"*"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{"ETag", "RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	handler := middleware.CORSMiddleware(policy)(testHandler())
+
+	// Preflight
+	req := httptest.NewRequest("OPTIONS", "/api/collections", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://anywhere.example",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, PUT",
+		"Access-Control-Allow-Headers":     "X-Custom",
+		"Access-Control-Max-Age":           "600",
+	}
+	for header, value := range want {
+		if got := rr.Header().Get(header); got != value {
+			t.Errorf("wrong preflight %s: got %q want %q", header, got, value)
+		}
+	}
+
+	// Actual request
+	req = httptest.NewRequest("GET", "/api/collections", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("Access-Control-Expose-Headers"); got != "ETag, RateLimit-Remaining" {
+		t.Errorf("wrong Access-Control-Expose-Headers: got %q want %q", got, "ETag, RateLimit-Remaining")
+	}
+	if got := rr.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("wrong Vary: got %q want %q", got, "Origin")
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/auth"
+)
+
+// TokenVerifier validates bearer tokens and resolves them to principals
+type TokenVerifier interface {
+	Verify(token string) (*auth.Principal, error)
+}
+
+// JWTMiddleware authenticates requests carrying a bearer token other than an API key,
+// and hands every other request to fallback
+func JWTMiddleware(verifier TokenVerifier, fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fallbackHandler := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" || auth.IsAPIKey(token) {
+				fallbackHandler.ServeHTTP(w, r)
+				return
+			}
+
+			// Check the token
+			principal, err := verifier.Verify(token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				api.RespondWithError(w, http.StatusUnauthorized, "INVALID_TOKEN", err.Error())
+				return
+			}
+
+			// Authentication successful, proceed with the token's principal
+			next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), principal)))
+		})
+	}
+}
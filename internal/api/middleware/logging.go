@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -34,7 +34,20 @@ func (rww *ResponseWriterWrapper) Write(b []byte) (int, error) {
 	return size, err
 }
 
-// LoggingMiddleware logs HTTP requests with standard details
+// Flush sends buffered data to the client if the underlying writer supports it
+func (rww *ResponseWriterWrapper) Flush() {
+	if flusher, ok := rww.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (rww *ResponseWriterWrapper) Unwrap() http.ResponseWriter {
+	return rww.ResponseWriter
+}
+
+// LoggingMiddleware logs every request with its method, path, status, size, duration and
+// request ID. Server errors are logged at error level and client errors at warn level.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -45,18 +58,21 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		// Process request
 		next.ServeHTTP(rww, r)
 
-		// Calculate duration
-		duration := time.Since(start)
-
-		// Log request details in industry standard format
-		log.Printf(
-			"%s | %s | %s | %d | %d bytes | %s",
-			time.Now().Format(time.RFC3339),
-			r.Method,
-			r.URL.Path,
-			rww.StatusCode,
-			rww.Size,
-			duration,
+		level := slog.LevelInfo
+		switch {
+		case rww.StatusCode >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case rww.StatusCode >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		slog.Log(r.Context(), level, "Request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rww.StatusCode,
+			"bytes", rww.Size,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"remote_addr", r.RemoteAddr,
 		)
 	})
 }
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/metrics"
+)
+
+// RequestMetrics counts and times HTTP requests
+type RequestMetrics struct {
+	requests *metrics.CounterVec
+	duration *metrics.HistogramVec
+	inFlight *metrics.Gauge
+}
+
+// NewRequestMetrics registers the HTTP request metrics
+func NewRequestMetrics(registry *metrics.Registry) *RequestMetrics {
+	return &RequestMetrics{
+		requests: registry.NewCounterVec("flexstore_http_requests_total", "HTTP requests by method, route and status.", "method", "route", "status"),
+		duration: registry.NewHistogramVec("flexstore_http_request_duration_seconds", "HTTP request latency by method, route and status.", metrics.DefaultBuckets, "method", "route", "status"),
+		inFlight: registry.NewGauge("flexstore_http_requests_in_flight", "HTTP requests being served."),
+	}
+}
+
+// MetricsMiddleware records the count, latency and status of requests, labelled with the
+// route template route returns rather than the raw path, so the number of series stays bounded
+func MetricsMiddleware(m *RequestMetrics, route func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			m.inFlight.Inc()
+			defer m.inFlight.Dec()
+
+			// Create response writer wrapper to capture status
+			rww := NewResponseWriterWrapper(w)
+			next.ServeHTTP(rww, r)
+
+			status := strconv.Itoa(rww.StatusCode)
+			template := route(r)
+			m.requests.Inc(r.Method, template, status)
+			m.duration.Observe(time.Since(start).Seconds(), r.Method, template, status)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+	"github.com/rbehzadan/flexstore/internal/metrics"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/collections/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods("GET")
+
+	route := func(r *http.Request) string {
+		var match mux.RouteMatch
+		if router.Match(r, &match) && match.Route != nil {
+			template, _ := match.Route.GetPathTemplate()
+			return template
+		}
+		return "unmatched"
+	}
+
+	registry := metrics.NewRegistry()
+	handler := middleware.MetricsMiddleware(middleware.NewRequestMetrics(registry), route)(router)
+
+	for _, path := range []string{"/api/collections/users", "/api/collections/orders", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	var b strings.Builder
+	if err := registry.Write(&b); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	output := b.String()
+
+	// Requests are labelled by route template, not by raw path
+	for _, want := range []string{
+		`flexstore_http_requests_total{method="GET",route="/api/collections/{name}",status="404"} 2`,
+		`flexstore_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`flexstore_http_request_duration_seconds_count{method="GET",route="/api/collections/{name}",status="404"} 2`,
+		`flexstore_http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "/api/collections/users") {
+		t.Errorf("raw path in output\n%s", output)
+	}
+}
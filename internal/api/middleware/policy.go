@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/rbehzadan/flexstore/internal/auth"
+)
+
+// AuthPolicy decides which requests may be served without authentication
+type AuthPolicy struct {
+	// PublicPaths are served without authentication; a trailing * matches every path with that prefix
+	PublicPaths []string
+
+	// AnonymousMethods are HTTP methods allowed without authentication on the routes
+	// AnonymousRoute accepts, e.g. GET and HEAD for anonymous reads with authenticated writes
+	AnonymousMethods []string
+
+	// AnonymousRoute reports whether a request is to a route that AnonymousMethods apply to;
+	// nil applies them to no route
+	AnonymousRoute func(*http.Request) bool
+}
+
+// IsPublic reports whether a request may be served without authentication
+func (p AuthPolicy) IsPublic(r *http.Request) bool {
+	return p.Anonymous(r) != auth.NotAnonymous
+}
+
+// Anonymous tells why a request may be served without authentication, if it may
+func (p AuthPolicy) Anonymous(r *http.Request) auth.Anonymous {
+	for _, path := range p.PublicPaths {
+		if prefix, ok := strings.CutSuffix(path, "*"); ok {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return auth.AnonymousPath
+			}
+		} else if r.URL.Path == path {
+			return auth.AnonymousPath
+		}
+	}
+
+	if p.AnonymousRoute != nil && p.AnonymousRoute(r) {
+		for _, method := range p.AnonymousMethods {
+			if strings.EqualFold(method, r.Method) {
+				return auth.AnonymousMethod
+			}
+		}
+	}
+
+	return auth.NotAnonymous
+}
+
+// AuthPolicyMiddleware requires authentication through authenticate for every request
+// that the policy does not allow anonymously
+func AuthPolicyMiddleware(policy AuthPolicy, authenticate func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := authenticate(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if anonymous := policy.Anonymous(r); anonymous != auth.NotAnonymous {
+				next.ServeHTTP(w, r.WithContext(auth.WithAnonymous(r.Context(), anonymous)))
+				return
+			}
+			authenticated.ServeHTTP(w, r)
+		})
+	}
+}
+
+// OpenAccessMiddleware marks every request as served with authentication disabled, which
+// lifts the access checks of handlers. Without it, requests lacking a principal are denied.
+func OpenAccessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(auth.WithAnonymous(r.Context(), auth.AnonymousOpen)))
+	})
+}
@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// fakeUsers authenticates a fixed set of usernames and passwords
+type fakeUsers map[string]string
+
+func (u fakeUsers) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	if stored, ok := u[username]; ok && stored == password {
+		return &models.User{Username: username}, nil
+	}
+	return nil, fmt.Errorf("invalid username or password")
+}
+
+func TestAuthPolicyMiddleware(t *testing.T) {
+	policy := middleware.AuthPolicy{
+		PublicPaths:      []string{"/health", "/public/*"},
+		AnonymousMethods: []string{"GET"},
+		AnonymousRoute: func(r *http.Request) bool {
+			return strings.HasPrefix(r.URL.Path, "/api/collections")
+		},
+	}
+	auth := middleware.BasicAuthMiddleware(fakeUsers{"admin": "secret"})
+	handler := middleware.AuthPolicyMiddleware(policy, auth)(testHandler())
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		withAuth bool
+		want     int
+	}{
+		{"public path", "POST", "/health", false, http.StatusOK},
+		{"public prefix", "DELETE", "/public/files/1", false, http.StatusOK},
+		{"anonymous read", "GET", "/api/collections", false, http.StatusOK},
+		{"anonymous write", "POST", "/api/collections", false, http.StatusUnauthorized},
+		{"anonymous read of other route", "GET", "/api/users", false, http.StatusUnauthorized},
+		{"authenticated write", "POST", "/api/collections", true, http.StatusOK},
+		{"prefix without wildcard", "POST", "/health/deep", false, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.withAuth {
+				req.SetBasicAuth("admin", "secret")
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/auth"
+)
+
+// Rate limit classes; each class has its own limit and its own bucket per client
+const (
+	RateLimitRead  = "read"
+	RateLimitWrite = "write"
+	RateLimitBulk  = "bulk"
+
+	// RateLimitAuthFailure counts failed authentications per IP address
+	RateLimitAuthFailure = "auth_failure"
+)
+
+// rateLimitPruneInterval is how often buckets that have refilled completely are dropped
+const rateLimitPruneInterval = time.Minute
+
+// RateLimit allows a burst of Requests, refilled evenly over Window
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// enabled reports whether the limit restricts anything
+func (l RateLimit) enabled() bool {
+	return l.Requests > 0 && l.Window > 0
+}
+
+// refillRate returns the number of requests regained per second
+func (l RateLimit) refillRate() float64 {
+	return float64(l.Requests) / l.Window.Seconds()
+}
+
+// bucketKey identifies the bucket of a client in one class
+type bucketKey struct {
+	class  string
+	client string
+}
+
+// bucket holds the tokens left to a client in one class
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter keeps a token bucket per client and class
+type RateLimiter struct {
+	limits map[string]RateLimit
+
+	mu         sync.Mutex
+	buckets    map[bucketKey]*bucket
+	lastPruned time.Time
+}
+
+// NewRateLimiter creates a rate limiter with limits per class; classes without a limit are not restricted
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limits:     limits,
+		buckets:    make(map[bucketKey]*bucket),
+		lastPruned: time.Now(),
+	}
+}
+
+// RateLimitResult describes the state of a client's bucket after a request
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+
+	// RetryAfter is the time until the next request is allowed, if this one was not
+	RetryAfter time.Duration
+}
+
+// Allow takes a token from the client's bucket for the class. The second result is false
+// when the class is not limited.
+func (l *RateLimiter) Allow(class, client string, now time.Time) (RateLimitResult, bool) {
+	return l.take(class, client, now, 1)
+}
+
+// Check reports whether the client's bucket for the class holds a token, without taking it
+func (l *RateLimiter) Check(class, client string, now time.Time) (RateLimitResult, bool) {
+	return l.take(class, client, now, 0)
+}
+
+// take takes cost tokens from the client's bucket for the class if it holds at least one
+func (l *RateLimiter) take(class, client string, now time.Time, cost float64) (RateLimitResult, bool) {
+	limit := l.limits[class]
+	if !limit.enabled() {
+		return RateLimitResult{}, false
+	}
+	rate := limit.refillRate()
+	capacity := float64(limit.Requests)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+
+	// Refill the bucket for the time since it was last used
+	key := bucketKey{class: class, client: client}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := RateLimitResult{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens -= cost
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = secondsToDuration((capacity - b.tokens) / rate)
+	return result, true
+}
+
+// prune drops buckets that have refilled completely, which are the same as no bucket
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPruned) < rateLimitPruneInterval {
+		return
+	}
+	l.lastPruned = now
+
+	for key, b := range l.buckets {
+		limit := l.limits[key.class]
+		if !limit.enabled() || b.tokens+now.Sub(b.updated).Seconds()*limit.refillRate() >= float64(limit.Requests) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// secondsToDuration converts a number of seconds to a duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// ceilSeconds formats a duration as a whole number of seconds, rounded up
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// RateLimitClient returns the key requests are counted under: the authenticated API key or
+// user, or the client's IP address for anonymous requests
+func RateLimitClient(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return principal.Subject()
+	}
+	return clientIP(r)
+}
+
+// clientIP returns the key of the client's IP address
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// RateLimitMiddleware limits the request rate of each client in the class classify assigns
+// to the request. It reports the client's quota in RateLimit-* headers and rejects requests
+// beyond it with 429 Too Many Requests and a Retry-After header.
+func RateLimitMiddleware(limiter *RateLimiter, classify func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, limited := limiter.Allow(classify(r), RateLimitClient(r), time.Now())
+			if !limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			if !result.Allowed {
+				retryAfter := ceilSeconds(result.RetryAfter)
+				w.Header().Set("Retry-After", retryAfter)
+				message := fmt.Sprintf("Rate limit exceeded, retry in %s seconds", retryAfter)
+				api.RespondWithError(w, http.StatusTooManyRequests, "RATE_LIMITED", message)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthFailureLimitMiddleware limits failed authentications through authenticate per IP
+// address in the RateLimitAuthFailure class of limiter. Every response with 401 Unauthorized
+// takes a token, and clients without tokens left are rejected with 429 Too Many Requests
+// before their credentials are checked, which bounds password guessing and the cost of
+// hashing guesses.
+func AuthFailureLimitMiddleware(limiter *RateLimiter, authenticate func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		authenticated := authenticate(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientIP(r)
+			result, limited := limiter.Check(RateLimitAuthFailure, client, time.Now())
+			if !limited {
+				authenticated.ServeHTTP(w, r)
+				return
+			}
+			if !result.Allowed {
+				retryAfter := ceilSeconds(result.RetryAfter)
+				w.Header().Set("Retry-After", retryAfter)
+				message := fmt.Sprintf("Too many failed authentications, retry in %s seconds", retryAfter)
+				api.RespondWithError(w, http.StatusTooManyRequests, "TOO_MANY_AUTH_FAILURES", message)
+				return
+			}
+
+			rww := NewResponseWriterWrapper(w)
+			authenticated.ServeHTTP(rww, r)
+			if rww.StatusCode == http.StatusUnauthorized {
+				limiter.Allow(RateLimitAuthFailure, client, time.Now())
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := middleware.NewRateLimiter(map[string]middleware.RateLimit{
+		middleware.RateLimitRead:  {Requests: 2, Window: time.Hour},
+		middleware.RateLimitWrite: {Requests: 1, Window: time.Hour},
+	})
+	classify := func(r *http.Request) string {
+		switch r.Method {
+		case http.MethodGet:
+			return middleware.RateLimitRead
+		case http.MethodPost:
+			return middleware.RateLimitWrite
+		}
+		return middleware.RateLimitBulk
+	}
+	handler := middleware.RateLimitMiddleware(limiter, classify)(testHandler())
+
+	tests := []struct {
+		name          string
+		method        string
+		remoteAddr    string
+		want          int
+		wantRemaining string
+	}{
+		{"first read", "GET", "10.0.0.1:1000", http.StatusOK, "1"},
+		{"second read", "GET", "10.0.0.1:1001", http.StatusOK, "0"},
+		{"read over limit", "GET", "10.0.0.1:1002", http.StatusTooManyRequests, "0"},
+		{"other client", "GET", "10.0.0.2:1000", http.StatusOK, "1"},
+		{"separate write limit", "POST", "10.0.0.1:1000", http.StatusOK, "0"},
+		{"write over limit", "POST", "10.0.0.1:1000", http.StatusTooManyRequests, "0"},
+		{"unlimited class", "PUT", "10.0.0.1:1000", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/collections", nil)
+			req.RemoteAddr = tt.remoteAddr
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+			if got := rr.Header().Get("RateLimit-Remaining"); got != tt.wantRemaining {
+				t.Errorf("wrong RateLimit-Remaining: got %q want %q", got, tt.wantRemaining)
+			}
+
+			retryAfter := rr.Header().Get("Retry-After")
+			if tt.want == http.StatusTooManyRequests {
+				if seconds, err := strconv.Atoi(retryAfter); err != nil || seconds < 1 {
+					t.Errorf("wrong Retry-After: got %q want a positive number of seconds", retryAfter)
+				}
+			} else if retryAfter != "" {
+				t.Errorf("unexpected Retry-After on allowed request: %q", retryAfter)
+			}
+		})
+	}
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	limiter := middleware.NewRateLimiter(map[string]middleware.RateLimit{
+		middleware.RateLimitWrite: {Requests: 2, Window: time.Minute},
+	})
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		if result, _ := limiter.Allow(middleware.RateLimitWrite, "user:alice", now); !result.Allowed {
+			t.Fatalf("request %d within the limit was rejected", i+1)
+		}
+	}
+	result, _ := limiter.Allow(middleware.RateLimitWrite, "user:alice", now)
+	if result.Allowed {
+		t.Fatalf("request over the limit was allowed")
+	}
+	if result.RetryAfter != 30*time.Second {
+		t.Errorf("wrong retry after: got %v want %v", result.RetryAfter, 30*time.Second)
+	}
+
+	// One request is regained every 30 seconds
+	result, _ = limiter.Allow(middleware.RateLimitWrite, "user:alice", now.Add(30*time.Second))
+	if !result.Allowed {
+		t.Errorf("request after refill was rejected")
+	}
+}
+
+func TestAuthFailureLimitMiddleware(t *testing.T) {
+	limiter := middleware.NewRateLimiter(map[string]middleware.RateLimit{
+		middleware.RateLimitAuthFailure: {Requests: 2, Window: time.Hour},
+	})
+	auth := middleware.BasicAuthMiddleware(fakeUsers{"admin": "secret"})
+	handler := middleware.AuthFailureLimitMiddleware(limiter, auth)(testHandler())
+
+	tests := []struct {
+		name       string
+		password   string
+		remoteAddr string
+		want       int
+	}{
+		{"valid credentials are not counted", "secret", "10.0.0.1:1000", http.StatusOK},
+		{"first failure", "guess1", "10.0.0.1:1000", http.StatusUnauthorized},
+		{"second failure", "guess2", "10.0.0.1:1001", http.StatusUnauthorized},
+		{"further guess rejected", "guess3", "10.0.0.1:1002", http.StatusTooManyRequests},
+		{"valid credentials rejected too", "secret", "10.0.0.1:1003", http.StatusTooManyRequests},
+		{"other client", "guess1", "10.0.0.2:1000", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/collections", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.SetBasicAuth("admin", tt.password)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+			if tt.want == http.StatusTooManyRequests && rr.Header().Get("Retry-After") == "" {
+				t.Error("Retry-After header missing")
+			}
+		})
+	}
+}
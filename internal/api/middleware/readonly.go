@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+)
+
+// ReadOnlyMiddleware rejects mutating requests, used when the instance runs as a replica
+func ReadOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+		default:
+			api.RespondWithError(w, http.StatusForbidden, "READ_ONLY_REPLICA", "This instance is a read-only replica")
+		}
+	})
+}
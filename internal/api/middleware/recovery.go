@@ -2,7 +2,7 @@ package middleware
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"runtime/debug"
 
@@ -14,7 +14,7 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("PANIC: %v\n%s", err, debug.Stack())
+				slog.ErrorContext(r.Context(), "Recovered from panic", "panic", err, "stack", string(debug.Stack()))
 
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusInternalServerError)
@@ -22,8 +22,9 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 				resp := api.Response{
 					Status: "error",
 					Error: &api.ErrorInfo{
-						Code:    "INTERNAL_SERVER_ERROR",
-						Message: "An unexpected error occurred",
+						Code:      "INTERNAL_SERVER_ERROR",
+						Message:   "An unexpected error occurred",
+						RequestID: RequestID(r.Context()),
 					},
 				}
 				json.NewEncoder(w).Encode(resp)
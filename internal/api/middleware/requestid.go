@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/logging"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = api.RequestIDHeader
+
+// maxRequestIDLength limits the length of request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware assigns every request an ID, reusing a well-formed ID sent by the
+// client, and returns it in the response. The ID is carried in the request context, so
+// records logged with the context are tagged with it.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// RequestID returns the ID assigned to a request by RequestIDMiddleware
+func RequestID(ctx context.Context) string {
+	return logging.RequestID(ctx)
+}
+
+// newRequestID generates a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID reports whether a client-supplied request ID is safe to log and store
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
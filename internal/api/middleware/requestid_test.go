@@ -0,0 +1,68 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := middleware.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = middleware.RequestID(r.Context())
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"generated", "", false},
+		{"client ID", "build-42.step:3", true},
+		{"invalid client ID", "bad id\n", false},
+		{"oversized client ID", strings.Repeat("a", 200), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				req.Header.Set(middleware.RequestIDHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			got := rr.Header().Get(middleware.RequestIDHeader)
+			if got == "" || got != seen {
+				t.Errorf("response ID %q does not match request ID %q", got, seen)
+			}
+			if (got == tt.header) != tt.keep {
+				t.Errorf("wrong request ID: got %q for header %q", got, tt.header)
+			}
+		})
+	}
+}
+
+func TestRequestIDInErrorResponse(t *testing.T) {
+	handler := middleware.RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.RespondWithError(w, http.StatusNotFound, "DOCUMENT_NOT_FOUND", "Document not found")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var response api.Response
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Error == nil || response.Error.RequestID != "req-1" {
+		t.Errorf("got error %+v want request ID %q", response.Error, "req-1")
+	}
+}
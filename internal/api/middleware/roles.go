@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// GrantResolver resolves the collection permissions granted to a principal by its roles
+type GrantResolver interface {
+	GrantsFor(ctx context.Context, principal *auth.Principal) ([]models.Grant, error)
+}
+
+// RoleMiddleware attaches the grants of the authenticated principal's roles to the principal.
+// Requests without a principal are passed through.
+func RoleMiddleware(roles GrantResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.FromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			grants, err := roles.GrantsFor(r.Context(), principal)
+			if api.RespondWithContextError(w, err) {
+				return
+			}
+			if err != nil {
+				api.RespondWithError(w, http.StatusInternalServerError, "RESOLVE_ROLES_ERROR", err.Error())
+				return
+			}
+			principal.Grants = grants
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
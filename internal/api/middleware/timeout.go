@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Query timeout classes; each class has its own timeout
+const (
+	QueryTimeoutRead  = "read"
+	QueryTimeoutWrite = "write"
+	QueryTimeoutBulk  = "bulk"
+
+	// QueryTimeoutStream is for responses streamed for as long as the client keeps reading,
+	// which no fixed timeout fits
+	QueryTimeoutStream = "stream"
+)
+
+// QueryTimeoutMiddleware bounds the context of each request by the timeout of its class, so
+// the database queries it runs are canceled once the timeout passes. Classes without a
+// timeout are not bounded; the request context still ends when the client goes away.
+func QueryTimeoutMiddleware(timeouts map[string]time.Duration, classify func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := timeouts[classify(r)]
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+)
+
+func TestQueryTimeoutMiddleware(t *testing.T) {
+	timeouts := map[string]time.Duration{
+		middleware.QueryTimeoutRead:  time.Hour,
+		middleware.QueryTimeoutWrite: 0,
+	}
+	classify := func(r *http.Request) string {
+		if r.Method == http.MethodGet {
+			return middleware.QueryTimeoutRead
+		}
+		return middleware.QueryTimeoutWrite
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		wantDeadline bool
+	}{
+		{"read with timeout", "GET", true},
+		{"write without timeout", "POST", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadline time.Time
+			var hasDeadline bool
+			handler := middleware.QueryTimeoutMiddleware(timeouts, classify)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, hasDeadline = r.Context().Deadline()
+			}))
+
+			start := time.Now()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, "/api/collections", nil))
+
+			if hasDeadline != tt.wantDeadline {
+				t.Fatalf("wrong deadline presence: got %v want %v", hasDeadline, tt.wantDeadline)
+			}
+			if hasDeadline && (deadline.Before(start.Add(time.Hour)) || deadline.After(time.Now().Add(time.Hour))) {
+				t.Errorf("wrong deadline: got %v after start want %v", deadline.Sub(start), time.Hour)
+			}
+		})
+	}
+}
+
+func TestQueryTimeoutResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		cancel   bool
+		want     int
+		wantCode string
+	}{
+		{"deadline exceeded", time.Millisecond, false, http.StatusGatewayTimeout, "QUERY_TIMEOUT"},
+		{"client went away", time.Hour, true, http.StatusServiceUnavailable, "REQUEST_CANCELED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeouts := map[string]time.Duration{middleware.QueryTimeoutRead: tt.timeout}
+			classify := func(*http.Request) string { return middleware.QueryTimeoutRead }
+
+			// The handler stands in for a query that runs until its context ends
+			handler := middleware.QueryTimeoutMiddleware(timeouts, classify)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+				if !api.RespondWithContextError(w, r.Context().Err()) {
+					t.Error("context error was not reported")
+				}
+			}))
+
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			req := httptest.NewRequest("GET", "/api/collections", nil).WithContext(ctx)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+
+			var response api.Response
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Error unmarshaling response: %v", err)
+			}
+			if response.Error == nil || response.Error.Code != tt.wantCode {
+				t.Errorf("wrong error info: got %+v want code %v", response.Error, tt.wantCode)
+			}
+		})
+	}
+}
@@ -1,22 +1,53 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+
+	"github.com/rbehzadan/flexstore/internal/logging"
 )
 
-// RespondWithError sends an error response
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// RespondWithError sends an error response, echoing the request ID so clients can quote it.
+// Server errors are logged with the request ID to correlate them with the failed call.
 func RespondWithError(w http.ResponseWriter, statusCode int, errorCode, message string) {
+	requestID := w.Header().Get(RequestIDHeader)
+	if statusCode >= http.StatusInternalServerError {
+		slog.Error("Request failed", logging.RequestIDKey, requestID, "status", statusCode, "code", errorCode, "error", message)
+	}
+
 	response := Response{
 		Status: "error",
 		Error: &ErrorInfo{
-			Code:    errorCode,
-			Message: message,
+			Code:      errorCode,
+			Message:   message,
+			RequestID: requestID,
 		},
 	}
 	RespondWithJSON(w, statusCode, response)
 }
 
+// RespondWithContextError responds to an error caused by the request's context and reports
+// whether it did: 504 Gateway Timeout when the database did not answer before the deadline of
+// the request, and 503 Service Unavailable when the request was canceled, usually because the
+// client went away
+func RespondWithContextError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		RespondWithError(w, http.StatusGatewayTimeout, "QUERY_TIMEOUT", "The database did not answer in time")
+	case errors.Is(err, context.Canceled):
+		RespondWithError(w, http.StatusServiceUnavailable, "REQUEST_CANCELED", "The request was canceled")
+	default:
+		return false
+	}
+	return true
+}
+
 // RespondWithJSON sends a JSON response
 func RespondWithJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 	// If data is already a Response, use it directly
@@ -1,5 +1,9 @@
 package api
 
+import (
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
 // Response is the standard API response structure
 type Response struct {
 	Status string      `json:"status"`
@@ -12,6 +16,9 @@ type Response struct {
 type ErrorInfo struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+
+	// RequestID identifies the request in the server logs
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // MetaInfo contains metadata like pagination
@@ -27,4 +34,6 @@ type HealthResponse struct {
 	Status  string `json:"status"`
 	Version string `json:"version"`
 	Uptime  string `json:"uptime"`
+
+	Replication *models.ReplicationStatus `json:"replication,omitempty"`
 }
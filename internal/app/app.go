@@ -1,30 +1,80 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rbehzadan/flexstore/internal/api/handlers"
 	"github.com/rbehzadan/flexstore/internal/api/middleware"
+	"github.com/rbehzadan/flexstore/internal/auth"
 	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/health"
+	"github.com/rbehzadan/flexstore/internal/jobs"
+	"github.com/rbehzadan/flexstore/internal/metrics"
+	"github.com/rbehzadan/flexstore/internal/models"
+	"github.com/rbehzadan/flexstore/internal/replication"
 	"github.com/rbehzadan/flexstore/internal/service"
 	"github.com/rbehzadan/flexstore/pkg/config"
 )
 
+// expirySweepBatchSize is the number of expired documents deleted per transaction
+const expirySweepBatchSize = 500
+
+// jwtLeeway tolerates clock skew between the server and the token issuer
+const jwtLeeway = 30 * time.Second
+
 // App represents the application
 type App struct {
-	Router            *mux.Router
-	DB                *db.DB
-	CollectionService *service.CollectionService
-	DocumentService   *service.DocumentService
-	Config            *config.Config
+	Router             *mux.Router
+	Handler            http.Handler
+	DB                 *db.DB
+	CollectionService  *service.CollectionService
+	DocumentService    *service.DocumentService
+	ReplicationService *service.ReplicationService
+	TrashService       *service.TrashService
+	APIKeyService      *service.APIKeyService
+	RoleService        *service.RoleService
+	AuditService       *service.AuditService
+	UserService        *service.UserService
+	Follower           *replication.Follower
+	JWTVerifier        *auth.JWTVerifier
+	Jobs               []*jobs.Periodic
+	Metrics            *metrics.Registry
+	Health             *health.Checker
+	Config             *config.Config
+
+	// stopWorkers cancels the context the follower and the background jobs run under
+	stopWorkers context.CancelFunc
+}
+
+// DatabaseConfig returns the database configuration described by the application configuration
+func DatabaseConfig(cfg *config.Config) *db.Config {
+	dbConfig := db.NewConfig(cfg.SqlitePath)
+	dbConfig.ReadConnections = cfg.DBReadConnections
+	dbConfig.BusyTimeout = cfg.DBBusyTimeout
+	dbConfig.Synchronous = cfg.DBSynchronous
+	dbConfig.SoftDelete = cfg.SoftDelete
+	dbConfig.Limits = models.DocumentLimits{
+		MaxRequestBytes:  cfg.MaxRequestBytes,
+		MaxDocumentBytes: cfg.MaxDocumentBytes,
+		MaxDepth:         cfg.MaxJSONDepth,
+		MaxArrayLength:   cfg.MaxArrayLength,
+		MaxKeys:          cfg.MaxObjectKeys,
+	}
+	return dbConfig
 }
 
 // NewApp initializes the application
 func NewApp(cfg *config.Config) (*App, error) {
 	// Initialize database
-	dbConfig := db.NewConfig(cfg.SqlitePath)
-	database, err := db.New(dbConfig)
+	database, err := db.New(DatabaseConfig(cfg))
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
@@ -32,46 +82,214 @@ func NewApp(cfg *config.Config) (*App, error) {
 	// Initialize repositories
 	collectionRepo := db.NewCollectionRepository(database)
 	documentRepo := db.NewDocumentRepository(database, collectionRepo)
+	changeRepo := db.NewChangeRepository(database)
+	trashRepo := db.NewTrashRepository(database)
+	apiKeyRepo := db.NewAPIKeyRepository(database)
+	roleRepo := db.NewRoleRepository(database)
+	userRepo := db.NewUserRepository(database)
+	auditRepo := db.NewAuditRepository(database)
 
 	// Initialize services
 	collectionService := service.NewCollectionService(collectionRepo)
 	documentService := service.NewDocumentService(documentRepo)
+	replicationService := service.NewReplicationService(changeRepo)
+	trashService := service.NewTrashService(trashRepo)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+	roleService := service.NewRoleService(roleRepo)
+	userService := service.NewUserService(userRepo, roleService)
+	auditService := service.NewAuditService(auditRepo)
+
+	// Startup work runs to completion
+	ctx := context.Background()
+
+	// Create the first administrator on a fresh database
+	if cfg.EnableBasicAuth {
+		created, generated, err := userService.Bootstrap(ctx, cfg.AuthUsername, cfg.AuthPassword)
+		if err != nil {
+			database.Close()
+			return nil, err
+		}
+		if created && generated != "" {
+			// Keep the password out of the logs, which are often collected and kept
+			path, err := saveGeneratedPassword(cfg.SqlitePath, generated)
+			if err != nil {
+				slog.Warn("Failed to save generated password, printing it to stderr", "error", err)
+				fmt.Fprintf(os.Stderr, "Generated password for admin user '%s': %s\n", cfg.AuthUsername, generated)
+				path = "stderr"
+			}
+			slog.Warn("Created admin user with generated password", "username", cfg.AuthUsername, "password_in", path)
+		} else if created {
+			slog.Info("Created admin user", "username", cfg.AuthUsername)
+		}
+	}
+
+	// Initialize replication follower if configured
+	var follower *replication.Follower
+	if cfg.ReplicateFrom != "" {
+		follower, err = replication.NewFollower(ctx, cfg.ReplicateFrom, cfg.ReplicationInterval, replicationService)
+		if err != nil {
+			database.Close()
+			return nil, fmt.Errorf("failed to initialize replication: %w", err)
+		}
+	}
+
+	// Load keys for JWT bearer tokens if configured
+	var jwtVerifier *auth.JWTVerifier
+	if cfg.JWTKeysFile != "" {
+		keys, err := auth.LoadJWTKeys(cfg.JWTKeysFile)
+		if err != nil {
+			database.Close()
+			return nil, fmt.Errorf("failed to initialize JWT authentication: %w", err)
+		}
+		jwtVerifier = auth.NewJWTVerifier(keys, auth.JWTConfig{
+			Issuer:           cfg.JWTIssuer,
+			Audience:         cfg.JWTAudience,
+			RolesClaim:       cfg.JWTRolesClaim,
+			CollectionsClaim: cfg.JWTCollectionsClaim,
+			Leeway:           jwtLeeway,
+		})
+	}
 
 	// Initialize router
 	router := mux.NewRouter()
 
 	app := &App{
-		Router:            router,
-		DB:                database,
-		CollectionService: collectionService,
-		DocumentService:   documentService,
-		Config:            cfg,
+		Router:             router,
+		DB:                 database,
+		CollectionService:  collectionService,
+		DocumentService:    documentService,
+		ReplicationService: replicationService,
+		TrashService:       trashService,
+		APIKeyService:      apiKeyService,
+		RoleService:        roleService,
+		AuditService:       auditService,
+		UserService:        userService,
+		Follower:           follower,
+		JWTVerifier:        jwtVerifier,
+		Metrics:            metrics.NewRegistry(),
+		Config:             cfg,
 	}
 
+	app.setupMetrics()
+	app.setupJobs()
+	app.setupHealth()
 	app.setupRoutes()
+	app.setupHandler()
 	return app, nil
 }
 
+// setupHandler wraps the router in the middleware that must run before routing
+func (a *App) setupHandler() {
+	a.Handler = a.Router
+
+	// Answer CORS preflight requests, which match no route
+	if len(a.Config.CORSAllowedOrigins) > 0 {
+		a.Handler = middleware.CORSMiddleware(middleware.CORSPolicy{
+			AllowedOrigins:   a.Config.CORSAllowedOrigins,
+			AllowedMethods:   a.Config.CORSAllowedMethods,
+			AllowedHeaders:   a.Config.CORSAllowedHeaders,
+			ExposedHeaders:   a.Config.CORSExposedHeaders,
+			AllowCredentials: a.Config.CORSAllowCredentials,
+			MaxAge:           a.Config.CORSMaxAge,
+		})(a.Handler)
+	}
+
+	// Count and time every request, including those answered before routing
+	a.Handler = middleware.MetricsMiddleware(middleware.NewRequestMetrics(a.Metrics), a.routeTemplate)(a.Handler)
+}
+
 // setupRoutes configures the routes
 func (a *App) setupRoutes() {
 	// Register middleware
+	a.Router.Use(middleware.RequestIDMiddleware)
 	a.Router.Use(middleware.LoggingMiddleware)
 	a.Router.Use(middleware.RecoveryMiddleware)
+	if a.Config.MaxRequestBytes > 0 {
+		a.Router.Use(middleware.BodyLimitMiddleware(a.Config.MaxRequestBytes))
+	}
+
+	// Bound the database work of each request, including the lookups of authentication;
+	// streams have no timeout and end when their client goes away
+	a.Router.Use(middleware.QueryTimeoutMiddleware(map[string]time.Duration{
+		middleware.QueryTimeoutRead:  a.Config.QueryTimeoutRead,
+		middleware.QueryTimeoutWrite: a.Config.QueryTimeoutWrite,
+		middleware.QueryTimeoutBulk:  a.Config.QueryTimeoutBulk,
+	}, queryTimeoutClass))
+
+	// Require authentication on every route the policy does not make public,
+	// by API key, JWT, client certificate or basic auth, resolve the caller's roles and check the scopes it grants
+	if a.Config.EnableBasicAuth {
+		policy := middleware.AuthPolicy{
+			PublicPaths:      a.Config.AuthPublicPaths,
+			AnonymousMethods: a.Config.AuthAnonymousMethods,
+			AnonymousRoute:   anonymousRoute,
+		}
+		authMiddleware := middleware.BasicAuthMiddleware(a.UserService)
+		if a.Config.TLSClientCAFile != "" {
+			authMiddleware = middleware.ClientCertMiddleware(authMiddleware)
+		}
+		if a.JWTVerifier != nil {
+			authMiddleware = middleware.JWTMiddleware(a.JWTVerifier, authMiddleware)
+		}
+		authMiddleware = middleware.APIKeyMiddleware(a.APIKeyService, authMiddleware)
+
+		// Limit failed authentications per IP address, checked before any credentials are
+		if a.Config.RateLimitAuthFailures > 0 {
+			failures := middleware.NewRateLimiter(map[string]middleware.RateLimit{
+				middleware.RateLimitAuthFailure: {Requests: a.Config.RateLimitAuthFailures, Window: time.Minute},
+			})
+			authMiddleware = middleware.AuthFailureLimitMiddleware(failures, authMiddleware)
+		}
+		a.Router.Use(middleware.AuthPolicyMiddleware(policy, authMiddleware))
+		a.Router.Use(middleware.RoleMiddleware(a.RoleService))
+		a.Router.Use(middleware.AuthorizeMiddleware(requiredScope))
+	} else {
+		a.Router.Use(middleware.OpenAccessMiddleware)
+	}
+
+	// Limit the request rate of each client, counted per API key, user or IP address
+	if a.Config.RateLimitReads > 0 || a.Config.RateLimitWrites > 0 || a.Config.RateLimitBulk > 0 {
+		limiter := middleware.NewRateLimiter(map[string]middleware.RateLimit{
+			middleware.RateLimitRead:  {Requests: a.Config.RateLimitReads, Window: time.Minute},
+			middleware.RateLimitWrite: {Requests: a.Config.RateLimitWrites, Window: time.Minute},
+			middleware.RateLimitBulk:  {Requests: a.Config.RateLimitBulk, Window: time.Minute},
+		})
+		a.Router.Use(middleware.RateLimitMiddleware(limiter, rateLimitClass))
+	}
+
+	// Followers serve reads only
+	if a.Follower != nil {
+		a.Router.Use(middleware.ReadOnlyMiddleware)
+	}
 
 	// Initialize handlers
-	healthHandler := handlers.HealthHandler(a.Config)
+	healthHandler := handlers.HealthHandler(a.Config, a.replicationStatus)
 	collectionHandlers := handlers.NewCollectionHandlers(a.CollectionService)
-	documentHandlers := handlers.NewDocumentHandlers(a.DocumentService)
+	bulkDocuments := a.Metrics.NewCounterVec("flexstore_bulk_documents_total", "Documents inserted by bulk inserts and uploads.", "collection")
+	documentHandlers := handlers.NewDocumentHandlers(a.DocumentService, bulkDocuments)
+	replicationHandlers := handlers.NewReplicationHandlers(a.ReplicationService)
+	trashHandlers := handlers.NewTrashHandlers(a.TrashService)
+	apiKeyHandlers := handlers.NewAPIKeyHandlers(a.APIKeyService)
+	roleHandlers := handlers.NewRoleHandlers(a.RoleService)
+	userHandlers := handlers.NewUserHandlers(a.UserService)
+	auditHandlers := handlers.NewAuditHandlers(a.AuditService)
 	protectedHandler := handlers.ProtectedHandler(a.Config)
 
-	// Register health endpoint
+	// Register health endpoints; liveness only shows the process is serving, readiness checks
+	// its dependencies
 	a.Router.HandleFunc("/health", healthHandler).Methods("GET")
+	a.Router.HandleFunc("/health/live", handlers.HealthHandler(a.Config, nil)).Methods("GET")
+	a.Router.HandleFunc("/health/ready", handlers.ReadinessHandler(a.Health.Run)).Methods("GET")
+
+	// Metrics for Prometheus
+	a.Router.Handle("/metrics", a.Metrics.Handler()).Methods("GET")
 
 	// Collection routes
 	a.Router.HandleFunc("/api/collections", collectionHandlers.ListCollections()).Methods("GET")
 	a.Router.HandleFunc("/api/collections", collectionHandlers.CreateCollection()).Methods("POST")
 	a.Router.HandleFunc("/api/collections/{name}", collectionHandlers.GetCollection()).Methods("GET")
 	a.Router.HandleFunc("/api/collections/{name}", collectionHandlers.DeleteCollection()).Methods("DELETE")
+	a.Router.HandleFunc("/api/collections/{name}/options", collectionHandlers.UpdateCollectionOptions()).Methods("PUT")
 
 	// Document routes
 	a.Router.HandleFunc("/api/collections/{name}/documents", documentHandlers.ListDocuments()).Methods("GET")
@@ -79,17 +297,64 @@ func (a *App) setupRoutes() {
 	a.Router.HandleFunc("/api/collections/{name}/documents/{id}", documentHandlers.GetDocument()).Methods("GET")
 	a.Router.HandleFunc("/api/collections/{name}/documents/{id}", documentHandlers.UpdateDocument()).Methods("PUT")
 	a.Router.HandleFunc("/api/collections/{name}/documents/{id}", documentHandlers.DeleteDocument()).Methods("DELETE")
+	a.Router.HandleFunc("/api/collections/{name}/documents/{id}/sharing", documentHandlers.ShareDocument()).Methods("PUT")
+
+	// Revision history
+	a.Router.HandleFunc("/api/collections/{name}/documents/{id}/revisions", documentHandlers.ListRevisions()).Methods("GET")
+	a.Router.HandleFunc("/api/collections/{name}/documents/{id}/revisions/{rev}", documentHandlers.GetRevision()).Methods("GET")
+	a.Router.HandleFunc("/api/collections/{name}/documents/{id}/revisions/{rev}/restore", documentHandlers.RestoreRevision()).Methods("POST")
 
 	// Bulk operations
 	a.Router.HandleFunc("/api/collections/{name}/bulk", documentHandlers.BulkCreateDocuments()).Methods("POST")
 	a.Router.HandleFunc("/api/upload/{name}", documentHandlers.UploadJSONFile()).Methods("POST")
 
+	// Trash
+	a.Router.HandleFunc("/api/trash/collections", trashHandlers.ListCollections()).Methods("GET")
+	a.Router.HandleFunc("/api/trash/collections/{name}", trashHandlers.PurgeCollection()).Methods("DELETE")
+	a.Router.HandleFunc("/api/trash/collections/{name}/restore", trashHandlers.RestoreCollection()).Methods("POST")
+	a.Router.HandleFunc("/api/trash/collections/{name}/documents", trashHandlers.ListDocuments()).Methods("GET")
+	a.Router.HandleFunc("/api/trash/collections/{name}/documents/{id}", trashHandlers.PurgeDocument()).Methods("DELETE")
+	a.Router.HandleFunc("/api/trash/collections/{name}/documents/{id}/restore", trashHandlers.RestoreDocument()).Methods("POST")
+
+	// Replication
+	a.Router.HandleFunc("/api/replication/changes", replicationHandlers.ListChanges()).Methods("GET")
+	a.Router.HandleFunc("/api/replication/snapshot", replicationHandlers.Snapshot()).Methods("GET")
+
+	// API key management
+	a.Router.HandleFunc("/api/keys", apiKeyHandlers.ListAPIKeys()).Methods("GET")
+	a.Router.HandleFunc("/api/keys", apiKeyHandlers.CreateAPIKey()).Methods("POST")
+	a.Router.HandleFunc("/api/keys/{id}", apiKeyHandlers.GetAPIKey()).Methods("GET")
+	a.Router.HandleFunc("/api/keys/{id}", apiKeyHandlers.RevokeAPIKey()).Methods("DELETE")
+	a.Router.HandleFunc("/api/keys/{id}/rotate", apiKeyHandlers.RotateAPIKey()).Methods("POST")
+
+	// User management
+	a.Router.HandleFunc("/api/users", userHandlers.ListUsers()).Methods("GET")
+	a.Router.HandleFunc("/api/users", userHandlers.CreateUser()).Methods("POST")
+	a.Router.HandleFunc("/api/users/{username}", userHandlers.GetUser()).Methods("GET")
+	a.Router.HandleFunc("/api/users/{username}/password", userHandlers.ResetPassword()).Methods("PUT")
+	a.Router.HandleFunc("/api/users/{username}/disable", userHandlers.DisableUser()).Methods("POST")
+	a.Router.HandleFunc("/api/users/{username}/enable", userHandlers.EnableUser()).Methods("POST")
+
+	// Role management
+	a.Router.HandleFunc("/api/roles", roleHandlers.ListRoles()).Methods("GET")
+	a.Router.HandleFunc("/api/roles", roleHandlers.CreateRole()).Methods("POST")
+	a.Router.HandleFunc("/api/roles/{role}", roleHandlers.GetRole()).Methods("GET")
+	a.Router.HandleFunc("/api/roles/{role}", roleHandlers.UpdateRole()).Methods("PUT")
+	a.Router.HandleFunc("/api/roles/{role}", roleHandlers.DeleteRole()).Methods("DELETE")
+	a.Router.HandleFunc("/api/roles/{role}/assignments", roleHandlers.ListAssignments()).Methods("GET")
+	a.Router.HandleFunc("/api/roles/{role}/assignments", roleHandlers.AssignRole()).Methods("POST")
+	a.Router.HandleFunc("/api/roles/{role}/assignments/{subject}", roleHandlers.UnassignRole()).Methods("DELETE")
+
+	// Audit log
+	a.Router.HandleFunc("/api/audit", auditHandlers.ListEntries()).Methods("GET")
+	a.Router.HandleFunc("/api/audit/export", auditHandlers.ExportEntries()).Methods("GET")
+
 	// Create a subrouter for protected routes
 	protectedRouter := a.Router.PathPrefix("/api/protected").Subrouter()
 
-	// Apply authentication middleware if enabled
+	// Protected routes always require authentication, whatever the policy allows anonymously
 	if a.Config.EnableBasicAuth {
-		authMiddleware := middleware.BasicAuthMiddleware(a.Config.AuthUsername, a.Config.AuthPassword)
+		authMiddleware := middleware.BasicAuthMiddleware(a.UserService)
 		protectedRouter.Use(authMiddleware)
 	}
 
@@ -98,6 +363,132 @@ func (a *App) setupRoutes() {
 
 }
 
+// setupJobs configures the background jobs.
+// Followers receive purges and expiry deletions through replication and run no jobs of their own.
+func (a *App) setupJobs() {
+	if a.Follower != nil {
+		return
+	}
+
+	// Purge expired trash, checking at least hourly
+	if a.Config.SoftDelete && a.Config.TrashRetention > 0 {
+		interval := a.Config.TrashRetention
+		if interval > time.Hour {
+			interval = time.Hour
+		}
+		a.Jobs = append(a.Jobs, jobs.NewPeriodic("trash-purge", interval, func(ctx context.Context) error {
+			_, _, err := a.TrashService.PurgeExpired(ctx, a.Config.TrashRetention)
+			return err
+		}))
+	}
+
+	// Prune the change log, checking at least hourly
+	if a.Config.ChangeRetention > 0 {
+		interval := a.Config.ChangeRetention
+		if interval > time.Hour {
+			interval = time.Hour
+		}
+		a.Jobs = append(a.Jobs, jobs.NewPeriodic("change-prune", interval, func(ctx context.Context) error {
+			_, err := a.ReplicationService.PruneChanges(ctx, a.Config.ChangeRetention)
+			return err
+		}))
+	}
+
+	// Delete expired documents
+	if a.Config.ExpirySweepInterval > 0 {
+		a.Jobs = append(a.Jobs, jobs.NewPeriodic("expiry-sweep", a.Config.ExpirySweepInterval, func(ctx context.Context) error {
+			_, err := a.DocumentService.SweepExpired(ctx, expirySweepBatchSize)
+			return err
+		}))
+	}
+}
+
+// Start launches the replication follower and the background jobs
+func (a *App) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopWorkers = cancel
+
+	// Start pulling from the primary when running as a follower
+	if a.Follower != nil {
+		a.Follower.Start(ctx)
+	}
+
+	// Start background jobs
+	for _, job := range a.Jobs {
+		job.Start(ctx)
+	}
+}
+
+// Shutdown stops the replication follower and the background jobs, canceling the work they
+// are doing, then checkpoints and closes the database. It waits for the workers to stop until
+// ctx is done.
+func (a *App) Shutdown(ctx context.Context) error {
+	if a.stopWorkers != nil {
+		a.stopWorkers()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if a.Follower != nil {
+			a.Follower.Stop()
+		}
+		for _, job := range a.Jobs {
+			job.Stop()
+		}
+	}()
+
+	var stopErr error
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		stopErr = fmt.Errorf("background workers did not stop in time: %w", ctx.Err())
+	}
+
+	checkpointErr := a.DB.Checkpoint()
+	if err := a.DB.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	return errors.Join(stopErr, checkpointErr)
+}
+
+// Close shuts down like Shutdown without a deadline
+func (a *App) Close() error {
+	return a.Shutdown(context.Background())
+}
+
+// saveGeneratedPassword writes a generated admin password to a file only the owner can read,
+// next to the database, and returns its path
+func saveGeneratedPassword(databasePath, password string) (string, error) {
+	path := filepath.Join(filepath.Dir(databasePath), "admin-password")
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	// Create the file afresh so it never keeps looser permissions of an earlier one
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := file.WriteString(password + "\n"); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// replicationStatus reports the follower status, or nil when running as a primary
+func (a *App) replicationStatus() *models.ReplicationStatus {
+	if a.Follower == nil {
+		return nil
+	}
+	return a.Follower.Status()
+}
+
 // SetupRouter configures and returns the router with all routes and middleware for testing
 func SetupRouter(cfg *config.Config) *mux.Router {
 	app, err := NewApp(cfg)
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/rbehzadan/flexstore/internal/health"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// workerDetails reports the state of the background workers
+type workerDetails struct {
+	Jobs        []models.JobStatus        `json:"jobs"`
+	Replication *models.ReplicationStatus `json:"replication,omitempty"`
+}
+
+// setupHealth registers the readiness checks
+func (a *App) setupHealth() {
+	a.Health = health.NewChecker(a.Config.HealthTimeout)
+
+	// The database answers reads and accepts writes
+	a.Health.Add("database", func(ctx context.Context) (any, error) {
+		return nil, a.DB.Reader().PingContext(ctx)
+	})
+	a.Health.Add("database_write", func(ctx context.Context) (any, error) {
+		return nil, a.DB.ProbeWrite(ctx)
+	})
+
+	// The disk has room for the database to grow
+	if a.Config.HealthMinFreeBytes > 0 {
+		a.Health.Add("disk", health.DiskSpace(filepath.Dir(a.Config.SqlitePath), a.Config.HealthMinFreeBytes))
+	}
+
+	a.Health.Add("workers", a.checkWorkers)
+}
+
+// checkWorkers fails if a background job has stopped and reports failed runs and failed
+// replication as degraded
+func (a *App) checkWorkers(ctx context.Context) (any, error) {
+	details := workerDetails{
+		Jobs:        make([]models.JobStatus, 0, len(a.Jobs)),
+		Replication: a.replicationStatus(),
+	}
+	for _, job := range a.Jobs {
+		details.Jobs = append(details.Jobs, job.Status())
+	}
+
+	for _, status := range details.Jobs {
+		if !status.Running {
+			return details, fmt.Errorf("job %s is not running", status.Name)
+		}
+	}
+	for _, status := range details.Jobs {
+		if status.LastError != "" {
+			return details, health.Degraded(fmt.Errorf("last run of job %s failed: %s", status.Name, status.LastError))
+		}
+	}
+	if details.Replication != nil && details.Replication.LastError != "" {
+		return details, health.Degraded(fmt.Errorf("replication failed: %s", details.Replication.LastError))
+	}
+	return details, nil
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/metrics"
+)
+
+// unmatchedRoute labels metrics of requests that match no route
+const unmatchedRoute = "unmatched"
+
+// routeTemplate returns the template of the route a request matches, such as
+// /api/collections/{name}, for labelling metrics
+func (a *App) routeTemplate(r *http.Request) string {
+	var match mux.RouteMatch
+	if !a.Router.Match(r, &match) || match.Route == nil {
+		return unmatchedRoute
+	}
+	template, err := match.Route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return template
+}
+
+// setupMetrics registers the metrics read from the database on every scrape
+func (a *App) setupMetrics() {
+	// Connection pools, labelled read for the readers and write for the writer
+	poolStats := func() []sql.DBStats {
+		return []sql.DBStats{a.DB.Reader().Stats(), a.DB.Stats()}
+	}
+	poolSamples := func(value func(sql.DBStats) float64) func() ([]metrics.Sample, error) {
+		return func() ([]metrics.Sample, error) {
+			stats := poolStats()
+			return []metrics.Sample{
+				{LabelValues: []string{"read"}, Value: value(stats[0])},
+				{LabelValues: []string{"write"}, Value: value(stats[1])},
+			}, nil
+		}
+	}
+	pool := []string{"pool"}
+
+	a.Metrics.NewGaugeFunc("flexstore_db_max_open_connections", "Maximum number of open database connections.", pool,
+		poolSamples(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	a.Metrics.NewGaugeFunc("flexstore_db_open_connections", "Open database connections.", pool,
+		poolSamples(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	a.Metrics.NewGaugeFunc("flexstore_db_in_use_connections", "Database connections in use.", pool,
+		poolSamples(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	a.Metrics.NewGaugeFunc("flexstore_db_idle_connections", "Idle database connections.", pool,
+		poolSamples(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	a.Metrics.NewCounterFunc("flexstore_db_wait_count_total", "Times a query waited for a database connection.", pool,
+		poolSamples(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	a.Metrics.NewCounterFunc("flexstore_db_wait_duration_seconds_total", "Time spent waiting for database connections.", pool,
+		poolSamples(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+
+	// Storage
+	a.Metrics.NewGaugeFunc("flexstore_db_size_bytes", "Size of the database files on disk.", nil, func() ([]metrics.Sample, error) {
+		size, err := a.DB.Size()
+		if err != nil {
+			return nil, err
+		}
+		return []metrics.Sample{{Value: float64(size)}}, nil
+	})
+	a.Metrics.NewGaugeFunc("flexstore_collection_documents", "Live documents per collection.", []string{"collection"}, func() ([]metrics.Sample, error) {
+		counts, err := a.CollectionService.DocumentCounts(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		samples := make([]metrics.Sample, 0, len(counts))
+		for name, count := range counts {
+			samples = append(samples, metrics.Sample{LabelValues: []string{name}, Value: float64(count)})
+		}
+		return samples, nil
+	})
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+)
+
+// rateLimitClass maps a request to the rate limit class of the route it matched
+func rateLimitClass(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			switch template {
+			case "/api/collections/{name}/bulk", "/api/upload/{name}":
+				return middleware.RateLimitBulk
+			}
+		}
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return middleware.RateLimitRead
+	}
+	return middleware.RateLimitWrite
+}
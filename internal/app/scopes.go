@@ -0,0 +1,72 @@
+package app
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// requiredScope maps a request to the scope it needs, based on the route it matched
+func requiredScope(r *http.Request) middleware.Requirement {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return middleware.Requirement{Scope: models.ScopeAdmin}
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return middleware.Requirement{Scope: models.ScopeAdmin}
+	}
+
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead
+	collection := mux.Vars(r)["name"]
+
+	// pick chooses between the read and write variant of a scope
+	pick := func(readScope, writeScope string) middleware.Requirement {
+		if read {
+			return middleware.Requirement{Scope: readScope, Collection: collection}
+		}
+		return middleware.Requirement{Scope: writeScope, Collection: collection}
+	}
+
+	switch {
+	case template == "/health", strings.HasPrefix(template, "/api/protected"):
+		return middleware.Requirement{}
+
+	case template == "/metrics":
+		return middleware.Requirement{Scope: models.ScopeMetricsRead}
+
+	case strings.HasPrefix(template, "/api/collections/{name}/documents"),
+		strings.HasPrefix(template, "/api/trash/collections/{name}/documents"),
+		template == "/api/collections/{name}/bulk",
+		template == "/api/upload/{name}":
+		return pick(models.ScopeDocumentsRead, models.ScopeDocumentsWrite)
+
+	case strings.HasPrefix(template, "/api/collections"),
+		strings.HasPrefix(template, "/api/trash/collections"):
+		return pick(models.ScopeCollectionsRead, models.ScopeCollectionsWrite)
+
+	default:
+		// Replication, key management and anything not listed above
+		return middleware.Requirement{Scope: models.ScopeAdmin}
+	}
+}
+
+// anonymousRoute reports whether the anonymous methods of the auth policy apply to a request:
+// only collection and document routes are opened up, never a route that needs the admin scope
+func anonymousRoute(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+	if !strings.HasPrefix(template, "/api/collections") && template != "/api/upload/{name}" {
+		return false
+	}
+	return requiredScope(r).Scope != models.ScopeAdmin
+}
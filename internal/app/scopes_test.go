@@ -0,0 +1,59 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/app"
+	"github.com/rbehzadan/flexstore/internal/models"
+	"github.com/rbehzadan/flexstore/pkg/config"
+)
+
+func TestMetricsScope(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.SqlitePath = filepath.Join(t.TempDir(), "db.sqlite")
+
+	a, err := app.NewApp(cfg)
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	defer a.Close()
+
+	metricsKey, err := a.APIKeyService.Create(t.Context(), "prometheus", []string{models.ScopeMetricsRead}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	documentsKey, err := a.APIKeyService.Create(t.Context(), "reader", []string{models.ScopeDocumentsRead}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		path string
+		want int
+	}{
+		{"scrape with metrics scope", metricsKey.Key, "/metrics", http.StatusOK},
+		{"scrape with other scope", documentsKey.Key, "/metrics", http.StatusForbidden},
+		{"scrape without credentials", "", "/metrics", http.StatusUnauthorized},
+		{"metrics scope on collections", metricsKey.Key, "/api/collections", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			rr := httptest.NewRecorder()
+			a.Handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.want)
+			}
+		})
+	}
+}
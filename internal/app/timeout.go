@@ -0,0 +1,29 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rbehzadan/flexstore/internal/api/middleware"
+)
+
+// queryTimeoutClass maps a request to the query timeout class of the route it matched; bulk
+// inserts and uploads get the bulk timeout, and the audit export and replication snapshot,
+// which stream whole tables, are streams
+func queryTimeoutClass(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			switch template {
+			case "/api/collections/{name}/bulk", "/api/upload/{name}":
+				return middleware.QueryTimeoutBulk
+			case "/api/audit/export", "/api/replication/snapshot":
+				return middleware.QueryTimeoutStream
+			}
+		}
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return middleware.QueryTimeoutRead
+	}
+	return middleware.QueryTimeoutWrite
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key so keys are recognizable in headers and logs
+const APIKeyPrefix = "fsk_"
+
+// GenerateAPIKey creates a new random API key and returns it with the prefix used to identify it
+func GenerateAPIKey() (key, prefix string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key = APIKeyPrefix + hex.EncodeToString(b)
+	return key, key[:len(APIKeyPrefix)+8], nil
+}
+
+// HashAPIKey returns the hash under which an API key is stored.
+// Keys carry enough entropy that a fast hash is sufficient.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// IsAPIKey reports whether a token looks like an API key
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// GenerateID creates a random identifier
+func GenerateID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// JWTKey is a key used to verify token signatures
+type JWTKey struct {
+	// ID matches the kid header of tokens; empty keys are tried for any token
+	ID string
+
+	// Key is a []byte secret for HS256, an *rsa.PublicKey for RS256 or an *ecdsa.PublicKey for ES256
+	Key interface{}
+}
+
+// jwk is a single JSON Web Key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// LoadJWTKeys reads verification keys from a JWKS JSON file or a PEM file
+// containing public keys or certificates
+func LoadJWTKeys(path string) ([]JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT keys: %w", err)
+	}
+
+	var keys []JWTKey
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		keys, err = ParseJWKS(data)
+	} else {
+		keys, err = parsePEMKeys(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", path)
+	}
+
+	return keys, nil
+}
+
+// ParseJWKS parses a JSON Web Key Set
+func ParseJWKS(data []byte) ([]JWTKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make([]JWTKey, 0, len(set.Keys))
+	for i, k := range set.Keys {
+		// Skip keys meant for encryption
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %d in JWKS: %w", i, err)
+		}
+		keys = append(keys, JWTKey{ID: k.Kid, Key: key})
+	}
+
+	return keys, nil
+}
+
+// publicKey converts a JSON Web Key to the key used for verification
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, fmt.Errorf("invalid symmetric key")
+		}
+		return secret, nil
+
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != 32 {
+			return nil, fmt.Errorf("invalid EC x coordinate")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil || len(y) != 32 {
+			return nil, fmt.Errorf("invalid EC y coordinate")
+		}
+
+		// Make sure the point is on the curve
+		point := append([]byte{0x04}, append(x, y...)...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("invalid EC point: %w", err)
+		}
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+}
+
+// parsePEMKeys parses public keys and certificates from PEM data
+func parsePEMKeys(data []byte) ([]JWTKey, error) {
+	var keys []JWTKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var key interface{}
+		switch block.Type {
+		case "PUBLIC KEY":
+			parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse public key: %w", err)
+			}
+			key = parsed
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse certificate: %w", err)
+			}
+			key = cert.PublicKey
+		default:
+			continue
+		}
+
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			keys = append(keys, JWTKey{Key: key})
+		default:
+			return nil, fmt.Errorf("unsupported public key type %T", key)
+		}
+	}
+
+	return keys, nil
+}
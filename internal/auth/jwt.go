@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// JWTConfig configures how tokens are validated and mapped to principals
+type JWTConfig struct {
+	// Issuer and Audience are checked against the iss and aud claims when set
+	Issuer   string
+	Audience string
+
+	// RolesClaim and CollectionsClaim name the claims holding the caller's roles and allowed collections
+	RolesClaim       string
+	CollectionsClaim string
+
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+}
+
+// JWTVerifier validates bearer tokens signed with HS256, RS256 or ES256
+type JWTVerifier struct {
+	keys   []JWTKey
+	config JWTConfig
+	now    func() time.Time
+}
+
+// NewJWTVerifier creates a verifier for tokens signed by any of the given keys
+func NewJWTVerifier(keys []JWTKey, config JWTConfig) *JWTVerifier {
+	if config.RolesClaim == "" {
+		config.RolesClaim = "roles"
+	}
+	if config.CollectionsClaim == "" {
+		config.CollectionsClaim = "collections"
+	}
+	return &JWTVerifier{keys: keys, config: config, now: time.Now}
+}
+
+// jwtHeader is the header of a compact JWS
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the signature and claims of a token and returns the principal it identifies
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	// Decode header
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header")
+	}
+
+	// Check signature
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature")
+	}
+	if err := v.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	// Decode claims
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return v.principal(claims)
+}
+
+// verifySignature checks the signature against the keys usable with the token's algorithm
+func (v *JWTVerifier) verifySignature(header jwtHeader, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	for _, key := range v.keys {
+		if header.Kid != "" && key.ID != "" && key.ID != header.Kid {
+			continue
+		}
+
+		// Each algorithm only accepts its own key type, which rules out algorithm confusion
+		var valid bool
+		switch k := key.Key.(type) {
+		case []byte:
+			if header.Alg != "HS256" {
+				continue
+			}
+			mac := hmac.New(sha256.New, k)
+			mac.Write([]byte(signed))
+			valid = hmac.Equal(signature, mac.Sum(nil))
+		case *rsa.PublicKey:
+			if header.Alg != "RS256" {
+				continue
+			}
+			valid = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) == nil
+		case *ecdsa.PublicKey:
+			if header.Alg != "ES256" || len(signature) != 64 {
+				continue
+			}
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			valid = ecdsa.Verify(k, digest[:], r, s)
+		}
+		if valid {
+			return nil
+		}
+	}
+
+	switch header.Alg {
+	case "HS256", "RS256", "ES256":
+		return fmt.Errorf("invalid token signature")
+	default:
+		return fmt.Errorf("unsupported token algorithm '%s'", header.Alg)
+	}
+}
+
+// validateClaims checks the time, issuer and audience claims
+func (v *JWTVerifier) validateClaims(claims map[string]interface{}) error {
+	now := v.now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.config.Leeway)) {
+		return fmt.Errorf("token has expired")
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if now.Add(v.config.Leeway).Before(time.Unix(int64(nbf), 0)) {
+			return fmt.Errorf("token is not valid yet")
+		}
+	}
+
+	if v.config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.config.Issuer {
+			return fmt.Errorf("token has wrong issuer")
+		}
+	}
+
+	if v.config.Audience != "" {
+		if !slices.Contains(stringsClaim(claims["aud"]), v.config.Audience) {
+			return fmt.Errorf("token has wrong audience")
+		}
+	}
+
+	return nil
+}
+
+// principal maps validated claims to a principal
+func (v *JWTVerifier) principal(claims map[string]interface{}) (*Principal, error) {
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	// Roles are resolved to collection permissions after authentication. Only tokens with an
+	// OAuth 2.0 scope claim, a space-separated list, are limited by scopes as well.
+	var scopes []string
+	if scope, ok := claims["scope"].(string); ok {
+		scopes = make([]string, 0)
+		for _, s := range strings.Fields(scope) {
+			if models.ValidateScopes([]string{s}) == nil && !slices.Contains(scopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+
+	return &Principal{
+		ID:          subject,
+		Name:        subject,
+		Method:      MethodJWT,
+		Roles:       stringsClaim(claims[v.config.RolesClaim]),
+		Scopes:      scopes,
+		Collections: stringsClaim(claims[v.config.CollectionsClaim]),
+	}, nil
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// stringsClaim reads a claim holding a string or an array of strings
+func stringsClaim(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
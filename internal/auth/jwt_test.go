@@ -0,0 +1,219 @@
+package auth_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// b64 encodes bytes as unpadded base64url
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// signToken creates a compact JWS with the given algorithm and signing key
+func signToken(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case nil:
+	}
+
+	return signed + "." + b64(signature)
+}
+
+// testKeys generates one key of each supported type
+func testKeys(t *testing.T) ([]byte, *rsa.PrivateKey, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return secret, rsaKey, ecKey
+}
+
+// writeJWKS writes the public parts of the keys to a JWKS file
+func writeJWKS(t *testing.T, secret []byte, rsaKey *rsa.PrivateKey, ecKey *ecdsa.PrivateKey) string {
+	t.Helper()
+
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	ecKey.X.FillBytes(x)
+	ecKey.Y.FillBytes(y)
+
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "oct", "kid": "hmac", "k": b64(secret)},
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(x), "y": b64(y)},
+		},
+	}
+	data, _ := json.Marshal(jwks)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJWTVerifier(t *testing.T) {
+	secret, rsaKey, ecKey := testKeys(t)
+	keys, err := auth.LoadJWTKeys(writeJWKS(t, secret, rsaKey, ecKey))
+	if err != nil {
+		t.Fatalf("failed to load JWKS: %v", err)
+	}
+	verifier := auth.NewJWTVerifier(keys, auth.JWTConfig{Issuer: "https://idp.example", Audience: "flexstore"})
+
+	now := time.Now()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub":         "alice",
+			"iss":         "https://idp.example",
+			"aud":         []string{"flexstore", "other"},
+			"exp":         now.Add(time.Hour).Unix(),
+			"nbf":         now.Add(-time.Minute).Unix(),
+			"roles":       []string{"writer"},
+			"collections": []string{"orders"},
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	otherRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"HS256", signToken(t, "HS256", "hmac", secret, claims(nil)), false},
+		{"RS256", signToken(t, "RS256", "rsa", rsaKey, claims(nil)), false},
+		{"ES256", signToken(t, "ES256", "ec", ecKey, claims(nil)), false},
+		{"RS256 without kid", signToken(t, "RS256", "", rsaKey, claims(nil)), false},
+		{"unknown signer", signToken(t, "RS256", "rsa", otherRSA, claims(nil)), true},
+		{"alg none", signToken(t, "none", "", nil, claims(nil)), true},
+		{"expired", signToken(t, "HS256", "hmac", secret, claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})), true},
+		{"no expiry", signToken(t, "HS256", "hmac", secret, claims(map[string]interface{}{"exp": nil})), true},
+		{"not yet valid", signToken(t, "HS256", "hmac", secret, claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})), true},
+		{"wrong issuer", signToken(t, "HS256", "hmac", secret, claims(map[string]interface{}{"iss": "https://evil.example"})), true},
+		{"wrong audience", signToken(t, "HS256", "hmac", secret, claims(map[string]interface{}{"aud": "other"})), true},
+		{"malformed", "not-a-token", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := verifier.Verify(tt.token)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected token to be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if principal.Name != "alice" || principal.Method != auth.MethodJWT {
+				t.Errorf("wrong principal: %+v", principal)
+			}
+			if !slices.Equal(principal.Roles, []string{"writer"}) {
+				t.Errorf("wrong roles: got %v want %v", principal.Roles, []string{"writer"})
+			}
+			if principal.Scoped() {
+				t.Errorf("token without a scope claim should not be scoped: %v", principal.Scopes)
+			}
+			if !slices.Equal(principal.Collections, []string{"orders"}) {
+				t.Errorf("wrong collections: got %v want %v", principal.Collections, []string{"orders"})
+			}
+		})
+	}
+}
+
+func TestLoadJWTKeysFromPEM(t *testing.T) {
+	_, rsaKey, _ := testKeys(t)
+
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := auth.LoadJWTKeys(path)
+	if err != nil {
+		t.Fatalf("failed to load PEM key: %v", err)
+	}
+	verifier := auth.NewJWTVerifier(keys, auth.JWTConfig{})
+
+	token := signToken(t, "RS256", "", rsaKey, map[string]interface{}{
+		"sub":   "svc",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"scope": "documents:read unknown:scope",
+	})
+	principal, err := verifier.Verify(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(principal.Scopes, []string{models.ScopeDocumentsRead}) {
+		t.Errorf("wrong scopes: got %v want %v", principal.Scopes, []string{models.ScopeDocumentsRead})
+	}
+}
@@ -0,0 +1,56 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/auth"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := auth.HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if !strings.HasPrefix(hash, "pbkdf2-sha256$") || strings.Contains(hash, "correct horse") {
+		t.Errorf("unexpected hash format: %s", hash)
+	}
+
+	other, err := auth.HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if other == hash {
+		t.Errorf("hashes of the same password should use different salts")
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"correct password", "correct horse", true},
+		{"wrong password", "battery staple", false},
+		{"empty password", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := auth.VerifyPassword(tt.password, hash)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyPasswordRejectsMalformedHash(t *testing.T) {
+	for _, hash := range []string{"", "plaintext", "bcrypt$10$abc$def", "pbkdf2-sha256$x$abc$def"} {
+		if _, err := auth.VerifyPassword("secret", hash); err == nil {
+			t.Errorf("expected an error for hash %q", hash)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// Authentication methods
+const (
+	MethodBasic  = "basic"
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+	MethodCert   = "certificate"
+)
+
+// Principal is the authenticated identity behind a request
+type Principal struct {
+	// ID identifies the principal for role assignments: the username, token subject, certificate
+	// common name or API key ID
+	ID     string
+	Name   string
+	Method string
+
+	// Roles are role names carried by the credential itself, such as a token's roles claim
+	Roles []string
+
+	// Scopes limit what the credential may do; nil means the credential carries no scopes
+	// and the principal is limited by its roles only
+	Scopes []string
+
+	// Collections restricts access to the listed collections; empty means all collections
+	Collections []string
+
+	// Grants are the collection permissions of the principal's roles
+	Grants []models.Grant
+}
+
+// Subject returns the name under which roles are assigned to the principal. Each
+// authentication method has its own namespace, so a token subject or certificate name never
+// gets the roles or documents of the user account it happens to match.
+func (p *Principal) Subject() string {
+	switch p.Method {
+	case MethodAPIKey:
+		return "key:" + p.ID
+	case MethodJWT:
+		return "jwt:" + p.ID
+	case MethodCert:
+		return "cert:" + p.ID
+	default:
+		return "user:" + p.ID
+	}
+}
+
+// Scoped reports whether the credential carries scopes
+func (p *Principal) Scoped() bool {
+	return p.Scopes != nil
+}
+
+// HasScope reports whether the principal was granted a scope; admin implies every scope
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, models.ScopeAdmin) || slices.Contains(p.Scopes, scope)
+}
+
+// Can reports whether the principal may perform an action on a collection.
+// Scoped credentials without roles are limited by their scopes alone, which are checked per route.
+func (p *Principal) Can(collection string, permission models.Permission) bool {
+	if p.HasScope(models.ScopeAdmin) {
+		return true
+	}
+	if !p.CanAccessCollection(collection) {
+		return false
+	}
+	if len(p.Grants) == 0 && p.Scoped() {
+		return true
+	}
+	for _, grant := range p.Grants {
+		if grant.Allows(collection, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCollectionAdmin reports whether the principal administers a collection, which lifts
+// document ownership restrictions. Unlike Can, scopes alone never make a principal one.
+func (p *Principal) IsCollectionAdmin(collection string) bool {
+	if p.HasScope(models.ScopeAdmin) {
+		return true
+	}
+	if !p.CanAccessCollection(collection) {
+		return false
+	}
+	for _, grant := range p.Grants {
+		if grant.Allows(collection, models.PermissionAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the principal may administer the server
+func (p *Principal) IsAdmin() bool {
+	return p.HasScope(models.ScopeAdmin) || (!p.Scoped() && p.Can("*", models.PermissionAdmin))
+}
+
+// Restricted reports whether the principal is limited to some collections
+func (p *Principal) Restricted() bool {
+	return len(p.Collections) > 0
+}
+
+// CanAccessCollection reports whether the principal may access a collection
+func (p *Principal) CanAccessCollection(name string) bool {
+	return !p.Restricted() || slices.Contains(p.Collections, name)
+}
+
+type contextKey struct{}
+
+type anonymousKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal carried by ctx, if any
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok
+}
+
+// Anonymous tells why a request is served without a principal
+type Anonymous int
+
+const (
+	// NotAnonymous requests carry a principal, or nothing let them through without one
+	NotAnonymous Anonymous = iota
+
+	// AnonymousOpen requests are served with authentication disabled and are not restricted
+	AnonymousOpen
+
+	// AnonymousPath requests are to a path the auth policy makes public
+	AnonymousPath
+
+	// AnonymousMethod requests use a method the auth policy allows anonymously on their route
+	AnonymousMethod
+)
+
+// WithAnonymous returns a copy of ctx marking a request served without a principal and why
+func WithAnonymous(ctx context.Context, anonymous Anonymous) context.Context {
+	return context.WithValue(ctx, anonymousKey{}, anonymous)
+}
+
+// AnonymousFrom returns why the request of ctx is served without a principal
+func AnonymousFrom(ctx context.Context) Anonymous {
+	anonymous, _ := ctx.Value(anonymousKey{}).(Anonymous)
+	return anonymous
+}
+
+// IsAnonymous reports whether ctx belongs to a request served without authentication
+// even though authentication is enabled
+func IsAnonymous(ctx context.Context) bool {
+	anonymous := AnonymousFrom(ctx)
+	return anonymous == AnonymousPath || anonymous == AnonymousMethod
+}
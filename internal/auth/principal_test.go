@@ -0,0 +1,116 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestPrincipalCan(t *testing.T) {
+	editor := []models.Grant{
+		{Collection: "orders", Permissions: []models.Permission{models.PermissionRead, models.PermissionWrite}},
+		{Collection: "logs_*", Permissions: []models.Permission{models.PermissionAdmin}},
+	}
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		collection string
+		permission models.Permission
+		want       bool
+	}{
+		{"granted permission", &auth.Principal{Grants: editor}, "orders", models.PermissionWrite, true},
+		{"missing permission", &auth.Principal{Grants: editor}, "orders", models.PermissionDelete, false},
+		{"wildcard pattern", &auth.Principal{Grants: editor}, "logs_2024", models.PermissionRead, true},
+		{"admin implies delete", &auth.Principal{Grants: editor}, "logs_2024", models.PermissionDelete, true},
+		{"no matching grant", &auth.Principal{Grants: editor}, "users", models.PermissionRead, false},
+		{"no roles", &auth.Principal{}, "orders", models.PermissionRead, false},
+		{"builtin reader", &auth.Principal{Grants: models.BuiltinRoles["reader"].Grants}, "users", models.PermissionRead, true},
+		{"builtin reader cannot write", &auth.Principal{Grants: models.BuiltinRoles["reader"].Grants}, "users", models.PermissionWrite, false},
+		{"admin scope", &auth.Principal{Scopes: []string{models.ScopeAdmin}}, "users", models.PermissionDelete, true},
+		{"scoped key without roles", &auth.Principal{Scopes: []string{models.ScopeDocumentsRead}}, "users", models.PermissionRead, true},
+		{"scoped key limited by roles", &auth.Principal{Scopes: []string{models.ScopeDocumentsWrite}, Grants: editor}, "users", models.PermissionWrite, false},
+		{"collection restriction", &auth.Principal{Grants: models.BuiltinRoles["writer"].Grants, Collections: []string{"orders"}}, "users", models.PermissionRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.Can(tt.collection, tt.permission); got != tt.want {
+				t.Errorf("Can(%q, %q) = %v, want %v", tt.collection, tt.permission, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipalIsAdmin(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		want      bool
+	}{
+		{"admin role", &auth.Principal{Grants: models.BuiltinRoles["admin"].Grants}, true},
+		{"writer role", &auth.Principal{Grants: models.BuiltinRoles["writer"].Grants}, false},
+		{"admin of some collections", &auth.Principal{Grants: []models.Grant{{Collection: "logs_*", Permissions: []models.Permission{models.PermissionAdmin}}}}, false},
+		{"admin scope", &auth.Principal{Scopes: []string{models.ScopeAdmin}}, true},
+		{"admin role on a scoped key", &auth.Principal{Scopes: []string{models.ScopeDocumentsRead}, Grants: models.BuiltinRoles["admin"].Grants}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.IsAdmin(); got != tt.want {
+				t.Errorf("IsAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipalIsCollectionAdmin(t *testing.T) {
+	logsAdmin := []models.Grant{{Collection: "logs_*", Permissions: []models.Permission{models.PermissionAdmin}}}
+
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		collection string
+		want       bool
+	}{
+		{"admin of the collection", &auth.Principal{Grants: logsAdmin}, "logs_2024", true},
+		{"admin of other collections", &auth.Principal{Grants: logsAdmin}, "orders", false},
+		{"writer role", &auth.Principal{Grants: models.BuiltinRoles["writer"].Grants}, "orders", false},
+		{"admin scope", &auth.Principal{Scopes: []string{models.ScopeAdmin}}, "orders", true},
+		{"scoped key without roles", &auth.Principal{Scopes: []string{models.ScopeDocumentsWrite}}, "orders", false},
+		{"collection restriction", &auth.Principal{Grants: logsAdmin, Collections: []string{"logs_2023"}}, "logs_2024", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.IsCollectionAdmin(tt.collection); got != tt.want {
+				t.Errorf("IsCollectionAdmin(%q) = %v, want %v", tt.collection, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipalSubject(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		want      string
+	}{
+		{"user", &auth.Principal{ID: "alice", Method: auth.MethodBasic}, "user:alice"},
+		{"api key", &auth.Principal{ID: "k1", Method: auth.MethodAPIKey}, "key:k1"},
+		{"token", &auth.Principal{ID: "alice", Method: auth.MethodJWT}, "jwt:alice"},
+		{"certificate", &auth.Principal{ID: "alice", Method: auth.MethodCert}, "cert:alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.principal.Subject(); got != tt.want {
+				t.Errorf("Subject() = %v, want %v", got, tt.want)
+			}
+			if err := models.ValidateSubject(tt.want); err != nil {
+				t.Errorf("subject %s cannot be assigned roles: %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config describes the server certificate and how client certificates are checked
+type Config struct {
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile is a PEM bundle of CAs that client certificates are verified against;
+	// empty disables client certificates
+	ClientCAFile string
+
+	// RequireClientCert rejects connections without a valid client certificate;
+	// otherwise a client certificate is verified only if one is presented
+	RequireClientCert bool
+
+	MinVersion uint16
+}
+
+// ParseTLSVersion converts a TLS version such as "1.2" to its crypto/tls constant
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown TLS version '%s', expected 1.0, 1.1, 1.2 or 1.3", version)
+	}
+}
+
+// Reloader serves a certificate and client CA bundle loaded from files and swaps
+// them for new versions without dropping existing connections
+type Reloader struct {
+	config Config
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+// NewReloader loads the files of config
+func NewReloader(config Config) (*Reloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, fmt.Errorf("both a certificate and a key file are required")
+	}
+	if config.RequireClientCert && config.ClientCAFile == "" {
+		return nil, fmt.Errorf("requiring client certificates needs a client CA file")
+	}
+
+	r := &Reloader{config: config}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// files returns the files the reloader reads
+func (r *Reloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+// Reload reads the files again. On error the previously loaded versions stay in use.
+func (r *Reloader) Reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		data, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in client CA file '%s'", r.config.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// ReloadIfChanged reloads the files if any of them was modified since they were last loaded
+func (r *Reloader) ReloadIfChanged() (bool, error) {
+	r.mu.RLock()
+	modTimes := r.modTimes
+	r.mu.RUnlock()
+
+	changed := false
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		if !info.ModTime().Equal(modTimes[file]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	return true, r.Reload()
+}
+
+// TLSConfig returns a server configuration that picks up reloaded files on new connections
+func (r *Reloader) TLSConfig() *tls.Config {
+	// The configuration returned per connection replaces the server's, so it repeats the
+	// protocols the server offers
+	nextProtos := []string{"h2", "http/1.1"}
+	return &tls.Config{
+		MinVersion: r.config.MinVersion,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+
+			config := &tls.Config{
+				MinVersion:   r.config.MinVersion,
+				NextProtos:   nextProtos,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+			if r.clientCAs != nil {
+				config.ClientCAs = r.clientCAs
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+				if r.config.RequireClientCert {
+					config.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return config, nil
+		},
+	}
+}
@@ -0,0 +1,240 @@
+package certs_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/certs"
+)
+
+// testCert is a generated certificate with its key
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert generates a certificate for commonName, signed by parent or self-signed if parent is nil
+func newTestCert(t *testing.T, commonName string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeFile writes data to a file in dir and returns its path
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// tlsClient returns a client trusting ca, presenting client if it is not nil
+func tlsClient(t *testing.T, ca *testCert, client *testCert) *http.Client {
+	t.Helper()
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: roots}
+	if client != nil {
+		pair, err := tls.X509KeyPair(client.certPEM, client.keyPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Send the certificate even when the server does not list its issuer
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &pair, nil
+		}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+}
+
+// startServer serves the common name of the verified client certificate, or "anonymous"
+func startServer(t *testing.T, reloader *certs.Reloader) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.VerifiedChains) > 0 {
+			w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+			return
+		}
+		w.Write([]byte("anonymous"))
+	}))
+	server.TLS = reloader.TLSConfig()
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil, true)
+	serverCert := newTestCert(t, "localhost", ca, false)
+	alice := newTestCert(t, "alice", ca, false)
+	stranger := newTestCert(t, "mallory", nil, false)
+
+	tests := []struct {
+		name    string
+		require bool
+		client  *testCert
+		want    string
+		wantErr bool
+	}{
+		{"verified client", false, alice, "alice", false},
+		{"no client certificate", false, nil, "anonymous", false},
+		{"untrusted client", false, stranger, "", true},
+		{"required and missing", true, nil, "", true},
+		{"required and present", true, alice, "alice", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader, err := certs.NewReloader(certs.Config{
+				CertFile:          writeFile(t, dir, "server.pem", serverCert.certPEM),
+				KeyFile:           writeFile(t, dir, "server.key", serverCert.keyPEM),
+				ClientCAFile:      writeFile(t, dir, "ca.pem", ca.certPEM),
+				RequireClientCert: tt.require,
+				MinVersion:        tls.VersionTLS12,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := startServer(t, reloader)
+
+			resp, err := tlsClient(t, ca, tt.client).Get(server.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Errorf("expected the connection to be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body := make([]byte, 64)
+			n, _ := resp.Body.Read(body)
+			if got := string(body[:n]); got != tt.want {
+				t.Errorf("wrong client: got %v want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadIfChanged(t *testing.T) {
+	dir := t.TempDir()
+	oldCA := newTestCert(t, "old-ca", nil, true)
+	newCA := newTestCert(t, "new-ca", nil, true)
+	oldCert := newTestCert(t, "localhost", oldCA, false)
+	newCert := newTestCert(t, "localhost", newCA, false)
+
+	certFile := writeFile(t, dir, "server.pem", oldCert.certPEM)
+	keyFile := writeFile(t, dir, "server.key", oldCert.keyPEM)
+	reloader, err := certs.NewReloader(certs.Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := startServer(t, reloader)
+
+	if changed, err := reloader.ReloadIfChanged(); err != nil || changed {
+		t.Errorf("unchanged files reloaded: got %v %v want false <nil>", changed, err)
+	}
+
+	// A broken certificate is not picked up
+	writeFile(t, dir, "server.pem", []byte("not a certificate"))
+	if _, err := reloader.ReloadIfChanged(); err == nil {
+		t.Errorf("expected an error for a broken certificate")
+	}
+	resp, err := tlsClient(t, oldCA, nil).Get(server.URL)
+	if err != nil {
+		t.Fatalf("old certificate not kept after a failed reload: %v", err)
+	}
+	resp.Body.Close()
+
+	// A new certificate is served once loaded
+	writeFile(t, dir, "server.pem", newCert.certPEM)
+	writeFile(t, dir, "server.key", newCert.keyPEM)
+	future := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if changed, err := reloader.ReloadIfChanged(); err != nil || !changed {
+		t.Fatalf("changed files not reloaded: got %v %v want true <nil>", changed, err)
+	}
+	resp, err = tlsClient(t, newCA, nil).Get(server.URL)
+	if err != nil {
+		t.Fatalf("new certificate not served: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	if version, err := certs.ParseTLSVersion("1.3"); err != nil || version != tls.VersionTLS13 {
+		t.Errorf("wrong version: got %v %v want %v", version, err, tls.VersionTLS13)
+	}
+	if _, err := certs.ParseTLSVersion("1.4"); err == nil {
+		t.Errorf("expected an error for an unknown version")
+	}
+}
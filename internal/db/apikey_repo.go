@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// APIKeyRepository handles API key operations
+type APIKeyRepository struct {
+	db *DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// apiKeyColumns lists the columns read into a models.APIKey
+const apiKeyColumns = `id, name, prefix, scopes, collections, expires_at, created_at, revoked_at`
+
+// Create stores a new API key under the hash of its secret
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey, keyHash string) error {
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to encode scopes: %w", err)
+	}
+	collections, err := json.Marshal(key.Collections)
+	if err != nil {
+		return fmt.Errorf("failed to encode collections: %w", err)
+	}
+
+	var expiresAt interface{}
+	if key.ExpiresAt != nil {
+		expiresAt = key.ExpiresAt.UTC()
+	}
+
+	query := `INSERT INTO api_keys (id, name, prefix, key_hash, scopes, collections, expires_at, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.db.ExecContext(ctx, query, key.ID, key.Name, key.Prefix, keyHash, string(scopes), string(collections), expiresAt, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an API key by ID
+func (r *APIKeyRepository) GetByID(ctx context.Context, id string) (*models.APIKey, error) {
+	row := r.db.reader.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id)
+
+	key, err := scanAPIKey(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("API key '%s' not found", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// GetByHash retrieves the API key whose secret has the given hash
+func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	row := r.db.reader.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, keyHash)
+
+	key, err := scanAPIKey(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("API key not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// List retrieves all API keys, newest first
+func (r *APIKeyRepository) List(ctx context.Context) (*models.APIKeyList, error) {
+	rows, err := r.db.reader.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over API keys: %w", err)
+	}
+
+	return &models.APIKeyList{
+		Total: len(keys),
+		Keys:  keys,
+	}, nil
+}
+
+// Revoke marks an API key as revoked
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, time.Now().UTC(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, fmt.Errorf("API key '%s' not found or already revoked", id)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// Rotate replaces the secret of an active API key
+func (r *APIKeyRepository) Rotate(ctx context.Context, id, prefix, keyHash string) (*models.APIKey, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE api_keys SET prefix = ?, key_hash = ? WHERE id = ? AND revoked_at IS NULL`,
+		prefix, keyHash, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate API key: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, fmt.Errorf("API key '%s' not found or revoked", id)
+	}
+
+	return r.GetByID(ctx, id)
+}
+
+// scanAPIKey reads an API key from a query result
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes, collections string
+	var expiresAt, revokedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &collections, &expiresAt, &key.CreatedAt, &revokedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan API key: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &key.Scopes); err != nil {
+		return nil, fmt.Errorf("failed to decode scopes: %w", err)
+	}
+	if err := json.Unmarshal([]byte(collections), &key.Collections); err != nil {
+		return nil, fmt.Errorf("failed to decode collections: %w", err)
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+
+	return &key, nil
+}
@@ -0,0 +1,254 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// auditBatchSize is the number of entries read per query when exporting or verifying the audit log
+const auditBatchSize = 1000
+
+// recordAudit appends an entry for a change to the audit log, chained to the previous entry.
+// The before hash is the after hash of the previous entry for the same collection or document,
+// so it is empty for records last changed before the audit log existed.
+func recordAudit(ctx context.Context, ex execer, change *models.Change, payload interface{}, actor models.Actor) error {
+	entry := &models.AuditEntry{
+		Timestamp:      change.Timestamp.UTC(),
+		Principal:      actor.Name,
+		SourceIP:       actor.SourceIP,
+		RequestID:      actor.RequestID,
+		Operation:      change.Operation,
+		CollectionName: change.CollectionName,
+		DocumentID:     change.DocumentID,
+	}
+	if payload, ok := payload.(string); ok {
+		sum := sha256.Sum256([]byte(payload))
+		entry.AfterHash = hex.EncodeToString(sum[:])
+	}
+
+	// Find the previous state of the record
+	err := ex.QueryRowContext(ctx,
+		`SELECT after_hash FROM audit_log WHERE collection_name = ? AND document_id = ? ORDER BY seq DESC LIMIT 1`,
+		entry.CollectionName, entry.DocumentID,
+	).Scan(&entry.BeforeHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get previous audit entry: %w", err)
+	}
+
+	// Chain to the last entry
+	err = ex.QueryRowContext(ctx, `SELECT seq, hash FROM audit_log ORDER BY seq DESC LIMIT 1`).Scan(&entry.Seq, &entry.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get last audit entry: %w", err)
+	}
+	entry.Seq++
+	entry.Hash = entry.ComputeHash()
+
+	query := `INSERT INTO audit_log (seq, timestamp, principal, source_ip, request_id, operation, collection_name,
+				  document_id, before_hash, after_hash, prev_hash, hash)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = ex.ExecContext(ctx,
+		query,
+		entry.Seq,
+		entry.Timestamp,
+		entry.Principal,
+		entry.SourceIP,
+		entry.RequestID,
+		string(entry.Operation),
+		entry.CollectionName,
+		entry.DocumentID,
+		entry.BeforeHash,
+		entry.AfterHash,
+		entry.PrevHash,
+		entry.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// AuditRepository handles audit log operations
+type AuditRepository struct {
+	db *DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// auditColumns lists the audit log columns in the order query scans them
+const auditColumns = `seq, timestamp, principal, source_ip, request_id, operation, collection_name,
+	document_id, before_hash, after_hash, prev_hash, hash`
+
+// auditFilter builds the WHERE clause for an audit query
+func auditFilter(queryParams *models.AuditQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if queryParams.Principal != "" {
+		add("principal = ?", queryParams.Principal)
+	}
+	if queryParams.Operation != "" {
+		add("operation = ?", queryParams.Operation)
+	}
+	if queryParams.CollectionName != "" {
+		add("collection_name = ?", queryParams.CollectionName)
+	}
+	if queryParams.DocumentID != "" {
+		add("document_id = ?", queryParams.DocumentID)
+	}
+	if queryParams.Since != nil {
+		add("timestamp >= ?", queryParams.Since.UTC())
+	}
+	if queryParams.Until != nil {
+		add("timestamp < ?", queryParams.Until.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return " WHERE 1 = 1", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// List retrieves audit log entries matching a query, newest first
+func (r *AuditRepository) List(ctx context.Context, queryParams *models.AuditQuery) (*models.AuditList, error) {
+	where, args := auditFilter(queryParams)
+
+	// Get total count
+	var total int
+	if err := r.db.reader.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	// Get entries with pagination
+	query := `SELECT ` + auditColumns + ` FROM audit_log` + where + `
+			  ORDER BY seq DESC
+			  LIMIT ? OFFSET ?`
+	entries, err := r.query(ctx, query, append(args, queryParams.Limit, queryParams.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuditList{
+		Total:   total,
+		Offset:  queryParams.Offset,
+		Limit:   queryParams.Limit,
+		Entries: entries,
+	}, nil
+}
+
+// Export passes every audit log entry matching a query to fn, oldest first. Entries are
+// read in batches so the database is not held while fn writes them out.
+func (r *AuditRepository) Export(ctx context.Context, queryParams *models.AuditQuery, fn func([]models.AuditEntry) error) error {
+	where, args := auditFilter(queryParams)
+	query := `SELECT ` + auditColumns + ` FROM audit_log` + where + ` AND seq > ?
+			  ORDER BY seq
+			  LIMIT ?`
+
+	var after int64
+	for {
+		entries, err := r.query(ctx, query, append(args, after, auditBatchSize)...)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		if err := fn(entries); err != nil {
+			return err
+		}
+		after = entries[len(entries)-1].Seq
+	}
+}
+
+// Verify recomputes the hash chain of the whole audit log and reports the first entry
+// that does not match
+func (r *AuditRepository) Verify(ctx context.Context) (*models.AuditVerification, error) {
+	result := &models.AuditVerification{Valid: true}
+	var prevHash string
+
+	err := r.Export(ctx, &models.AuditQuery{}, func(entries []models.AuditEntry) error {
+		for i := range entries {
+			entry := &entries[i]
+			switch {
+			case entry.Seq != result.LastSeq+1:
+				result.Error = fmt.Sprintf("entries %d to %d are missing", result.LastSeq+1, entry.Seq-1)
+			case entry.PrevHash != prevHash:
+				result.Error = "entry is not chained to the previous entry"
+			case entry.Hash != entry.ComputeHash():
+				result.Error = "entry does not match its hash"
+			}
+			if result.Error != "" {
+				result.Valid = false
+				result.BadSeq = entry.Seq
+				return errVerificationFailed
+			}
+
+			result.Entries++
+			result.LastSeq = entry.Seq
+			prevHash = entry.Hash
+		}
+		return nil
+	})
+	if err != nil && err != errVerificationFailed {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// errVerificationFailed stops an export once verification has found a broken entry
+var errVerificationFailed = errors.New("audit log verification failed")
+
+// query runs an audit log query and scans the entries
+func (r *AuditRepository) query(ctx context.Context, query string, args ...interface{}) ([]models.AuditEntry, error) {
+	rows, err := r.db.reader.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var entry models.AuditEntry
+		var operation string
+		err := rows.Scan(
+			&entry.Seq,
+			&entry.Timestamp,
+			&entry.Principal,
+			&entry.SourceIP,
+			&entry.RequestID,
+			&operation,
+			&entry.CollectionName,
+			&entry.DocumentID,
+			&entry.BeforeHash,
+			&entry.AfterHash,
+			&entry.PrevHash,
+			&entry.Hash,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.Operation = models.ChangeOperation(operation)
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over audit entries: %w", err)
+	}
+
+	return entries, nil
+}
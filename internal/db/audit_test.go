@@ -0,0 +1,91 @@
+package db_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestAuditLogChain(t *testing.T) {
+	database, err := db.New(db.NewConfig(filepath.Join(t.TempDir(), "db.sqlite")))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	collections := db.NewCollectionRepository(database)
+	documents := db.NewDocumentRepository(database, collections)
+	audit := db.NewAuditRepository(database)
+
+	alice := models.Actor{Name: "alice", SourceIP: "10.0.0.1", RequestID: "req-1"}
+	doc, err := documents.Create(t.Context(), "notes", json.RawMessage(`{"v":1}`), nil, "", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Update(t.Context(), doc.ID, "notes", json.RawMessage(`{"v":2}`), nil, nil, models.Actor{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := documents.Delete(t.Context(), doc.ID, "notes", models.Actor{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Creating the document also created its collection
+	list, err := audit.List(t.Context(), &models.AuditQuery{DocumentID: doc.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 3 {
+		t.Fatalf("wrong number of document entries: got %v want %v", list.Total, 3)
+	}
+	deleted, updated, created := list.Entries[0], list.Entries[1], list.Entries[2]
+	if created.Principal != "alice" || created.SourceIP != "10.0.0.1" || created.RequestID != "req-1" {
+		t.Errorf("wrong actor: got %+v", created)
+	}
+	if created.BeforeHash != "" || created.AfterHash == "" {
+		t.Errorf("wrong hashes for create: before %q after %q", created.BeforeHash, created.AfterHash)
+	}
+	if updated.BeforeHash != created.AfterHash || updated.AfterHash == created.AfterHash {
+		t.Errorf("update is not linked to the previous state: got before %q want %q", updated.BeforeHash, created.AfterHash)
+	}
+	if deleted.Operation != models.ChangeDocumentDelete || deleted.BeforeHash != updated.AfterHash || deleted.AfterHash != "" {
+		t.Errorf("wrong delete entry: %+v", deleted)
+	}
+
+	result, err := audit.Verify(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Entries != 4 {
+		t.Fatalf("expected a valid chain of 4 entries: %+v", result)
+	}
+
+	// Rewriting history breaks the chain at the altered entry
+	if _, err := database.Exec(`UPDATE audit_log SET principal = 'mallory' WHERE seq = ?`, updated.Seq); err != nil {
+		t.Fatal(err)
+	}
+	result, err = audit.Verify(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.BadSeq != updated.Seq {
+		t.Errorf("tampering not detected at entry %d: %+v", updated.Seq, result)
+	}
+
+	// So does removing an entry
+	if _, err := database.Exec(`UPDATE audit_log SET principal = 'bob' WHERE seq = ?`, updated.Seq); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`DELETE FROM audit_log WHERE seq = ?`, created.Seq); err != nil {
+		t.Fatal(err)
+	}
+	result, err = audit.Verify(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.BadSeq != updated.Seq {
+		t.Errorf("removed entry not detected: %+v", result)
+	}
+}
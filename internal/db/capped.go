@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// checkCapSize rejects a document that could never fit in a capped collection
+func checkCapSize(capOptions *models.CapOptions, data []byte) error {
+	if capOptions != nil && capOptions.MaxBytes > 0 && int64(len(data)) > capOptions.MaxBytes {
+		return fmt.Errorf("document of %d bytes exceeds the collection cap of %d bytes", len(data), capOptions.MaxBytes)
+	}
+	return nil
+}
+
+// evictOverflow deletes the oldest documents of a capped collection until it fits within its cap
+// and returns the number of documents evicted. Expired documents awaiting the sweeper do not
+// count against the cap, and the document with ID keep, the one just written, is never evicted.
+func evictOverflow(ctx context.Context, tx *sql.Tx, collectionName string, options models.CollectionOptions, keep string, now time.Time) (int, error) {
+	capOptions := options.Cap
+	if capOptions == nil || (capOptions.MaxDocuments <= 0 && capOptions.MaxBytes <= 0) {
+		return 0, nil
+	}
+
+	count, size, err := measureCollection(ctx, tx, collectionName, now)
+	if err != nil {
+		return 0, err
+	}
+
+	// fits reports whether the collection is within its cap
+	fits := func() bool {
+		return (capOptions.MaxDocuments <= 0 || count <= capOptions.MaxDocuments) &&
+			(capOptions.MaxBytes <= 0 || size <= capOptions.MaxBytes)
+	}
+	if fits() {
+		return 0, nil
+	}
+
+	// Pick the oldest documents until the rest fits
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, LENGTH(data) FROM documents
+		 WHERE `+liveDocuments+` AND id != ?
+		 ORDER BY created_at, id`,
+		collectionName, now.UTC(), keep,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find documents to evict: %w", err)
+	}
+	var evicted []string
+	for !fits() && rows.Next() {
+		var id string
+		var length int64
+		if err := rows.Scan(&id, &length); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan document to evict: %w", err)
+		}
+		evicted = append(evicted, id)
+		count--
+		size -= length
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating over documents to evict: %w", err)
+	}
+
+	// Evict them
+	for _, id := range evicted {
+		if err := removeDocument(ctx, tx, options.History, collectionName, id, systemActor, now); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(evicted), nil
+}
@@ -0,0 +1,174 @@
+package db_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestCappedCollectionEvictsOldest(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{Cap: &models.CapOptions{MaxDocuments: 3}}
+	if _, err := collections.CreateWithOptions(t.Context(), "feed", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		doc, err := documents.Create(t.Context(), "feed", json.RawMessage(fmt.Sprintf(`{"n":%d}`, i)), nil, "", models.Actor{Name: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, doc.ID)
+		time.Sleep(time.Millisecond)
+	}
+
+	list, err := documents.List(t.Context(), "feed", models.NewDocumentQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 3 {
+		t.Fatalf("wrong number of documents: got %v want %v", list.Total, 3)
+	}
+	for _, id := range ids[:2] {
+		if _, err := documents.GetByID(t.Context(), id, "feed"); err == nil {
+			t.Errorf("oldest document %s was not evicted", id)
+		}
+	}
+
+	// A bulk insert larger than the cap keeps only its newest documents
+	items := []json.RawMessage{json.RawMessage(`{"n":5}`), json.RawMessage(`{"n":6}`), json.RawMessage(`{"n":7}`), json.RawMessage(`{"n":8}`)}
+	if _, err := documents.BulkCreate(t.Context(), "feed", items, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	list, err = documents.List(t.Context(), "feed", models.NewDocumentQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 3 {
+		t.Errorf("wrong number of documents after bulk insert: got %v want %v", list.Total, 3)
+	}
+}
+
+func TestCappedCollectionByBytes(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	// Each document below is 10 bytes
+	options := models.CollectionOptions{Cap: &models.CapOptions{MaxBytes: 25}}
+	if _, err := collections.CreateWithOptions(t.Context(), "logs", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := documents.Create(t.Context(), "logs", json.RawMessage(`{"n":"aa"}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	for _, data := range []string{`{"n":"bb"}`, `{"n":"cc"}`} {
+		if _, err := documents.Create(t.Context(), "logs", json.RawMessage(data), nil, "", models.Actor{Name: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := documents.GetByID(t.Context(), first.ID, "logs"); err == nil {
+		t.Errorf("oldest document was not evicted")
+	}
+
+	if _, err := documents.Create(t.Context(), "logs", json.RawMessage(`{"n":"this is far too large"}`), nil, "", models.Actor{Name: "alice"}); err == nil {
+		t.Errorf("document larger than the cap was accepted")
+	}
+}
+
+func TestCapAppliedToExistingCollection(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	for i := 0; i < 4; i++ {
+		if _, err := documents.Create(t.Context(), "events", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	options := models.CollectionOptions{Cap: &models.CapOptions{MaxDocuments: 2}}
+	if _, err := collections.UpdateOptions(t.Context(), "events", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := documents.List(t.Context(), "events", models.NewDocumentQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 2 {
+		t.Errorf("collection not trimmed to its cap: got %v want %v", list.Total, 2)
+	}
+}
+
+func TestCappedCollectionUpdateGrowth(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	// Each document below is 10 bytes
+	options := models.CollectionOptions{Cap: &models.CapOptions{MaxBytes: 25}}
+	if _, err := collections.CreateWithOptions(t.Context(), "logs", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, data := range []string{`{"n":"aa"}`, `{"n":"bb"}`} {
+		doc, err := documents.Create(t.Context(), "logs", json.RawMessage(data), nil, "", models.Actor{Name: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, doc.ID)
+		time.Sleep(time.Millisecond)
+	}
+
+	// Growing the oldest document evicts the other one rather than itself
+	if _, err := documents.Update(t.Context(), ids[0], "logs", json.RawMessage(`{"n":"aaaaaaaa"}`), nil, nil, models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.GetByID(t.Context(), ids[0], "logs"); err != nil {
+		t.Errorf("updated document was evicted: %v", err)
+	}
+	if _, err := documents.GetByID(t.Context(), ids[1], "logs"); err == nil {
+		t.Errorf("collection over its byte cap after update")
+	}
+
+	// An update larger than the cap is rejected
+	if _, err := documents.Update(t.Context(), ids[0], "logs", json.RawMessage(`{"n":"this is far too large"}`), nil, nil, models.Actor{Name: "alice"}); err == nil {
+		t.Errorf("update larger than the cap was accepted")
+	}
+}
+
+func TestCappedCollectionIgnoresExpired(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{Cap: &models.CapOptions{MaxDocuments: 2}}
+	if _, err := collections.CreateWithOptions(t.Context(), "feed", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An expired document awaiting the sweeper does not take up room
+	soon := time.Now().Add(10 * time.Millisecond)
+	if _, err := documents.Create(t.Context(), "feed", json.RawMessage(`{"n":0}`), &soon, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	var ids []string
+	for i := 1; i <= 2; i++ {
+		doc, err := documents.Create(t.Context(), "feed", json.RawMessage(fmt.Sprintf(`{"n":%d}`, i)), nil, "", models.Actor{Name: "alice"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, doc.ID)
+	}
+
+	for _, id := range ids {
+		if _, err := documents.GetByID(t.Context(), id, "feed"); err != nil {
+			t.Errorf("document %s evicted while the cap counted an expired document: %v", id, err)
+		}
+	}
+}
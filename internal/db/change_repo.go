@@ -0,0 +1,426 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// execer is implemented by both *DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// systemAuthor attributes changes made by the server itself, such as expiry and eviction
+const systemAuthor = "system"
+
+// systemActor is the actor behind changes made by the server itself
+var systemActor = models.Actor{Name: systemAuthor}
+
+// recordChange appends a mutation made by actor to the change log and the audit log
+func recordChange(ctx context.Context, ex execer, change *models.Change, actor models.Actor) error {
+	var payload interface{}
+	switch {
+	case change.Document != nil:
+		b, err := json.Marshal(change.Document)
+		if err != nil {
+			return fmt.Errorf("failed to encode change payload: %w", err)
+		}
+		payload = string(b)
+	case change.Collection != nil:
+		b, err := json.Marshal(change.Collection)
+		if err != nil {
+			return fmt.Errorf("failed to encode change payload: %w", err)
+		}
+		payload = string(b)
+	}
+
+	if change.Timestamp.IsZero() {
+		change.Timestamp = time.Now()
+	}
+	change.Author = actor.Name
+
+	query := `INSERT INTO changes (operation, collection_name, document_id, payload, author, timestamp)
+			  VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := ex.ExecContext(ctx,
+		query,
+		string(change.Operation),
+		change.CollectionName,
+		change.DocumentID,
+		payload,
+		change.Author,
+		change.Timestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record change: %w", err)
+	}
+
+	return recordAudit(ctx, ex, change, payload, actor)
+}
+
+// ChangeRepository handles change log and replication operations
+type ChangeRepository struct {
+	db *DB
+}
+
+// NewChangeRepository creates a new change repository
+func NewChangeRepository(db *DB) *ChangeRepository {
+	return &ChangeRepository{db: db}
+}
+
+// ListSince retrieves up to limit changes with a sequence number greater than seq. It returns
+// models.ErrSnapshotRequired when the changes right after seq have been pruned.
+func (r *ChangeRepository) ListSince(ctx context.Context, seq int64, limit int) (*models.ChangeList, error) {
+	list := &models.ChangeList{
+		Changes: make([]models.Change, 0),
+	}
+
+	// Get the head of the change log
+	var latestTimestamp sql.NullTime
+	err := r.db.reader.QueryRowContext(ctx, `SELECT seq, timestamp FROM changes ORDER BY seq DESC LIMIT 1`).
+		Scan(&list.LatestSeq, &latestTimestamp)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get latest change: %w", err)
+	}
+	if latestTimestamp.Valid {
+		list.LatestTimestamp = latestTimestamp.Time
+	}
+
+	// Get changes after the requested sequence number
+	query := `SELECT seq, operation, collection_name, document_id, payload, author, timestamp
+			  FROM changes
+			  WHERE seq > ?
+			  ORDER BY seq
+			  LIMIT ?`
+	rows, err := r.db.reader.QueryContext(ctx, query, seq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change models.Change
+		var operation string
+		var documentID, payload sql.NullString
+		err := rows.Scan(
+			&change.Seq,
+			&operation,
+			&change.CollectionName,
+			&documentID,
+			&payload,
+			&change.Author,
+			&change.Timestamp,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan change: %w", err)
+		}
+		change.Operation = models.ChangeOperation(operation)
+		change.DocumentID = documentID.String
+
+		if err := decodeChangePayload(&change, payload); err != nil {
+			return nil, err
+		}
+		list.Changes = append(list.Changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over changes: %w", err)
+	}
+
+	// Sequence numbers have no gaps, so a missing successor of seq has been pruned
+	if len(list.Changes) > 0 && list.Changes[0].Seq != seq+1 {
+		return nil, models.ErrSnapshotRequired
+	}
+
+	return list, nil
+}
+
+// Prune deletes changes recorded before cutoff and returns the number deleted. The latest
+// change is always kept, so followers can tell how far behind they are.
+func (r *ChangeRepository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM changes
+			  WHERE julianday(timestamp) < julianday(?)
+			  AND seq < (SELECT MAX(seq) FROM changes)`
+	result, err := r.db.ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune changes: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// decodeChangePayload restores the collection or document carried by a change
+func decodeChangePayload(change *models.Change, payload sql.NullString) error {
+	if !payload.Valid || payload.String == "" {
+		return nil
+	}
+
+	switch change.Operation {
+	case models.ChangeCollectionCreate, models.ChangeCollectionUpdate:
+		change.Collection = &models.Collection{}
+		if err := json.Unmarshal([]byte(payload.String), change.Collection); err != nil {
+			return fmt.Errorf("failed to decode change %d: %w", change.Seq, err)
+		}
+	case models.ChangeDocumentPut, models.ChangeDocumentShare:
+		change.Document = &models.Document{}
+		if err := json.Unmarshal([]byte(payload.String), change.Document); err != nil {
+			return fmt.Errorf("failed to decode change %d: %w", change.Seq, err)
+		}
+	}
+
+	return nil
+}
+
+// GetCheckpoint returns the position in the change log of the primary up to which changes
+// from a replication source have been applied, or nil when nothing has been applied yet
+func (r *ChangeRepository) GetCheckpoint(ctx context.Context, source string) (*models.ChangeCheckpoint, error) {
+	query := `SELECT last_seq, last_timestamp FROM replication_state WHERE source = ?`
+
+	var checkpoint models.ChangeCheckpoint
+	var timestamp sql.NullTime
+	err := r.db.reader.QueryRowContext(ctx, query, source).Scan(&checkpoint.Seq, &timestamp)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replication checkpoint: %w", err)
+	}
+	checkpoint.Timestamp = timestamp.Time
+
+	return &checkpoint, nil
+}
+
+// ApplyBatch applies replicated changes with their original ids and timestamps
+// and advances the checkpoint for the source in the same transaction
+func (r *ChangeRepository) ApplyBatch(ctx context.Context, source string, changes []models.Change) (err error) {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Apply each change in order
+	for i := range changes {
+		if err = applyChange(ctx, tx, &changes[i]); err != nil {
+			return fmt.Errorf("failed to apply change %d: %w", changes[i].Seq, err)
+		}
+	}
+
+	// Advance checkpoint
+	last := changes[len(changes)-1]
+	if err = saveCheckpoint(ctx, tx, source, last.Seq, last.Timestamp); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// saveCheckpoint records the last applied sequence number and change timestamp for a source
+func saveCheckpoint(ctx context.Context, tx *sql.Tx, source string, seq int64, timestamp time.Time) error {
+	query := `INSERT INTO replication_state (source, last_seq, last_timestamp, updated_at)
+			  VALUES (?, ?, ?, ?)
+			  ON CONFLICT(source) DO UPDATE SET
+				  last_seq = excluded.last_seq,
+				  last_timestamp = excluded.last_timestamp,
+				  updated_at = excluded.updated_at`
+	_, err := tx.ExecContext(ctx, query, source, seq, timestamp, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save replication checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// applyChange applies a single replicated change within a transaction
+func applyChange(ctx context.Context, tx *sql.Tx, change *models.Change) error {
+	switch change.Operation {
+	case models.ChangeCollectionCreate, models.ChangeCollectionUpdate:
+		collection := change.Collection
+		if collection == nil {
+			return fmt.Errorf("missing collection payload")
+		}
+		options, err := encodeCollectionOptions(collection.Options)
+		if err != nil {
+			return err
+		}
+		query := `INSERT INTO collections (name, options, created_at, updated_at) VALUES (?, ?, ?, ?)
+				  ON CONFLICT(name) DO UPDATE SET
+					  options = excluded.options,
+					  created_at = excluded.created_at,
+					  updated_at = excluded.updated_at`
+		_, err = tx.ExecContext(ctx, query, collection.Name, options, collection.CreatedAt, collection.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		if collection.Options.HistoryEnabled() {
+			if err := seedRevisions(ctx, tx, collection.Name); err != nil {
+				return err
+			}
+		}
+
+		return pruneRevisions(ctx, tx, collection.Name, "", collection.Options.History, change.Timestamp)
+
+	case models.ChangeCollectionDelete:
+		_, err := tx.ExecContext(ctx, `DELETE FROM collections WHERE name = ?`, change.CollectionName)
+		return err
+
+	case models.ChangeDocumentPut:
+		document := change.Document
+		if document == nil {
+			return fmt.Errorf("missing document payload")
+		}
+
+		// Make sure the parent collection exists
+		_, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO collections (name, created_at, updated_at) VALUES (?, ?, ?)`,
+			document.CollectionName, document.CreatedAt, document.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		// Mirror the primary's revision history
+		options, err := getCollectionOptions(ctx, tx, document.CollectionName)
+		if err != nil {
+			return err
+		}
+		var trashed bool
+		operation := models.RevisionUpdate
+		err = tx.QueryRowContext(ctx,
+			`SELECT deleted_at IS NOT NULL FROM documents WHERE id = ? AND collection_name = ?`,
+			document.ID, document.CollectionName,
+		).Scan(&trashed)
+		if err == sql.ErrNoRows {
+			operation = models.RevisionCreate
+		} else if err != nil {
+			return err
+		} else if trashed {
+			operation = models.RevisionRestore
+		}
+		err = recordRevision(ctx, tx, options.History, document.CollectionName, document.ID, operation, document.Data, change.Author, change.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		sharedWith, err := encodeSharedWith(document.SharedWith)
+		if err != nil {
+			return err
+		}
+		query := `INSERT INTO documents (id, collection_name, data, created_at, updated_at, expires_at, owner, shared_with)
+				  VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				  ON CONFLICT(id, collection_name) DO UPDATE SET
+					  data = excluded.data,
+					  created_at = excluded.created_at,
+					  updated_at = excluded.updated_at,
+					  expires_at = excluded.expires_at,
+					  owner = excluded.owner,
+					  shared_with = excluded.shared_with,
+					  deleted_at = NULL`
+		_, err = tx.ExecContext(ctx,
+			query,
+			document.ID,
+			document.CollectionName,
+			document.Data,
+			document.CreatedAt,
+			document.UpdatedAt,
+			storedExpiry(document.ExpiresAt),
+			document.Owner,
+			sharedWith,
+		)
+		if err != nil {
+			return err
+		}
+		if err = stampRevision(ctx, tx, options.History, document.CollectionName, document.ID); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE collections SET updated_at = ? WHERE name = ?`, change.Timestamp, document.CollectionName)
+		return err
+
+	case models.ChangeDocumentShare:
+		if change.Document == nil {
+			return fmt.Errorf("missing document payload")
+		}
+		sharedWith, err := encodeSharedWith(change.Document.SharedWith)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE documents SET shared_with = ? WHERE id = ? AND collection_name = ?`,
+			sharedWith, change.DocumentID, change.CollectionName,
+		)
+		if err != nil {
+			return err
+		}
+		options, err := getCollectionOptions(ctx, tx, change.CollectionName)
+		if err != nil {
+			return err
+		}
+		return stampRevision(ctx, tx, options.History, change.CollectionName, change.DocumentID)
+
+	case models.ChangeCollectionTrash:
+		return trashCollection(ctx, tx, change.CollectionName, change.Timestamp)
+
+	case models.ChangeCollectionRestore:
+		return restoreCollection(ctx, tx, change.CollectionName, change.Timestamp)
+
+	case models.ChangeDocumentDelete, models.ChangeDocumentTrash:
+		// Purging a document that is already in the trash leaves its history untouched
+		var live int
+		err := tx.QueryRowContext(ctx,
+			`SELECT 1 FROM documents WHERE id = ? AND collection_name = ? AND deleted_at IS NULL`,
+			change.DocumentID, change.CollectionName,
+		).Scan(&live)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == nil {
+			options, err := getCollectionOptions(ctx, tx, change.CollectionName)
+			if err != nil {
+				return err
+			}
+			err = recordRevision(ctx, tx, options.History, change.CollectionName, change.DocumentID, models.RevisionDelete, nil, change.Author, change.Timestamp)
+			if err != nil {
+				return err
+			}
+		}
+
+		if change.Operation == models.ChangeDocumentTrash {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE documents SET deleted_at = ? WHERE id = ? AND collection_name = ?`,
+				change.Timestamp.UTC(), change.DocumentID, change.CollectionName,
+			)
+		} else {
+			_, err = tx.ExecContext(ctx, `DELETE FROM documents WHERE id = ? AND collection_name = ?`, change.DocumentID, change.CollectionName)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE collections SET updated_at = ? WHERE name = ?`, change.Timestamp, change.CollectionName)
+		return err
+
+	default:
+		return fmt.Errorf("unknown operation '%s'", change.Operation)
+	}
+}
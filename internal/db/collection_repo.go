@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -18,10 +20,24 @@ func NewCollectionRepository(db *DB) *CollectionRepository {
 	return &CollectionRepository{db: db}
 }
 
-// Create creates a new collection
-func (r *CollectionRepository) Create(name string) (*models.Collection, error) {
+// Create creates a new collection with default options
+func (r *CollectionRepository) Create(ctx context.Context, name string, actor models.Actor) (*models.Collection, error) {
+	return r.CreateWithOptions(ctx, name, models.CollectionOptions{}, actor)
+}
+
+// CreateWithOptions creates a new collection with the given options
+func (r *CollectionRepository) CreateWithOptions(ctx context.Context, name string, options models.CollectionOptions, actor models.Actor) (*models.Collection, error) {
+	// Validate options
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	encodedOptions, err := encodeCollectionOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if collection already exists
-	exists, err := r.Exists(name)
+	exists, err := r.Exists(ctx, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if collection exists: %w", err)
 	}
@@ -29,40 +45,81 @@ func (r *CollectionRepository) Create(name string) (*models.Collection, error) {
 		return nil, fmt.Errorf("collection '%s' already exists", name)
 	}
 
+	// A trashed collection keeps its name until it is restored or purged
+	trashed, err := r.inTrash(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if trashed {
+		return nil, fmt.Errorf("collection '%s' is in the trash; restore or purge it first", name)
+	}
+
 	// Create collection
 	collection := models.NewCollection(name)
+	collection.Options = options
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
 
 	// Insert collection into database
-	query := `INSERT INTO collections (name, created_at, updated_at) VALUES (?, ?, ?)`
-	_, err = r.db.Exec(query, collection.Name, collection.CreatedAt, collection.UpdatedAt)
+	query := `INSERT INTO collections (name, options, created_at, updated_at) VALUES (?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, query, collection.Name, encodedOptions, collection.CreatedAt, collection.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create collection: %w", err)
 	}
 
+	// Record change
+	err = recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeCollectionCreate,
+		CollectionName: collection.Name,
+		Collection:     collection,
+		Timestamp:      collection.CreatedAt,
+	}, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return collection, nil
 }
 
 // GetByName retrieves a collection by name
-func (r *CollectionRepository) GetByName(name string) (*models.Collection, error) {
-	query := `SELECT name, created_at, updated_at FROM collections WHERE name = ?`
-	row := r.db.QueryRow(query, name)
+func (r *CollectionRepository) GetByName(ctx context.Context, name string) (*models.Collection, error) {
+	query := `SELECT name, options, created_at, updated_at FROM collections WHERE name = ? AND deleted_at IS NULL`
+	row := r.db.reader.QueryRowContext(ctx, query, name)
 
 	var collection models.Collection
-	err := row.Scan(&collection.Name, &collection.CreatedAt, &collection.UpdatedAt)
+	var options string
+	err := row.Scan(&collection.Name, &options, &collection.CreatedAt, &collection.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("collection '%s' not found", name)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get collection: %w", err)
 	}
+	if collection.Options, err = decodeCollectionOptions(options); err != nil {
+		return nil, err
+	}
 
 	return &collection, nil
 }
 
 // Exists checks if a collection exists
-func (r *CollectionRepository) Exists(name string) (bool, error) {
-	query := `SELECT 1 FROM collections WHERE name = ?`
-	row := r.db.QueryRow(query, name)
+func (r *CollectionRepository) Exists(ctx context.Context, name string) (bool, error) {
+	query := `SELECT 1 FROM collections WHERE name = ? AND deleted_at IS NULL`
+	row := r.db.reader.QueryRowContext(ctx, query, name)
 
 	var exists int
 	err := row.Scan(&exists)
@@ -76,10 +133,26 @@ func (r *CollectionRepository) Exists(name string) (bool, error) {
 	return true, nil
 }
 
-// Delete deletes a collection
-func (r *CollectionRepository) Delete(name string) error {
+// inTrash checks if a collection is in the trash
+func (r *CollectionRepository) inTrash(ctx context.Context, name string) (bool, error) {
+	query := `SELECT 1 FROM collections WHERE name = ? AND deleted_at IS NOT NULL`
+
+	var trashed int
+	err := r.db.reader.QueryRowContext(ctx, query, name).Scan(&trashed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check if collection is in the trash: %w", err)
+	}
+
+	return true, nil
+}
+
+// Delete deletes a collection, moving it and its documents to the trash when soft delete is enabled
+func (r *CollectionRepository) Delete(ctx context.Context, name string, actor models.Actor) error {
 	// Check if collection exists
-	exists, err := r.Exists(name)
+	exists, err := r.Exists(ctx, name)
 	if err != nil {
 		return fmt.Errorf("failed to check if collection exists: %w", err)
 	}
@@ -87,29 +160,56 @@ func (r *CollectionRepository) Delete(name string) error {
 		return fmt.Errorf("collection '%s' not found", name)
 	}
 
-	// Delete collection
-	query := `DELETE FROM collections WHERE name = ?`
-	_, err = r.db.Exec(query, name)
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Move to trash or delete permanently
+	now := time.Now()
+	if r.db.config.SoftDelete {
+		if err = trashCollection(ctx, tx, name, now); err != nil {
+			return err
+		}
+		err = recordChange(ctx, tx, &models.Change{
+			Operation:      models.ChangeCollectionTrash,
+			CollectionName: name,
+			Timestamp:      now,
+		}, actor)
+	} else {
+		err = purgeCollection(ctx, tx, name, actor)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to delete collection: %w", err)
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
 // List retrieves all collections
-func (r *CollectionRepository) List() (*models.CollectionList, error) {
+func (r *CollectionRepository) List(ctx context.Context) (*models.CollectionList, error) {
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM collections`
+	countQuery := `SELECT COUNT(*) FROM collections WHERE deleted_at IS NULL`
 	var total int
-	err := r.db.QueryRow(countQuery).Scan(&total)
+	err := r.db.reader.QueryRowContext(ctx, countQuery).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count collections: %w", err)
 	}
 
 	// Get collections
-	query := `SELECT name, created_at, updated_at FROM collections ORDER BY name`
-	rows, err := r.db.Query(query)
+	query := `SELECT name, options, created_at, updated_at FROM collections WHERE deleted_at IS NULL ORDER BY name`
+	rows, err := r.db.reader.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list collections: %w", err)
 	}
@@ -118,10 +218,14 @@ func (r *CollectionRepository) List() (*models.CollectionList, error) {
 	collections := make([]models.Collection, 0)
 	for rows.Next() {
 		var collection models.Collection
-		err := rows.Scan(&collection.Name, &collection.CreatedAt, &collection.UpdatedAt)
+		var options string
+		err := rows.Scan(&collection.Name, &options, &collection.CreatedAt, &collection.UpdatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan collection: %w", err)
 		}
+		if collection.Options, err = decodeCollectionOptions(options); err != nil {
+			return nil, err
+		}
 		collections = append(collections, collection)
 	}
 
@@ -136,9 +240,9 @@ func (r *CollectionRepository) List() (*models.CollectionList, error) {
 }
 
 // Update updates a collection
-func (r *CollectionRepository) Update(name string) (*models.Collection, error) {
+func (r *CollectionRepository) Update(ctx context.Context, name string) (*models.Collection, error) {
 	// Check if collection exists
-	collection, err := r.GetByName(name)
+	collection, err := r.GetByName(ctx, name)
 	if err != nil {
 		return nil, err
 	}
@@ -148,10 +252,152 @@ func (r *CollectionRepository) Update(name string) (*models.Collection, error) {
 
 	// Update collection
 	query := `UPDATE collections SET updated_at = ? WHERE name = ?`
-	_, err = r.db.Exec(query, collection.UpdatedAt, name)
+	_, err = r.db.ExecContext(ctx, query, collection.UpdatedAt, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update collection: %w", err)
 	}
 
 	return collection, nil
 }
+
+// UpdateOptions replaces the options of a collection
+func (r *CollectionRepository) UpdateOptions(ctx context.Context, name string, options models.CollectionOptions, actor models.Actor) (*models.Collection, error) {
+	// Validate options
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	encodedOptions, err := encodeCollectionOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if collection exists
+	collection, err := r.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	collection.Options = options
+	collection.UpdatedAt = time.Now()
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Update collection
+	query := `UPDATE collections SET options = ?, updated_at = ? WHERE name = ?`
+	_, err = tx.ExecContext(ctx, query, encodedOptions, collection.UpdatedAt, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update collection options: %w", err)
+	}
+
+	// Start history from the current version of each document
+	if options.HistoryEnabled() {
+		if err = seedRevisions(ctx, tx, name); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the new retention limits to existing history
+	if err = pruneRevisions(ctx, tx, name, "", options.History, collection.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Trim a capped collection to its new size
+	if _, err = evictOverflow(ctx, tx, name, options, "", collection.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Record change
+	err = recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeCollectionUpdate,
+		CollectionName: name,
+		Collection:     collection,
+		Timestamp:      collection.UpdatedAt,
+	}, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return collection, nil
+}
+
+// queryRower is implemented by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// DocumentCounts returns the number of live documents in each collection
+func (r *CollectionRepository) DocumentCounts(ctx context.Context) (map[string]int, error) {
+	query := `SELECT c.name, COUNT(d.id) FROM collections c
+			  LEFT JOIN documents d ON d.collection_name = c.name AND d.deleted_at IS NULL
+			  AND (d.expires_at IS NULL OR d.expires_at > ?)
+			  WHERE c.deleted_at IS NULL
+			  GROUP BY c.name`
+	rows, err := r.db.reader.QueryContext(ctx, query, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count documents: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var count int
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan document count: %w", err)
+		}
+		counts[name] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over document counts: %w", err)
+	}
+
+	return counts, nil
+}
+
+// getCollectionOptions loads the options of a collection, possibly within a transaction
+func getCollectionOptions(ctx context.Context, q queryRower, name string) (models.CollectionOptions, error) {
+	var options string
+	err := q.QueryRowContext(ctx, `SELECT options FROM collections WHERE name = ? AND deleted_at IS NULL`, name).Scan(&options)
+	if err == sql.ErrNoRows {
+		return models.CollectionOptions{}, fmt.Errorf("collection '%s' not found", name)
+	}
+	if err != nil {
+		return models.CollectionOptions{}, fmt.Errorf("failed to get collection options: %w", err)
+	}
+	return decodeCollectionOptions(options)
+}
+
+// encodeCollectionOptions serializes collection options for storage
+func encodeCollectionOptions(options models.CollectionOptions) (string, error) {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode collection options: %w", err)
+	}
+	return string(b), nil
+}
+
+// decodeCollectionOptions parses stored collection options
+func decodeCollectionOptions(options string) (models.CollectionOptions, error) {
+	var decoded models.CollectionOptions
+	if options == "" {
+		return decoded, nil
+	}
+	if err := json.Unmarshal([]byte(options), &decoded); err != nil {
+		return decoded, fmt.Errorf("failed to decode collection options: %w", err)
+	}
+	return decoded, nil
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -23,17 +24,17 @@ func NewDocumentRepository(db *DB, collectionRepo *CollectionRepository) *Docume
 	}
 }
 
-// Create creates a new document
-func (r *DocumentRepository) Create(collectionName string, data json.RawMessage) (*models.Document, error) {
+// Create creates a new document owned by owner. A nil expiresAt applies the collection's default TTL.
+func (r *DocumentRepository) Create(ctx context.Context, collectionName string, data json.RawMessage, expiresAt *time.Time, owner string, actor models.Actor) (*models.Document, error) {
 	// Check if collection exists
-	exists, err := r.collectionRepo.Exists(collectionName)
+	exists, err := r.collectionRepo.Exists(ctx, collectionName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if collection exists: %w", err)
 	}
 
 	// If collection doesn't exist, create it first
 	if !exists {
-		_, err := r.collectionRepo.Create(collectionName)
+		_, err := r.collectionRepo.Create(ctx, collectionName, actor)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create collection: %w", err)
 		}
@@ -46,35 +47,21 @@ func (r *DocumentRepository) Create(collectionName string, data json.RawMessage)
 
 	// Create document
 	document := models.NewDocument(collectionName, data)
+	document.ExpiresAt = expiresAt
+	document.Owner = owner
 
 	// Insert document into database
-	query := `INSERT INTO documents (id, collection_name, data, created_at, updated_at) 
-			  VALUES (?, ?, ?, ?, ?)`
-	_, err = r.db.Exec(
-		query,
-		document.ID,
-		document.CollectionName,
-		document.Data,
-		document.CreatedAt,
-		document.UpdatedAt,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create document: %w", err)
-	}
-
-	// Update collection timestamp
-	_, err = r.collectionRepo.Update(collectionName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update collection timestamp: %w", err)
+	if err := r.insert(ctx, document, actor); err != nil {
+		return nil, err
 	}
 
 	return document, nil
 }
 
 // CreateWithID creates a new document with the specified ID
-func (r *DocumentRepository) CreateWithID(id, collectionName string, data json.RawMessage) (*models.Document, error) {
+func (r *DocumentRepository) CreateWithID(ctx context.Context, id, collectionName string, data json.RawMessage, expiresAt *time.Time, owner string, actor models.Actor) (*models.Document, error) {
 	// Check if collection exists
-	exists, err := r.collectionRepo.Exists(collectionName)
+	exists, err := r.collectionRepo.Exists(ctx, collectionName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if collection exists: %w", err)
 	}
@@ -82,15 +69,6 @@ func (r *DocumentRepository) CreateWithID(id, collectionName string, data json.R
 		return nil, fmt.Errorf("collection '%s' not found", collectionName)
 	}
 
-	// Check if document with this ID already exists
-	exists, err = r.Exists(id, collectionName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check if document exists: %w", err)
-	}
-	if exists {
-		return nil, fmt.Errorf("document with ID '%s' already exists in collection '%s'", id, collectionName)
-	}
-
 	// Validate JSON data
 	if err := models.ValidateJSON(data); err != nil {
 		return nil, err
@@ -98,46 +76,160 @@ func (r *DocumentRepository) CreateWithID(id, collectionName string, data json.R
 
 	// Create document
 	document := models.NewDocumentWithID(id, collectionName, data)
+	document.ExpiresAt = expiresAt
+	document.Owner = owner
 
 	// Insert document into database
-	query := `INSERT INTO documents (id, collection_name, data, created_at, updated_at) 
-			  VALUES (?, ?, ?, ?, ?)`
-	_, err = r.db.Exec(
+	if err := r.insert(ctx, document, actor); err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// insert writes a new document, its change record and the collection timestamp in a single transaction
+func (r *DocumentRepository) insert(ctx context.Context, document *models.Document, actor models.Actor) (err error) {
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	options, err := getCollectionOptions(ctx, tx, document.CollectionName)
+	if err != nil {
+		return err
+	}
+
+	// Reject documents beyond the collection's limits or larger than its cap
+	if err = r.limits(options).Check(document.Data); err != nil {
+		return err
+	}
+	if err = checkCapSize(options.Cap, document.Data); err != nil {
+		return err
+	}
+
+	// Apply the collection's default TTL
+	if document.ExpiresAt == nil {
+		document.ExpiresAt = options.DefaultExpiry(document.CreatedAt)
+	}
+
+	// A trashed document keeps its ID until it is purged, as it can still be restored. An
+	// expired one is removed as the expiry sweep would, so the new document replaces it.
+	var deletedAt, expiresAt sql.NullTime
+	err = tx.QueryRowContext(ctx,
+		`SELECT deleted_at, expires_at FROM documents WHERE id = ? AND collection_name = ?`,
+		document.ID, document.CollectionName,
+	).Scan(&deletedAt, &expiresAt)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return fmt.Errorf("failed to check if document exists: %w", err)
+	case deletedAt.Valid:
+		return fmt.Errorf("document with ID '%s' of collection '%s': %w", document.ID, document.CollectionName, models.ErrDocumentInTrash)
+	case expiresAt.Valid && !expiresAt.Time.After(document.CreatedAt):
+		if err = removeDocument(ctx, tx, options.History, document.CollectionName, document.ID, systemActor, document.CreatedAt); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("document with ID '%s' already exists in collection '%s'", document.ID, document.CollectionName)
+	}
+
+	// Record revision
+	err = recordRevision(ctx, tx, options.History, document.CollectionName, document.ID, models.RevisionCreate, document.Data, actor.Name, document.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	sharedWith, err := encodeSharedWith(document.SharedWith)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO documents (id, collection_name, data, created_at, updated_at, expires_at, owner, shared_with) 
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx,
 		query,
 		document.ID,
 		document.CollectionName,
 		document.Data,
 		document.CreatedAt,
 		document.UpdatedAt,
+		storedExpiry(document.ExpiresAt),
+		document.Owner,
+		sharedWith,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create document: %w", err)
+		return fmt.Errorf("failed to create document: %w", err)
+	}
+	if err = stampRevision(ctx, tx, options.History, document.CollectionName, document.ID); err != nil {
+		return err
+	}
+
+	// Record change
+	err = recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeDocumentPut,
+		CollectionName: document.CollectionName,
+		DocumentID:     document.ID,
+		Document:       document,
+		Timestamp:      document.UpdatedAt,
+	}, actor)
+	if err != nil {
+		return err
+	}
+
+	// Evict the oldest documents of a capped collection
+	if _, err = evictOverflow(ctx, tx, document.CollectionName, options, document.ID, document.UpdatedAt); err != nil {
+		return err
+	}
+
+	// Reject the document if the collection is full
+	if err = checkQuota(ctx, tx, document.CollectionName, options.Quota, document.UpdatedAt); err != nil {
+		return err
 	}
 
 	// Update collection timestamp
-	_, err = r.collectionRepo.Update(collectionName)
+	_, err = tx.ExecContext(ctx, `UPDATE collections SET updated_at = ? WHERE name = ?`, document.UpdatedAt, document.CollectionName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update collection timestamp: %w", err)
+		return fmt.Errorf("failed to update collection timestamp: %w", err)
 	}
 
-	return document, nil
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
 }
 
 // GetByID retrieves a document by ID
-func (r *DocumentRepository) GetByID(id, collectionName string) (*models.Document, error) {
-	query := `SELECT id, collection_name, data, created_at, updated_at 
+func (r *DocumentRepository) GetByID(ctx context.Context, id, collectionName string) (*models.Document, error) {
+	return getDocument(ctx, r.db.reader, id, collectionName, time.Now())
+}
+
+// getDocument retrieves a document that is neither trashed nor expired at now
+func getDocument(ctx context.Context, q queryRower, id, collectionName string, now time.Time) (*models.Document, error) {
+	query := `SELECT id, collection_name, data, created_at, updated_at, expires_at, owner, shared_with 
 			  FROM documents 
-			  WHERE id = ? AND collection_name = ?`
-	row := r.db.QueryRow(query, id, collectionName)
+			  WHERE id = ? AND collection_name = ? AND deleted_at IS NULL` + notExpired
+	row := q.QueryRowContext(ctx, query, id, collectionName, now.UTC())
 
 	var document models.Document
 	var dataBytes []byte
+	var expiresAt sql.NullTime
+	var sharedWith string
 	err := row.Scan(
 		&document.ID,
 		&document.CollectionName,
 		&dataBytes,
 		&document.CreatedAt,
 		&document.UpdatedAt,
+		&expiresAt,
+		&document.Owner,
+		&sharedWith,
 	)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("document with ID '%s' not found in collection '%s'", id, collectionName)
@@ -147,13 +239,17 @@ func (r *DocumentRepository) GetByID(id, collectionName string) (*models.Documen
 	}
 
 	document.Data = json.RawMessage(dataBytes)
+	document.ExpiresAt = scannedExpiry(expiresAt)
+	if document.SharedWith, err = decodeSharedWith(sharedWith); err != nil {
+		return nil, err
+	}
 	return &document, nil
 }
 
 // Exists checks if a document exists
-func (r *DocumentRepository) Exists(id, collectionName string) (bool, error) {
-	query := `SELECT 1 FROM documents WHERE id = ? AND collection_name = ?`
-	row := r.db.QueryRow(query, id, collectionName)
+func (r *DocumentRepository) Exists(ctx context.Context, id, collectionName string) (bool, error) {
+	query := `SELECT 1 FROM documents WHERE id = ? AND collection_name = ? AND deleted_at IS NULL` + notExpired
+	row := r.db.reader.QueryRowContext(ctx, query, id, collectionName, time.Now().UTC())
 
 	var exists int
 	err := row.Scan(&exists)
@@ -167,46 +263,114 @@ func (r *DocumentRepository) Exists(id, collectionName string) (bool, error) {
 	return true, nil
 }
 
-// Update updates a document
-func (r *DocumentRepository) Update(id, collectionName string, data json.RawMessage) (*models.Document, error) {
-	// Check if document exists
-	exists, err := r.Exists(id, collectionName)
+// Update updates a document the caller may see. A nil expiresAt renews the collection's
+// default TTL, or keeps the current expiry when the collection has none.
+func (r *DocumentRepository) Update(ctx context.Context, id, collectionName string, data json.RawMessage, expiresAt *time.Time, access *models.DocumentAccess, actor models.Actor) (*models.Document, error) {
+	// Validate JSON data
+	if err := models.ValidateJSON(data); err != nil {
+		return nil, err
+	}
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if document exists: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	if !exists {
-		return nil, fmt.Errorf("document with ID '%s' not found in collection '%s'", id, collectionName)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Get existing document; the transaction holds the write lock, so it cannot be deleted,
+	// trashed or unshared before it is updated
+	document, err := getDocument(ctx, tx, id, collectionName, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err = checkDocumentAccess(ctx, tx, document, access, false); err != nil {
+		return nil, err
 	}
 
-	// Validate JSON data
-	if err := models.ValidateJSON(data); err != nil {
+	options, err := getCollectionOptions(ctx, tx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.limits(options).Check(data); err != nil {
+		return nil, err
+	}
+	if err = checkCapSize(options.Cap, data); err != nil {
+		return nil, err
+	}
+
+	// Record revision
+	previousSize := len(document.Data)
+	document.Data = data
+	document.UpdatedAt = time.Now()
+	if expiresAt != nil {
+		document.ExpiresAt = expiresAt
+	} else if defaultExpiry := options.DefaultExpiry(document.UpdatedAt); defaultExpiry != nil {
+		document.ExpiresAt = defaultExpiry
+	}
+	err = recordRevision(ctx, tx, options.History, collectionName, id, models.RevisionUpdate, data, actor.Name, document.UpdatedAt)
+	if err != nil {
 		return nil, err
 	}
 
 	// Update document
-	now := time.Now()
 	query := `UPDATE documents 
-			  SET data = ?, updated_at = ? 
+			  SET data = ?, updated_at = ?, expires_at = ? 
 			  WHERE id = ? AND collection_name = ?`
-	_, err = r.db.Exec(query, data, now, id, collectionName)
+	_, err = tx.ExecContext(ctx, query, data, document.UpdatedAt, storedExpiry(document.ExpiresAt), id, collectionName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update document: %w", err)
 	}
 
+	// Only a growing document can push the collection over its quota
+	if len(data) > previousSize {
+		if err = checkQuota(ctx, tx, collectionName, options.Quota, document.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	// Record change
+	err = recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeDocumentPut,
+		CollectionName: collectionName,
+		DocumentID:     id,
+		Document:       document,
+		Timestamp:      document.UpdatedAt,
+	}, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	// A growing document can push a capped collection over its byte cap
+	if len(data) > previousSize {
+		if _, err = evictOverflow(ctx, tx, collectionName, options, id, document.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+
 	// Update collection timestamp
-	_, err = r.collectionRepo.Update(collectionName)
+	_, err = tx.ExecContext(ctx, `UPDATE collections SET updated_at = ? WHERE name = ?`, document.UpdatedAt, collectionName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update collection timestamp: %w", err)
 	}
 
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Get updated document
-	return r.GetByID(id, collectionName)
+	return r.GetByID(ctx, id, collectionName)
 }
 
-// Delete deletes a document
-func (r *DocumentRepository) Delete(id, collectionName string) error {
+// Delete deletes a document, moving it to the trash when soft delete is enabled
+func (r *DocumentRepository) Delete(ctx context.Context, id, collectionName string, actor models.Actor) error {
 	// Check if document exists
-	exists, err := r.Exists(id, collectionName)
+	exists, err := r.Exists(ctx, id, collectionName)
 	if err != nil {
 		return fmt.Errorf("failed to check if document exists: %w", err)
 	}
@@ -214,26 +378,72 @@ func (r *DocumentRepository) Delete(id, collectionName string) error {
 		return fmt.Errorf("document with ID '%s' not found in collection '%s'", id, collectionName)
 	}
 
-	// Delete document
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	options, err := getCollectionOptions(ctx, tx, collectionName)
+	if err != nil {
+		return err
+	}
+
+	// Record revision
+	now := time.Now()
+	err = recordRevision(ctx, tx, options.History, collectionName, id, models.RevisionDelete, nil, actor.Name, now)
+	if err != nil {
+		return err
+	}
+
+	// Move to trash or delete permanently
+	operation := models.ChangeDocumentDelete
 	query := `DELETE FROM documents WHERE id = ? AND collection_name = ?`
-	_, err = r.db.Exec(query, id, collectionName)
+	args := []interface{}{id, collectionName}
+	if r.db.config.SoftDelete {
+		operation = models.ChangeDocumentTrash
+		query = `UPDATE documents SET deleted_at = ? WHERE id = ? AND collection_name = ?`
+		args = []interface{}{now.UTC(), id, collectionName}
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to delete document: %w", err)
 	}
 
+	// Record change
+	err = recordChange(ctx, tx, &models.Change{
+		Operation:      operation,
+		CollectionName: collectionName,
+		DocumentID:     id,
+		Timestamp:      now,
+	}, actor)
+	if err != nil {
+		return err
+	}
+
 	// Update collection timestamp
-	_, err = r.collectionRepo.Update(collectionName)
+	_, err = tx.ExecContext(ctx, `UPDATE collections SET updated_at = ? WHERE name = ?`, now, collectionName)
 	if err != nil {
 		return fmt.Errorf("failed to update collection timestamp: %w", err)
 	}
 
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
 // List retrieves documents from a collection with pagination
-func (r *DocumentRepository) List(collectionName string, queryParams *models.DocumentQuery) (*models.DocumentList, error) {
+func (r *DocumentRepository) List(ctx context.Context, collectionName string, queryParams *models.DocumentQuery) (*models.DocumentList, error) {
 	// Check if collection exists
-	exists, err := r.collectionRepo.Exists(collectionName)
+	exists, err := r.collectionRepo.Exists(ctx, collectionName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if collection exists: %w", err)
 	}
@@ -241,21 +451,35 @@ func (r *DocumentRepository) List(collectionName string, queryParams *models.Doc
 		return nil, fmt.Errorf("collection '%s' not found", collectionName)
 	}
 
+	// Read from history for point-in-time queries
+	if queryParams.AsOf != nil {
+		return r.listAsOf(ctx, collectionName, queryParams)
+	}
+
+	// Limit the result to the caller's documents in a collection with ownership enabled
+	access, err := r.RestrictAccess(ctx, collectionName, queryParams.Access)
+	if err != nil {
+		return nil, err
+	}
+	ownership, ownershipArgs := accessFilter("documents", access)
+
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM documents WHERE collection_name = ?`
+	now := time.Now().UTC()
+	countQuery := `SELECT COUNT(*) FROM documents WHERE collection_name = ? AND deleted_at IS NULL` + notExpired + ownership
 	var total int
-	err = r.db.QueryRow(countQuery, collectionName).Scan(&total)
+	err = r.db.reader.QueryRowContext(ctx, countQuery, append([]interface{}{collectionName, now}, ownershipArgs...)...).Scan(&total)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count documents: %w", err)
 	}
 
 	// Get documents with pagination
-	query := `SELECT id, collection_name, data, created_at, updated_at 
+	query := `SELECT id, collection_name, data, created_at, updated_at, expires_at, owner, shared_with 
 			  FROM documents 
-			  WHERE collection_name = ? 
+			  WHERE collection_name = ? AND deleted_at IS NULL` + notExpired + ownership + `
 			  ORDER BY created_at DESC 
 			  LIMIT ? OFFSET ?`
-	rows, err := r.db.Query(query, collectionName, queryParams.Limit, queryParams.Offset)
+	args := append([]interface{}{collectionName, now}, ownershipArgs...)
+	rows, err := r.db.reader.QueryContext(ctx, query, append(args, queryParams.Limit, queryParams.Offset)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list documents: %w", err)
 	}
@@ -265,17 +489,26 @@ func (r *DocumentRepository) List(collectionName string, queryParams *models.Doc
 	for rows.Next() {
 		var document models.Document
 		var dataBytes []byte
+		var expiresAt sql.NullTime
+		var sharedWith string
 		err := rows.Scan(
 			&document.ID,
 			&document.CollectionName,
 			&dataBytes,
 			&document.CreatedAt,
 			&document.UpdatedAt,
+			&expiresAt,
+			&document.Owner,
+			&sharedWith,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan document: %w", err)
 		}
 		document.Data = json.RawMessage(dataBytes)
+		document.ExpiresAt = scannedExpiry(expiresAt)
+		if document.SharedWith, err = decodeSharedWith(sharedWith); err != nil {
+			return nil, err
+		}
 		documents = append(documents, document)
 	}
 
@@ -291,10 +524,10 @@ func (r *DocumentRepository) List(collectionName string, queryParams *models.Doc
 	}, nil
 }
 
-// BulkCreate creates multiple documents in a collection
-func (r *DocumentRepository) BulkCreate(collectionName string, dataItems []json.RawMessage) ([]models.Document, error) {
+// BulkCreate creates multiple documents owned by owner in a collection
+func (r *DocumentRepository) BulkCreate(ctx context.Context, collectionName string, dataItems []json.RawMessage, owner string, actor models.Actor) ([]models.Document, error) {
 	// Check if collection exists
-	exists, err := r.collectionRepo.Exists(collectionName)
+	exists, err := r.collectionRepo.Exists(ctx, collectionName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if collection exists: %w", err)
 	}
@@ -303,7 +536,7 @@ func (r *DocumentRepository) BulkCreate(collectionName string, dataItems []json.
 	}
 
 	// Begin transaction
-	tx, err := r.db.Begin()
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -313,9 +546,16 @@ func (r *DocumentRepository) BulkCreate(collectionName string, dataItems []json.
 		}
 	}()
 
+	options, err := getCollectionOptions(ctx, tx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := r.limits(options)
+
 	// Prepare statement for inserting documents
-	stmt, err := tx.Prepare(`INSERT INTO documents (id, collection_name, data, created_at, updated_at) 
-							 VALUES (?, ?, ?, ?, ?)`)
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO documents (id, collection_name, data, created_at, updated_at, expires_at, owner) 
+							 VALUES (?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to prepare statement: %w", err)
 	}
@@ -325,30 +565,68 @@ func (r *DocumentRepository) BulkCreate(collectionName string, dataItems []json.
 	documents := make([]models.Document, 0, len(dataItems))
 	for _, data := range dataItems {
 		// Validate JSON
-		if err := models.ValidateJSON(data); err != nil {
+		if err = limits.Check(data); err != nil {
+			return nil, err
+		}
+		if err = checkCapSize(options.Cap, data); err != nil {
 			return nil, err
 		}
 
 		// Create document
 		document := models.NewDocument(collectionName, data)
+		document.ExpiresAt = options.DefaultExpiry(document.CreatedAt)
+		document.Owner = owner
 		documents = append(documents, *document)
 
+		// Record revision
+		err = recordRevision(ctx, tx, options.History, collectionName, document.ID, models.RevisionCreate, document.Data, actor.Name, document.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+
 		// Insert document
-		_, err = stmt.Exec(
+		_, err = stmt.ExecContext(ctx,
 			document.ID,
 			document.CollectionName,
 			document.Data,
 			document.CreatedAt,
 			document.UpdatedAt,
+			storedExpiry(document.ExpiresAt),
+			document.Owner,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to insert document: %w", err)
 		}
+		if err = stampRevision(ctx, tx, options.History, collectionName, document.ID); err != nil {
+			return nil, err
+		}
+
+		// Record change
+		err = recordChange(ctx, tx, &models.Change{
+			Operation:      models.ChangeDocumentPut,
+			CollectionName: collectionName,
+			DocumentID:     document.ID,
+			Document:       document,
+			Timestamp:      document.UpdatedAt,
+		}, actor)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Update collection timestamp
+	// Evict the oldest documents of a capped collection
 	now := time.Now()
-	_, err = tx.Exec(`UPDATE collections SET updated_at = ? WHERE name = ?`, now, collectionName)
+	if _, err = evictOverflow(ctx, tx, collectionName, options, "", now); err != nil {
+		return nil, err
+	}
+
+	// Reject the whole batch if it does not fit in the collection's quota
+	if err = checkQuota(ctx, tx, collectionName, options.Quota, now); err != nil {
+		return nil, err
+	}
+
+	// Update collection timestamp
+	_, err = tx.ExecContext(ctx, `UPDATE collections SET updated_at = ? WHERE name = ?`, now, collectionName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update collection timestamp: %w", err)
 	}
@@ -360,3 +638,50 @@ func (r *DocumentRepository) BulkCreate(collectionName string, dataItems []json.
 
 	return documents, nil
 }
+
+// limits returns the document limits of a collection with the given options
+func (r *DocumentRepository) limits(options models.CollectionOptions) models.DocumentLimits {
+	return r.db.config.Limits.Tighten(options.Limits)
+}
+
+// Limits returns the document limits of a collection; a collection that does not exist
+// yet gets the server's limits
+func (r *DocumentRepository) Limits(ctx context.Context, collectionName string) (models.DocumentLimits, error) {
+	exists, err := r.collectionRepo.Exists(ctx, collectionName)
+	if err != nil {
+		return models.DocumentLimits{}, fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+	if !exists {
+		return r.db.config.Limits, nil
+	}
+
+	options, err := getCollectionOptions(ctx, r.db.reader, collectionName)
+	if err != nil {
+		return models.DocumentLimits{}, err
+	}
+	return r.limits(options), nil
+}
+
+// removeDocument permanently deletes a live document on behalf of the server,
+// keeping its revision history and the change log up to date
+func removeDocument(ctx context.Context, tx *sql.Tx, history *models.HistoryOptions, collectionName, id string, actor models.Actor, now time.Time) error {
+	// Record revision
+	err := recordRevision(ctx, tx, history, collectionName, id, models.RevisionDelete, nil, actor.Name, now)
+	if err != nil {
+		return err
+	}
+
+	// Delete document
+	_, err = tx.ExecContext(ctx, `DELETE FROM documents WHERE id = ? AND collection_name = ?`, id, collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	// Record change
+	return recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeDocumentDelete,
+		CollectionName: collectionName,
+		DocumentID:     id,
+		Timestamp:      now,
+	}, actor)
+}
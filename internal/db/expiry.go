@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// notExpired filters out documents whose expiry has passed; it takes the current UTC time as argument
+const notExpired = ` AND (expires_at IS NULL OR expires_at > ?)`
+
+// storedExpiry converts an expiry to the UTC form it is stored and compared in
+func storedExpiry(expiresAt *time.Time) interface{} {
+	if expiresAt == nil {
+		return nil
+	}
+	return expiresAt.UTC()
+}
+
+// scannedExpiry converts a scanned expiry column back to an optional time
+func scannedExpiry(expiresAt sql.NullTime) *time.Time {
+	if !expiresAt.Valid {
+		return nil
+	}
+	return &expiresAt.Time
+}
+
+// DeleteExpired permanently deletes up to limit documents whose expiry is at or before now
+// and returns the number of documents deleted
+func (r *DocumentRepository) DeleteExpired(ctx context.Context, now time.Time, limit int) (deleted int, err error) {
+	// Begin transaction; it takes the write lock at once, so the documents found below cannot
+	// be renewed, deleted or trashed before they are removed
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Find expired documents
+	query := `SELECT collection_name, id FROM documents
+			  WHERE expires_at IS NOT NULL AND expires_at <= ? AND deleted_at IS NULL
+			  ORDER BY expires_at
+			  LIMIT ?`
+	rows, err := tx.QueryContext(ctx, query, now.UTC(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired documents: %w", err)
+	}
+	var expired [][2]string
+	for rows.Next() {
+		var key [2]string
+		if err = rows.Scan(&key[0], &key[1]); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan expired document: %w", err)
+		}
+		expired = append(expired, key)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating over expired documents: %w", err)
+	}
+
+	options := make(map[string]models.CollectionOptions)
+	for _, key := range expired {
+		collectionName, id := key[0], key[1]
+
+		// Load collection options once per collection
+		collectionOptions, ok := options[collectionName]
+		if !ok {
+			collectionOptions, err = getCollectionOptions(ctx, tx, collectionName)
+			if err != nil {
+				return 0, err
+			}
+			options[collectionName] = collectionOptions
+		}
+
+		if err = removeDocument(ctx, tx, collectionOptions.History, collectionName, id, systemActor, now); err != nil {
+			return 0, err
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(expired), nil
+}
@@ -0,0 +1,98 @@
+package db_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestDocumentExpiry(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{TTL: models.Duration(time.Hour)}
+	if _, err := collections.CreateWithOptions(t.Context(), "sessions", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The collection TTL applies when no expiry is given
+	session, err := documents.Create(t.Context(), "sessions", json.RawMessage(`{"user":"alice"}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.ExpiresAt == nil || session.ExpiresAt.Sub(session.CreatedAt) != time.Hour {
+		t.Errorf("collection TTL not applied: got %v", session.ExpiresAt)
+	}
+
+	// An explicit expiry overrides the collection TTL
+	soon := time.Now().Add(20 * time.Millisecond)
+	short, err := documents.Create(t.Context(), "sessions", json.RawMessage(`{"user":"bob"}`), &soon, "", models.Actor{Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.GetByID(t.Context(), short.ID, "sessions"); err != nil {
+		t.Fatalf("document not readable before expiry: %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	// Expired documents are hidden before they are swept
+	if _, err := documents.GetByID(t.Context(), short.ID, "sessions"); err == nil {
+		t.Errorf("expired document is still readable")
+	}
+	list, err := documents.List(t.Context(), "sessions", models.NewDocumentQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Documents[0].ID != session.ID {
+		t.Errorf("wrong documents listed: %+v", list)
+	}
+
+	// The sweeper deletes expired documents in batches
+	deleted, err := documents.DeleteExpired(t.Context(), time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("wrong number of documents swept: got %v want %v", deleted, 1)
+	}
+	deleted, err = documents.DeleteExpired(t.Context(), time.Now().Add(2*time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("wrong number of documents swept: got %v want %v", deleted, 1)
+	}
+}
+
+func TestUpdateRenewsExpiry(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	// Without a collection TTL an update keeps the current expiry
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	doc, err := documents.Create(t.Context(), "cache", json.RawMessage(`{"v":1}`), &expiresAt, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := documents.Update(t.Context(), doc.ID, "cache", json.RawMessage(`{"v":2}`), nil, nil, models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ExpiresAt == nil || !updated.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("expiry not kept: got %v want %v", updated.ExpiresAt, expiresAt)
+	}
+
+	// With a collection TTL an update renews it
+	options := models.CollectionOptions{TTL: models.Duration(24 * time.Hour)}
+	if _, err := collections.UpdateOptions(t.Context(), "cache", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err = documents.Update(t.Context(), doc.ID, "cache", json.RawMessage(`{"v":3}`), nil, nil, models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ExpiresAt == nil || updated.ExpiresAt.Sub(updated.UpdatedAt) < 23*time.Hour {
+		t.Errorf("expiry not renewed: got %v", updated.ExpiresAt)
+	}
+}
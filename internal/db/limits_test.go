@@ -0,0 +1,53 @@
+package db_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestCollectionLimits(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{Limits: &models.DocumentLimits{MaxDepth: 2, MaxDocumentBytes: 32}}
+	if _, err := collections.CreateWithOptions(t.Context(), "events", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := documents.Create(t.Context(), "events", json.RawMessage(`{"a":{"b":1}}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deep := json.RawMessage(`{"a":{"b":[1]}}`)
+	if _, err := documents.Create(t.Context(), "events", deep, nil, "", models.Actor{Name: "alice"}); !errors.Is(err, models.ErrDocumentTooDeep) {
+		t.Errorf("wrong error for create: got %v want %v", err, models.ErrDocumentTooDeep)
+	}
+	if _, err := documents.Update(t.Context(), doc.ID, "events", deep, nil, nil, models.Actor{Name: "alice"}); !errors.Is(err, models.ErrDocumentTooDeep) {
+		t.Errorf("wrong error for update: got %v want %v", err, models.ErrDocumentTooDeep)
+	}
+
+	// A bulk insert with one document over the limits is rejected as a whole
+	items := []json.RawMessage{json.RawMessage(`{}`), json.RawMessage(`{"text":"longer than thirty-two bytes"}`)}
+	if _, err := documents.BulkCreate(t.Context(), "events", items, "", models.Actor{Name: "alice"}); !errors.Is(err, models.ErrDocumentTooLarge) {
+		t.Errorf("wrong error for bulk insert: got %v want %v", err, models.ErrDocumentTooLarge)
+	}
+	list, err := documents.List(t.Context(), "events", models.NewDocumentQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 {
+		t.Errorf("wrong number of documents: got %v want %v", list.Total, 1)
+	}
+
+	// Other collections are not affected
+	if _, err := documents.Create(t.Context(), "other", deep, nil, "", models.Actor{Name: "alice"}); err != nil {
+		t.Errorf("unexpected error in a collection without limits: %v", err)
+	}
+	limits, err := documents.Limits(t.Context(), "missing")
+	if err != nil || limits != (models.DocumentLimits{}) {
+		t.Errorf("wrong limits of a missing collection: got %+v %v want none", limits, err)
+	}
+}
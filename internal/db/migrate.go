@@ -0,0 +1,383 @@
+package db
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the schema migrations, named NNNN_name.up.sql and NNNN_name.down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned for a database migrated by a newer version of the server
+var ErrSchemaTooNew = errors.New("database schema is newer than this version supports")
+
+// Migration is a versioned change to the schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState describes a migration and whether it is applied to the database
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+
+	// Unknown migrations are applied to the database but not part of this version
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// migrations are the migrations of this version, ordered by version
+var migrations = mustLoadMigrations(migrationFiles)
+
+// mustLoadMigrations loads the embedded migrations, which are checked by the tests
+func mustLoadMigrations(fsys fs.FS) []Migration {
+	list, err := loadMigrations(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+// loadMigrations reads the migrations of a directory named migrations. Versions start at 1
+// and have no gaps, and every migration has an up and a down script.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		file := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		prefix, name, found := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || !found || err != nil || version < 1 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s: use NNNN_name.up.sql or NNNN_name.down.sql", file)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join("migrations", file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	for i, m := range list {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("migration %d needs an up and a down script", m.Version)
+		}
+	}
+	return list, nil
+}
+
+// LatestSchemaVersion returns the schema version this version of the server migrates to
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
+
+// ensureMigrationsTable creates the table recording the applied migrations
+func (db *DB) ensureMigrationsTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	);`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create schema migrations table: %w", err)
+	}
+	return nil
+}
+
+// SchemaVersion returns the version of the latest migration applied to the database, or 0
+// if none is
+func (db *DB) SchemaVersion() (int, error) {
+	if err := db.ensureMigrationsTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
+
+// checkSchemaVersion returns the schema version, failing with ErrSchemaTooNew if the database
+// has migrations this version does not know
+func (db *DB) checkSchemaVersion() (int, error) {
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	if version > LatestSchemaVersion() {
+		return version, fmt.Errorf("%w: database is at version %d, this version supports up to %d; upgrade the server",
+			ErrSchemaTooNew, version, LatestSchemaVersion())
+	}
+	return version, nil
+}
+
+// Migrate applies the pending migrations in order, each in its own transaction, and returns
+// how many it applied
+func (db *DB) Migrate() (int, error) {
+	version, err := db.checkSchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, m := range migrations[version:] {
+		if err := db.applyMigration(m, version == 0 && applied == 0); err != nil {
+			return applied, err
+		}
+		applied++
+		slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+	}
+	return applied, nil
+}
+
+// applyMigration runs the up script of a migration and records it. The first migration of a
+// database created before migrations were tracked brings its tables up to date first.
+func (db *DB) applyMigration(m Migration, first bool) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.Version, err)
+	}
+	defer tx.Rollback()
+
+	if first {
+		if err := adoptLegacySchema(tx); err != nil {
+			return fmt.Errorf("failed to upgrade existing schema: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(m.Up); err != nil {
+		return fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		m.Version, m.Name, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.Version, err)
+	}
+	return nil
+}
+
+// MigrateDown reverts the latest steps migrations, each in its own transaction, and returns
+// how many it reverted
+func (db *DB) MigrateDown(steps int) (int, error) {
+	version, err := db.checkSchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+	for ; reverted < steps && version > 0; version-- {
+		m := migrations[version-1]
+		if err := db.revertMigration(m); err != nil {
+			return reverted, err
+		}
+		reverted++
+		slog.Info("Reverted migration", "version", m.Version, "name", m.Name)
+	}
+	return reverted, nil
+}
+
+// revertMigration runs the down script of a migration and removes its record
+func (db *DB) revertMigration(m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin reverting migration %d: %w", m.Version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.Down); err != nil {
+		return fmt.Errorf("failed to revert migration %d (%s): %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version); err != nil {
+		return fmt.Errorf("failed to record reverting migration %d: %w", m.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit reverting migration %d: %w", m.Version, err)
+	}
+	return nil
+}
+
+// MigrationStatus lists the migrations of this version and those applied to the database,
+// ordered by version
+func (db *DB) MigrationStatus() ([]MigrationState, error) {
+	if err := db.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, name, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]MigrationState)
+	for rows.Next() {
+		var state MigrationState
+		var appliedAt time.Time
+		if err := rows.Scan(&state.Version, &state.Name, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		state.AppliedAt = &appliedAt
+		applied[state.Version] = state
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+
+	status := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if a, ok := applied[m.Version]; ok {
+			state.AppliedAt = a.AppliedAt
+			delete(applied, m.Version)
+		}
+		status = append(status, state)
+	}
+
+	// Migrations of a newer version
+	for _, state := range applied {
+		state.Unknown = true
+		status = append(status, state)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, nil
+}
+
+// adoptLegacySchema adds the columns introduced before migrations were tracked to the tables
+// of an existing database, so the first migration finds the schema it creates. New
+// databases have no tables yet and are left alone.
+func adoptLegacySchema(tx *sql.Tx) error {
+	var tables int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'collections'").Scan(&tables); err != nil {
+		return fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	if tables == 0 {
+		return nil
+	}
+
+	if _, err := addColumnIfMissing(tx, "collections", "options", "TEXT NOT NULL DEFAULT '{}'"); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "changes", "author", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	added, err := addColumnIfMissing(tx, "document_revisions", "superseded_at", "TIMESTAMP")
+	if err != nil {
+		return err
+	}
+	if added {
+		// Close the validity interval of revisions recorded before the column existed
+		backfill := `
+		UPDATE document_revisions SET superseded_at = (
+			SELECT n.created_at FROM document_revisions n
+			WHERE n.collection_name = document_revisions.collection_name
+			AND n.document_id = document_revisions.document_id
+			AND n.revision > document_revisions.revision
+			ORDER BY n.revision LIMIT 1
+		)`
+		if _, err := tx.Exec(backfill); err != nil {
+			return fmt.Errorf("failed to backfill revision validity: %w", err)
+		}
+	}
+
+	if _, err := addColumnIfMissing(tx, "collections", "deleted_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "documents", "deleted_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "documents", "expires_at", "TIMESTAMP"); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "documents", "owner", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(tx, "documents", "shared_with", "TEXT NOT NULL DEFAULT '[]'"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column to an existing table created by an older version
+// and reports whether the column was added. Missing tables are left to the migrations.
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	defer rows.Close()
+
+	exists := false
+	for rows.Next() {
+		exists = true
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return false, fmt.Errorf("failed to inspect %s table: %w", table, err)
+		}
+		if name == column {
+			return false, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	rows.Close()
+	if !exists {
+		return false, nil
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := tx.Exec(query); err != nil {
+		return false, fmt.Errorf("failed to add %s column to %s: %w", column, table, err)
+	}
+
+	return true, nil
+}
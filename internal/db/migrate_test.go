@@ -0,0 +1,199 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+)
+
+// tableExists reports whether the database has a table
+func tableExists(t *testing.T, database *db.DB, table string) bool {
+	t.Helper()
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count > 0
+}
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	database, err := db.New(db.NewConfig(filepath.Join(t.TempDir(), "db.sqlite")))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	version, err := database.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != db.LatestSchemaVersion() {
+		t.Errorf("wrong schema version: got %v want %v", version, db.LatestSchemaVersion())
+	}
+
+	status, err := database.MigrationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != db.LatestSchemaVersion() {
+		t.Fatalf("wrong number of migrations: got %v want %v", len(status), db.LatestSchemaVersion())
+	}
+	for i, state := range status {
+		if state.Version != i+1 || state.Name == "" || state.AppliedAt == nil || state.Unknown {
+			t.Errorf("migration %d: got %+v, want it applied", i+1, state)
+		}
+	}
+
+	// Migrating again has nothing to do
+	applied, err := database.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 0 {
+		t.Errorf("wrong number of migrations applied again: got %v want 0", applied)
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	database, err := db.New(db.NewConfig(filepath.Join(t.TempDir(), "db.sqlite")))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+	latest := db.LatestSchemaVersion()
+
+	// Revert everything but the initial schema
+	reverted, err := database.MigrateDown(latest - 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted != latest-1 {
+		t.Errorf("wrong number of migrations reverted: got %v want %v", reverted, latest-1)
+	}
+	if version, _ := database.SchemaVersion(); version != 1 {
+		t.Errorf("wrong schema version after down: got %v want 1", version)
+	}
+	if tableExists(t, database, "health_probe") {
+		t.Error("health_probe table exists after reverting its migration")
+	}
+
+	// Reverting more migrations than applied stops at the empty schema
+	reverted, err = database.MigrateDown(latest + 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted != 1 {
+		t.Errorf("wrong number of migrations reverted: got %v want 1", reverted)
+	}
+	if tableExists(t, database, "collections") {
+		t.Error("collections table exists after reverting all migrations")
+	}
+
+	applied, err := database.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != latest {
+		t.Errorf("wrong number of migrations applied: got %v want %v", applied, latest)
+	}
+	if !tableExists(t, database, "collections") || !tableExists(t, database, "health_probe") {
+		t.Error("tables missing after migrating up again")
+	}
+}
+
+func TestMigrateRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+	database, err := db.New(db.NewConfig(path))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	// A newer version of the server applied a migration this one does not know
+	newer := db.LatestSchemaVersion() + 1
+	if _, err := database.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'future', CURRENT_TIMESTAMP)", newer); err != nil {
+		t.Fatal(err)
+	}
+	database.Close()
+
+	if _, err := db.New(db.NewConfig(path)); !errors.Is(err, db.ErrSchemaTooNew) {
+		t.Fatalf("wrong error for newer schema: got %v want %v", err, db.ErrSchemaTooNew)
+	}
+
+	// The status still shows the unknown migration
+	database, err = db.Open(db.NewConfig(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	if _, err := database.MigrateDown(1); !errors.Is(err, db.ErrSchemaTooNew) {
+		t.Errorf("wrong error reverting newer schema: got %v want %v", err, db.ErrSchemaTooNew)
+	}
+	status, err := database.MigrationStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := status[len(status)-1]
+	if last.Version != newer || !last.Unknown || last.AppliedAt == nil {
+		t.Errorf("wrong status of unknown migration: got %+v", last)
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.sqlite")
+
+	// Tables as created by versions before migrations and the columns added since
+	legacy, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		`CREATE TABLE collections (name TEXT PRIMARY KEY, created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE documents (id TEXT, collection_name TEXT, data TEXT NOT NULL, created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (id, collection_name))`,
+		`CREATE TABLE document_revisions (collection_name TEXT NOT NULL, document_id TEXT NOT NULL, revision INTEGER NOT NULL, operation TEXT NOT NULL, data TEXT, author TEXT NOT NULL DEFAULT '', created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (collection_name, document_id, revision))`,
+		`CREATE TABLE changes (seq INTEGER PRIMARY KEY AUTOINCREMENT, operation TEXT NOT NULL, collection_name TEXT NOT NULL, document_id TEXT, payload TEXT, timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO collections (name) VALUES ('notes')`,
+		`INSERT INTO documents (id, collection_name, data) VALUES ('a', 'notes', '{"v":2}')`,
+		`INSERT INTO document_revisions (collection_name, document_id, revision, operation, data, created_at) VALUES
+			('notes', 'a', 1, 'create', '{"v":1}', '2024-01-01 00:00:00'),
+			('notes', 'a', 2, 'update', '{"v":2}', '2024-02-01 00:00:00')`,
+	}
+	for _, statement := range statements {
+		if _, err := legacy.Exec(statement); err != nil {
+			t.Fatalf("failed to create legacy schema: %v", err)
+		}
+	}
+	legacy.Close()
+
+	database, err := db.New(db.NewConfig(path))
+	if err != nil {
+		t.Fatalf("failed to migrate legacy database: %v", err)
+	}
+	defer database.Close()
+
+	// The documents survive with the new columns
+	collections := db.NewCollectionRepository(database)
+	doc, err := db.NewDocumentRepository(database, collections).GetByID(t.Context(), "a", "notes")
+	if err != nil {
+		t.Fatalf("failed to read legacy document: %v", err)
+	}
+	if string(doc.Data) != `{"v":2}` {
+		t.Errorf("wrong document data: got %s want %s", doc.Data, `{"v":2}`)
+	}
+
+	// The validity of the older revision was closed by the backfill
+	var superseded sql.NullString
+	if err := database.QueryRow("SELECT superseded_at FROM document_revisions WHERE revision = 1").Scan(&superseded); err != nil {
+		t.Fatal(err)
+	}
+	if !superseded.Valid {
+		t.Error("older revision was not superseded by the backfill")
+	}
+	if version, _ := database.SchemaVersion(); version != db.LatestSchemaVersion() {
+		t.Errorf("wrong schema version: got %v want %v", version, db.LatestSchemaVersion())
+	}
+}
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS role_assignments;
+DROP TABLE IF EXISTS roles;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS replication_state;
+DROP TABLE IF EXISTS changes;
+DROP TABLE IF EXISTS document_revisions;
+DROP TABLE IF EXISTS documents;
+DROP TABLE IF EXISTS collections;
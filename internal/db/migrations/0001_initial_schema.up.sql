@@ -0,0 +1,124 @@
+-- Collections and their documents
+CREATE TABLE IF NOT EXISTS collections (
+	name TEXT PRIMARY KEY,
+	options TEXT NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS documents (
+	id TEXT,
+	collection_name TEXT,
+	data TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMP,
+	expires_at TIMESTAMP,
+	owner TEXT NOT NULL DEFAULT '',
+	shared_with TEXT NOT NULL DEFAULT '[]',
+	PRIMARY KEY (id, collection_name),
+	FOREIGN KEY (collection_name) REFERENCES collections(name) ON DELETE CASCADE
+);
+
+-- Document revision history
+CREATE TABLE IF NOT EXISTS document_revisions (
+	collection_name TEXT NOT NULL,
+	document_id TEXT NOT NULL,
+	revision INTEGER NOT NULL,
+	operation TEXT NOT NULL,
+	data TEXT,
+	author TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	superseded_at TIMESTAMP,
+	PRIMARY KEY (collection_name, document_id, revision),
+	FOREIGN KEY (collection_name) REFERENCES collections(name) ON DELETE CASCADE
+);
+
+-- Change log used by replication, and the checkpoints of followers
+CREATE TABLE IF NOT EXISTS changes (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	operation TEXT NOT NULL,
+	collection_name TEXT NOT NULL,
+	document_id TEXT,
+	payload TEXT,
+	author TEXT NOT NULL DEFAULT '',
+	timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS replication_state (
+	source TEXT PRIMARY KEY,
+	last_seq INTEGER NOT NULL DEFAULT 0,
+	last_timestamp TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- API keys, user accounts, and roles with their assignments to users and API keys
+CREATE TABLE IF NOT EXISTS api_keys (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	scopes TEXT NOT NULL DEFAULT '[]',
+	collections TEXT NOT NULL DEFAULT '[]',
+	expires_at TIMESTAMP,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	username TEXT PRIMARY KEY,
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	disabled_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS roles (
+	name TEXT PRIMARY KEY,
+	grants TEXT NOT NULL DEFAULT '[]',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_assignments (
+	role TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (role, subject)
+);
+
+-- Hash-chained audit log
+CREATE TABLE IF NOT EXISTS audit_log (
+	seq INTEGER PRIMARY KEY,
+	timestamp TIMESTAMP NOT NULL,
+	principal TEXT NOT NULL DEFAULT '',
+	source_ip TEXT NOT NULL DEFAULT '',
+	request_id TEXT NOT NULL DEFAULT '',
+	operation TEXT NOT NULL,
+	collection_name TEXT NOT NULL,
+	document_id TEXT NOT NULL DEFAULT '',
+	before_hash TEXT NOT NULL DEFAULT '',
+	after_hash TEXT NOT NULL DEFAULT '',
+	prev_hash TEXT NOT NULL DEFAULT '',
+	hash TEXT NOT NULL
+);
+
+-- Trashed rows, for listing and purging
+CREATE INDEX IF NOT EXISTS idx_collections_deleted_at ON collections (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_documents_deleted_at ON documents (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Expiring documents, for the sweeper
+CREATE INDEX IF NOT EXISTS idx_documents_expires_at ON documents (expires_at) WHERE expires_at IS NOT NULL;
+
+-- Documents by owner, for collections with ownership enabled
+CREATE INDEX IF NOT EXISTS idx_documents_owner ON documents (collection_name, owner);
+
+-- Assignments by subject, for resolving a caller's roles
+CREATE INDEX IF NOT EXISTS idx_role_assignments_subject ON role_assignments (subject);
+
+-- The audit log by record, for finding the previous state of a collection or document
+CREATE INDEX IF NOT EXISTS idx_audit_log_record ON audit_log (collection_name, document_id, seq);
+
+-- Revisions by validity, for point-in-time reads
+CREATE INDEX IF NOT EXISTS idx_document_revisions_validity ON document_revisions (collection_name, created_at);
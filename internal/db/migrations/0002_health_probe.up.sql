@@ -0,0 +1,5 @@
+-- The single row readiness checks write to
+CREATE TABLE IF NOT EXISTS health_probe (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	checked_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,3 @@
+ALTER TABLE document_revisions DROP COLUMN document_created_at;
+ALTER TABLE document_revisions DROP COLUMN shared_with;
+ALTER TABLE document_revisions DROP COLUMN owner;
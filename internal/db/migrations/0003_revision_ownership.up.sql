@@ -0,0 +1,22 @@
+-- The owner, sharing and creation time of the document at each revision, so past versions
+-- are authorized and dated without the live document, which may be gone
+ALTER TABLE document_revisions ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE document_revisions ADD COLUMN shared_with TEXT NOT NULL DEFAULT '[]';
+ALTER TABLE document_revisions ADD COLUMN document_created_at TIMESTAMP;
+
+UPDATE document_revisions SET
+	owner = COALESCE((
+		SELECT d.owner FROM documents d
+		WHERE d.id = document_revisions.document_id AND d.collection_name = document_revisions.collection_name
+	), ''),
+	shared_with = COALESCE((
+		SELECT d.shared_with FROM documents d
+		WHERE d.id = document_revisions.document_id AND d.collection_name = document_revisions.collection_name
+	), '[]'),
+	document_created_at = COALESCE((
+		SELECT d.created_at FROM documents d
+		WHERE d.id = document_revisions.document_id AND d.collection_name = document_revisions.collection_name
+	), (
+		SELECT MIN(r.created_at) FROM document_revisions r
+		WHERE r.collection_name = document_revisions.collection_name AND r.document_id = document_revisions.document_id
+	));
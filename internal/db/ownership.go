@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// encodeSharedWith serializes the subjects a document is shared with for storage
+func encodeSharedWith(sharedWith []string) (string, error) {
+	if sharedWith == nil {
+		sharedWith = []string{}
+	}
+	b, err := json.Marshal(sharedWith)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode shared_with: %w", err)
+	}
+	return string(b), nil
+}
+
+// decodeSharedWith parses the stored subjects a document is shared with
+func decodeSharedWith(sharedWith string) ([]string, error) {
+	var decoded []string
+	if sharedWith == "" {
+		return nil, nil
+	}
+	if err := json.Unmarshal([]byte(sharedWith), &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode shared_with: %w", err)
+	}
+	if len(decoded) == 0 {
+		return nil, nil
+	}
+	return decoded, nil
+}
+
+// accessFilter returns a condition, for documents under the given table alias, that limits
+// a query to the documents visible to access; unrestricted access adds no condition
+func accessFilter(alias string, access *models.DocumentAccess) (string, []interface{}) {
+	if access == nil || access.Elevated {
+		return "", nil
+	}
+	condition := fmt.Sprintf(
+		` AND ((%[1]s.owner != '' AND %[1]s.owner = ?) OR EXISTS (SELECT 1 FROM json_each(%[1]s.shared_with) WHERE value = ?))`,
+		alias,
+	)
+	return condition, []interface{}{access.Subject, access.Subject}
+}
+
+// restrictAccess returns the access to enforce in a live or trashed collection: nil when
+// the collection does not have ownership enabled or the caller is unrestricted
+func restrictAccess(ctx context.Context, q queryRower, collectionName string, access *models.DocumentAccess) (*models.DocumentAccess, error) {
+	if access == nil || access.Elevated {
+		return nil, nil
+	}
+
+	var options string
+	err := q.QueryRowContext(ctx, `SELECT options FROM collections WHERE name = ?`, collectionName).Scan(&options)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("collection '%s' not found", collectionName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection options: %w", err)
+	}
+	decoded, err := decodeCollectionOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if !decoded.OwnershipEnabled() {
+		return nil, nil
+	}
+
+	return access, nil
+}
+
+// documentOwnership loads the owner and sharing of a live or trashed document
+func documentOwnership(ctx context.Context, q queryRower, id, collectionName string) (*models.Document, error) {
+	document := &models.Document{ID: id, CollectionName: collectionName}
+	var sharedWith string
+	err := q.QueryRowContext(ctx,
+		`SELECT owner, shared_with FROM documents WHERE id = ? AND collection_name = ?`,
+		id, collectionName,
+	).Scan(&document.Owner, &sharedWith)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("document with ID '%s' not found in collection '%s'", id, collectionName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document owner: %w", err)
+	}
+	if document.SharedWith, err = decodeSharedWith(sharedWith); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// checkDocumentAccess reports a document as not found unless access may see it, or own it
+// when owner is set, in a collection with ownership enabled
+func checkDocumentAccess(ctx context.Context, q queryRower, document *models.Document, access *models.DocumentAccess, owner bool) error {
+	access, err := restrictAccess(ctx, q, document.CollectionName, access)
+	if err != nil || access == nil {
+		return err
+	}
+	if !access.CanSee(document) || (owner && !access.Owns(document)) {
+		return fmt.Errorf("document with ID '%s' not found in collection '%s'", document.ID, document.CollectionName)
+	}
+	return nil
+}
+
+// RestrictAccess returns the access to enforce in a collection, or nil when it is unrestricted
+func (r *DocumentRepository) RestrictAccess(ctx context.Context, collectionName string, access *models.DocumentAccess) (*models.DocumentAccess, error) {
+	return restrictAccess(ctx, r.db.reader, collectionName, access)
+}
+
+// RestrictAccess returns the access to enforce in a collection, or nil when it is unrestricted
+func (r *CollectionRepository) RestrictAccess(ctx context.Context, name string, access *models.DocumentAccess) (*models.DocumentAccess, error) {
+	return restrictAccess(ctx, r.db.reader, name, access)
+}
+
+// GetOwnership retrieves the owner and sharing of a live or trashed document
+func (r *DocumentRepository) GetOwnership(ctx context.Context, id, collectionName string) (*models.Document, error) {
+	return documentOwnership(ctx, r.db.reader, id, collectionName)
+}
+
+// Share replaces the subjects a document owned by the caller is shared with
+func (r *DocumentRepository) Share(ctx context.Context, id, collectionName string, sharedWith []string, access *models.DocumentAccess, actor models.Actor) (*models.Document, error) {
+	for _, subject := range sharedWith {
+		if err := models.ValidateSubject(subject); err != nil {
+			return nil, err
+		}
+	}
+	encoded, err := encodeSharedWith(sharedWith)
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Get existing document; the transaction holds the write lock, so it cannot be deleted,
+	// trashed or change hands before it is shared
+	now := time.Now()
+	document, err := getDocument(ctx, tx, id, collectionName, now)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkDocumentAccess(ctx, tx, document, access, true); err != nil {
+		return nil, err
+	}
+	document.SharedWith, _ = decodeSharedWith(encoded)
+
+	// Update sharing; the data is unchanged, so no revision is recorded, but the current one
+	// is shared as well
+	_, err = tx.ExecContext(ctx, `UPDATE documents SET shared_with = ? WHERE id = ? AND collection_name = ?`, encoded, id, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to share document: %w", err)
+	}
+	options, err := getCollectionOptions(ctx, tx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err = stampRevision(ctx, tx, options.History, collectionName, id); err != nil {
+		return nil, err
+	}
+
+	// Record change
+	err = recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeDocumentShare,
+		CollectionName: collectionName,
+		DocumentID:     id,
+		Document:       document,
+		Timestamp:      now,
+	}, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return document, nil
+}
@@ -0,0 +1,175 @@
+package db_test
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestOwnershipFiltersDocuments(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{Ownership: &models.OwnershipOptions{Enabled: true}}
+	if _, err := collections.CreateWithOptions(t.Context(), "notes", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mine, err := documents.Create(t.Context(), "notes", json.RawMessage(`{"n":1}`), nil, "user:alice", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []json.RawMessage{json.RawMessage(`{"n":2}`), json.RawMessage(`{"n":3}`)}
+	theirs, err := documents.BulkCreate(t.Context(), "notes", items, "user:bob", models.Actor{Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Share(t.Context(), theirs[0].ID, "notes", []string{"user:alice"}, nil, models.Actor{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		access *models.DocumentAccess
+		want   []string
+	}{
+		{"owner and shared", &models.DocumentAccess{Subject: "user:alice"}, []string{mine.ID, theirs[0].ID}},
+		{"owner only", &models.DocumentAccess{Subject: "user:bob"}, []string{theirs[0].ID, theirs[1].ID}},
+		{"anonymous", &models.DocumentAccess{}, nil},
+		{"elevated", &models.DocumentAccess{Subject: "user:carol", Elevated: true}, []string{mine.ID, theirs[0].ID, theirs[1].ID}},
+		{"unrestricted", nil, []string{mine.ID, theirs[0].ID, theirs[1].ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := models.NewDocumentQuery()
+			query.Access = tt.access
+			list, err := documents.List(t.Context(), "notes", query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, doc := range list.Documents {
+				got = append(got, doc.ID)
+			}
+			slices.Sort(got)
+			want := slices.Clone(tt.want)
+			slices.Sort(want)
+			if !slices.Equal(got, want) || list.Total != len(want) {
+				t.Errorf("wrong documents: got %v (total %v) want %v", got, list.Total, want)
+			}
+		})
+	}
+
+	// Collections without ownership ignore the caller
+	if _, err := documents.Create(t.Context(), "public", json.RawMessage(`{"n":4}`), nil, "user:bob", models.Actor{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	query := models.NewDocumentQuery()
+	query.Access = &models.DocumentAccess{}
+	list, err := documents.List(t.Context(), "public", query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 {
+		t.Errorf("wrong number of documents without ownership: got %v want %v", list.Total, 1)
+	}
+}
+
+func TestOwnershipOnRestore(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{
+		Ownership: &models.OwnershipOptions{Enabled: true},
+		History:   &models.HistoryOptions{Enabled: true},
+	}
+	if _, err := collections.CreateWithOptions(t.Context(), "notes", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := documents.Create(t.Context(), "notes", json.RawMessage(`{"v":1}`), nil, "user:alice", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Share(t.Context(), doc.ID, "notes", []string{"key:abc"}, nil, models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Update(t.Context(), doc.ID, "notes", json.RawMessage(`{"v":2}`), nil, nil, models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := documents.RestoreRevision(t.Context(), doc.ID, "notes", 1, "user:bob", models.Actor{Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Owner != "user:alice" {
+		t.Errorf("wrong owner: got %v want %v", restored.Owner, "user:alice")
+	}
+	if !slices.Equal(restored.SharedWith, []string{"key:abc"}) {
+		t.Errorf("wrong sharing: got %v want %v", restored.SharedWith, []string{"key:abc"})
+	}
+
+	// A purged document is re-created for whoever restores it
+	if err := documents.Delete(t.Context(), doc.ID, "notes", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	restored, err = documents.RestoreRevision(t.Context(), doc.ID, "notes", 1, "user:bob", models.Actor{Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Owner != "user:bob" || len(restored.SharedWith) != 0 {
+		t.Errorf("wrong ownership of re-created document: got %v %v want %v []", restored.Owner, restored.SharedWith, "user:bob")
+	}
+
+	if _, err := documents.Share(t.Context(), doc.ID, "notes", []string{"alice"}, nil, models.Actor{Name: "alice"}); err == nil {
+		t.Errorf("expected an invalid subject to be rejected")
+	}
+}
+
+func TestOwnershipOnWrite(t *testing.T) {
+	collections, documents, _ := newSoftDeleteRepositories(t)
+
+	options := models.CollectionOptions{Ownership: &models.OwnershipOptions{Enabled: true}}
+	if _, err := collections.CreateWithOptions(t.Context(), "notes", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	alice := &models.DocumentAccess{Subject: "user:alice"}
+	bob := &models.DocumentAccess{Subject: "user:bob"}
+	doc, err := documents.Create(t.Context(), "notes", json.RawMessage(`{"v":1}`), nil, alice.Subject, models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Share(t.Context(), doc.ID, "notes", []string{bob.Subject}, alice, models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Whoever the document is shared with may update it, but only its owner may share it
+	if _, err := documents.Update(t.Context(), doc.ID, "notes", json.RawMessage(`{"v":2}`), nil, bob, models.Actor{Name: "bob"}); err != nil {
+		t.Errorf("update by shared subject rejected: %v", err)
+	}
+	if _, err := documents.Share(t.Context(), doc.ID, "notes", nil, bob, models.Actor{Name: "bob"}); err == nil {
+		t.Errorf("share by shared subject allowed")
+	}
+
+	// Unsharing takes the access away
+	if _, err := documents.Share(t.Context(), doc.ID, "notes", nil, alice, models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Update(t.Context(), doc.ID, "notes", json.RawMessage(`{"v":3}`), nil, bob, models.Actor{Name: "bob"}); err == nil {
+		t.Errorf("update after unsharing allowed")
+	}
+
+	// Trashed documents cannot be written
+	if err := documents.Delete(t.Context(), doc.ID, "notes", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Update(t.Context(), doc.ID, "notes", json.RawMessage(`{"v":4}`), nil, alice, models.Actor{Name: "alice"}); err == nil {
+		t.Errorf("update of trashed document allowed")
+	}
+	if _, err := documents.Share(t.Context(), doc.ID, "notes", []string{bob.Subject}, alice, models.Actor{Name: "alice"}); err == nil {
+		t.Errorf("share of trashed document allowed")
+	}
+}
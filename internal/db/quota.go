@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// liveDocuments selects the documents of a collection that are neither in the trash nor
+// expired, which are the ones its cap and quota count; it takes the collection name and the
+// current UTC time as arguments
+const liveDocuments = `collection_name = ? AND deleted_at IS NULL` + notExpired
+
+// measureCollection returns the number and total size of the live documents of a collection
+func measureCollection(ctx context.Context, tx *sql.Tx, collectionName string, now time.Time) (count int, size int64, err error) {
+	err = tx.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(LENGTH(data)), 0) FROM documents WHERE `+liveDocuments,
+		collectionName, now.UTC(),
+	).Scan(&count, &size)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to measure collection: %w", err)
+	}
+	return count, size, nil
+}
+
+// checkQuota rejects a write that left a collection beyond its quota. It runs after the
+// write inside the same transaction, so returning an error rolls the write back. Documents
+// in the trash do not count, as restoring one checks the quota again.
+func checkQuota(ctx context.Context, tx *sql.Tx, collectionName string, quota *models.QuotaOptions, now time.Time) error {
+	if quota == nil || (quota.MaxDocuments <= 0 && quota.MaxBytes <= 0) {
+		return nil
+	}
+
+	count, size, err := measureCollection(ctx, tx, collectionName, now)
+	if err != nil {
+		return err
+	}
+
+	if quota.MaxDocuments > 0 && count > quota.MaxDocuments {
+		return fmt.Errorf("%w: collection '%s' would hold %d documents, the quota is %d", models.ErrQuotaExceeded, collectionName, count, quota.MaxDocuments)
+	}
+	if quota.MaxBytes > 0 && size > quota.MaxBytes {
+		return fmt.Errorf("%w: collection '%s' would hold %d bytes, the quota is %d", models.ErrQuotaExceeded, collectionName, size, quota.MaxBytes)
+	}
+	return nil
+}
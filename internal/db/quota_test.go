@@ -0,0 +1,108 @@
+package db_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestQuotaRejectsDocuments(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{Quota: &models.QuotaOptions{MaxDocuments: 3}}
+	if _, err := collections.CreateWithOptions(t.Context(), "jobs", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := documents.Create(t.Context(), "jobs", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A bulk insert that does not fit is rejected as a whole
+	items := []json.RawMessage{json.RawMessage(`{}`), json.RawMessage(`{}`)}
+	if _, err := documents.BulkCreate(t.Context(), "jobs", items, "", models.Actor{Name: "alice"}); !errors.Is(err, models.ErrQuotaExceeded) {
+		t.Errorf("wrong error for bulk insert beyond the quota: got %v want %v", err, models.ErrQuotaExceeded)
+	}
+	list, err := documents.List(t.Context(), "jobs", models.NewDocumentQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 2 {
+		t.Errorf("wrong number of documents after rejected bulk insert: got %v want %v", list.Total, 2)
+	}
+
+	if _, err := documents.Create(t.Context(), "jobs", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Create(t.Context(), "jobs", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"}); !errors.Is(err, models.ErrQuotaExceeded) {
+		t.Errorf("wrong error for insert beyond the quota: got %v want %v", err, models.ErrQuotaExceeded)
+	}
+}
+
+func TestQuotaByBytes(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	// Each document below is 10 bytes
+	options := models.CollectionOptions{Quota: &models.QuotaOptions{MaxBytes: 25}}
+	if _, err := collections.CreateWithOptions(t.Context(), "logs", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := documents.Create(t.Context(), "logs", json.RawMessage(`{"n":"aa"}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Create(t.Context(), "logs", json.RawMessage(`{"n":"bb"}`), nil, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Create(t.Context(), "logs", json.RawMessage(`{"n":"cc"}`), nil, "", models.Actor{Name: "alice"}); !errors.Is(err, models.ErrQuotaExceeded) {
+		t.Errorf("wrong error for insert beyond the quota: got %v want %v", err, models.ErrQuotaExceeded)
+	}
+
+	// Updates may not grow the collection beyond its quota, but may shrink it
+	if _, err := documents.Update(t.Context(), first.ID, "logs", json.RawMessage(`{"n":"aaaaaaaa"}`), nil, nil, models.Actor{Name: "alice"}); !errors.Is(err, models.ErrQuotaExceeded) {
+		t.Errorf("wrong error for update beyond the quota: got %v want %v", err, models.ErrQuotaExceeded)
+	}
+	if _, err := documents.Update(t.Context(), first.ID, "logs", json.RawMessage(`{}`), nil, nil, models.Actor{Name: "alice"}); err != nil {
+		t.Errorf("shrinking update rejected: %v", err)
+	}
+}
+
+func TestQuotaCountsLiveDocuments(t *testing.T) {
+	collections, documents, trash := newSoftDeleteRepositories(t)
+
+	options := models.CollectionOptions{Quota: &models.QuotaOptions{MaxDocuments: 2}}
+	if _, err := collections.CreateWithOptions(t.Context(), "jobs", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired documents awaiting the sweeper do not count
+	if _, err := documents.Create(t.Context(), "jobs", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(10 * time.Millisecond)
+	if _, err := documents.Create(t.Context(), "jobs", json.RawMessage(`{}`), &expiresAt, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	trashed, err := documents.Create(t.Context(), "jobs", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatalf("insert in place of an expired document rejected: %v", err)
+	}
+
+	// Nor do documents in the trash, but restoring one counts it again
+	if err := documents.Delete(t.Context(), trashed.ID, "jobs", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Create(t.Context(), "jobs", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatalf("insert in place of a trashed document rejected: %v", err)
+	}
+	if _, err := trash.RestoreDocument(t.Context(), trashed.ID, "jobs", models.Actor{Name: "alice"}); !errors.Is(err, models.ErrQuotaExceeded) {
+		t.Errorf("wrong error for restore beyond the quota: got %v want %v", err, models.ErrQuotaExceeded)
+	}
+}
@@ -0,0 +1,569 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// recordRevision stores a new document version when the collection keeps history.
+// It must run before the document row is modified so the version being replaced can
+// be captured if the document predates history being enabled. The revision takes the
+// owner, sharing and creation time of the document as it stands; writes that create the
+// document or change its ownership afterwards call stampRevision.
+func recordRevision(ctx context.Context, tx *sql.Tx, history *models.HistoryOptions, collectionName, id string, operation models.RevisionOperation, data json.RawMessage, author string, timestamp time.Time) error {
+	if history == nil || !history.Enabled {
+		return nil
+	}
+
+	// Get the latest revision number
+	var latest sql.NullInt64
+	query := `SELECT MAX(revision) FROM document_revisions WHERE collection_name = ? AND document_id = ?`
+	if err := tx.QueryRowContext(ctx, query, collectionName, id).Scan(&latest); err != nil {
+		return fmt.Errorf("failed to get latest revision: %w", err)
+	}
+	next := latest.Int64 + 1
+
+	// Get the ownership and creation time of the document, live or trashed
+	owner, sharedWith, createdAt := "", "[]", timestamp
+	err := tx.QueryRowContext(ctx,
+		`SELECT owner, shared_with, created_at FROM documents WHERE id = ? AND collection_name = ?`,
+		id, collectionName,
+	).Scan(&owner, &sharedWith, &createdAt)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read document ownership: %w", err)
+	}
+
+	insert := `INSERT INTO document_revisions (collection_name, document_id, revision, operation, data, author, created_at, superseded_at, owner, shared_with, document_created_at)
+			   VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	// Close the validity interval of the current revision
+	if latest.Valid {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE document_revisions SET superseded_at = ? WHERE collection_name = ? AND document_id = ? AND revision = ?`,
+			timestamp.UTC(), collectionName, id, latest.Int64,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to supersede revision: %w", err)
+		}
+	}
+
+	// Seed the history with the version being replaced
+	if !latest.Valid && operation != models.RevisionCreate {
+		var prior []byte
+		var priorUpdatedAt time.Time
+		err := tx.QueryRowContext(ctx,
+			`SELECT data, updated_at FROM documents WHERE id = ? AND collection_name = ? AND deleted_at IS NULL`,
+			id, collectionName,
+		).Scan(&prior, &priorUpdatedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to read previous version: %w", err)
+		}
+		if err == nil {
+			_, err = tx.ExecContext(ctx, insert, collectionName, id, next, string(models.RevisionCreate), prior, "", priorUpdatedAt.UTC(), timestamp.UTC(), owner, sharedWith, createdAt.UTC())
+			if err != nil {
+				return fmt.Errorf("failed to record revision: %w", err)
+			}
+			next++
+		}
+	}
+
+	// Deletions are recorded as revisions without data
+	var revisionData interface{}
+	if data != nil {
+		revisionData = []byte(data)
+	}
+
+	_, err = tx.ExecContext(ctx, insert, collectionName, id, next, string(operation), revisionData, author, timestamp.UTC(), nil, owner, sharedWith, createdAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+
+	return pruneRevisions(ctx, tx, collectionName, id, history, timestamp)
+}
+
+// stampRevision copies the owner, sharing and creation time of a document to its current
+// revision, after a write that created the document or changed its ownership
+func stampRevision(ctx context.Context, tx *sql.Tx, history *models.HistoryOptions, collectionName, id string) error {
+	if history == nil || !history.Enabled {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`UPDATE document_revisions SET
+			owner = d.owner, shared_with = d.shared_with, document_created_at = d.created_at
+		 FROM documents d
+		 WHERE d.id = document_revisions.document_id AND d.collection_name = document_revisions.collection_name
+		 AND document_revisions.collection_name = ? AND document_revisions.document_id = ?
+		 AND document_revisions.superseded_at IS NULL`,
+		collectionName, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record revision ownership: %w", err)
+	}
+	return nil
+}
+
+// pruneRevisions enforces the retention limits of a collection's history.
+// An empty id prunes every document in the collection. The latest revision
+// of a document is always kept.
+func pruneRevisions(ctx context.Context, tx *sql.Tx, collectionName, id string, history *models.HistoryOptions, now time.Time) error {
+	if history == nil {
+		return nil
+	}
+
+	latest := `(SELECT MAX(r.revision) FROM document_revisions r
+				WHERE r.collection_name = document_revisions.collection_name
+				AND r.document_id = document_revisions.document_id)`
+
+	// Keep at most MaxRevisions per document
+	if history.MaxRevisions > 0 {
+		query := `DELETE FROM document_revisions
+				  WHERE collection_name = ? AND (? = '' OR document_id = ?)
+				  AND revision <= ` + latest + ` - ?`
+		_, err := tx.ExecContext(ctx, query, collectionName, id, id, history.MaxRevisions)
+		if err != nil {
+			return fmt.Errorf("failed to prune revisions: %w", err)
+		}
+	}
+
+	// Drop revisions older than MaxAge
+	if history.MaxAge > 0 {
+		cutoff := now.Add(-history.MaxAge.Std()).UTC()
+		query := `DELETE FROM document_revisions
+				  WHERE collection_name = ? AND (? = '' OR document_id = ?)
+				  AND created_at < ? AND revision < ` + latest
+		_, err := tx.ExecContext(ctx, query, collectionName, id, id, cutoff)
+		if err != nil {
+			return fmt.Errorf("failed to prune revisions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// seedRevisions records the current version of every document in a collection that has
+// no revision yet, so history is complete from the moment it is enabled
+func seedRevisions(ctx context.Context, tx *sql.Tx, collectionName string) error {
+	query := `SELECT id, data, updated_at, owner, shared_with, created_at FROM documents d
+			  WHERE collection_name = ? AND deleted_at IS NULL AND NOT EXISTS (
+				  SELECT 1 FROM document_revisions r
+				  WHERE r.collection_name = d.collection_name AND r.document_id = d.id
+			  )`
+	rows, err := tx.QueryContext(ctx, query, collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to list documents without history: %w", err)
+	}
+	defer rows.Close()
+
+	type unrecorded struct {
+		document   models.Document
+		sharedWith string
+	}
+	var documents []unrecorded
+	for rows.Next() {
+		var u unrecorded
+		var data []byte
+		if err := rows.Scan(&u.document.ID, &data, &u.document.UpdatedAt, &u.document.Owner, &u.sharedWith, &u.document.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan document: %w", err)
+		}
+		u.document.Data = json.RawMessage(data)
+		documents = append(documents, u)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over documents: %w", err)
+	}
+	rows.Close()
+
+	insert := `INSERT INTO document_revisions (collection_name, document_id, revision, operation, data, author, created_at, owner, shared_with, document_created_at)
+			   VALUES (?, ?, 1, ?, ?, '', ?, ?, ?, ?)`
+	for _, u := range documents {
+		document := u.document
+		_, err := tx.ExecContext(ctx, insert, collectionName, document.ID, string(models.RevisionCreate), []byte(document.Data), document.UpdatedAt.UTC(), document.Owner, u.sharedWith, document.CreatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("failed to record revision: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ListRevisions retrieves the revision history of a document
+func (r *DocumentRepository) ListRevisions(ctx context.Context, id, collectionName string) (*models.RevisionList, error) {
+	// Check if collection exists
+	exists, err := r.collectionRepo.Exists(ctx, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("collection '%s' not found", collectionName)
+	}
+
+	query := `SELECT collection_name, document_id, revision, operation, data, author, created_at, superseded_at
+			  FROM document_revisions
+			  WHERE collection_name = ? AND document_id = ?
+			  ORDER BY revision`
+	rows, err := r.db.reader.QueryContext(ctx, query, collectionName, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := make([]models.Revision, 0)
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over revisions: %w", err)
+	}
+	rows.Close()
+
+	// A document without history must at least exist
+	if len(revisions) == 0 {
+		exists, err := r.Exists(ctx, id, collectionName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if document exists: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("document with ID '%s' not found in collection '%s'", id, collectionName)
+		}
+	}
+
+	return &models.RevisionList{
+		Total:     len(revisions),
+		Revisions: revisions,
+	}, nil
+}
+
+// GetRevision retrieves a single revision of a document
+func (r *DocumentRepository) GetRevision(ctx context.Context, id, collectionName string, revision int) (*models.Revision, error) {
+	query := `SELECT collection_name, document_id, revision, operation, data, author, created_at, superseded_at
+			  FROM document_revisions
+			  WHERE collection_name = ? AND document_id = ? AND revision = ?`
+	row := r.db.reader.QueryRowContext(ctx, query, collectionName, id, revision)
+
+	result, err := scanRevision(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("revision %d of document '%s' not found in collection '%s'", revision, id, collectionName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RestoreRevision makes a previous revision the current version of a document,
+// re-creating the document owned by owner if it has been purged
+func (r *DocumentRepository) RestoreRevision(ctx context.Context, id, collectionName string, revision int, owner string, actor models.Actor) (*models.Document, error) {
+	// Get the revision to restore
+	source, err := r.GetRevision(ctx, id, collectionName, revision)
+	if err != nil {
+		return nil, err
+	}
+	if source.Data == nil {
+		return nil, fmt.Errorf("revision %d of document '%s' is a deletion and cannot be restored", revision, id)
+	}
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	options, err := getCollectionOptions(ctx, tx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkCapSize(options.Cap, source.Data); err != nil {
+		return nil, err
+	}
+
+	// Check whether the document currently exists
+	now := time.Now()
+	document := &models.Document{
+		ID:             id,
+		CollectionName: collectionName,
+		Data:           source.Data,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		ExpiresAt:      options.DefaultExpiry(now),
+		Owner:          owner,
+	}
+	var sharedWith string
+	err = tx.QueryRowContext(ctx,
+		`SELECT created_at, owner, shared_with FROM documents WHERE id = ? AND collection_name = ?`,
+		id, collectionName,
+	).Scan(&document.CreatedAt, &document.Owner, &sharedWith)
+	exists := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check if document exists: %w", err)
+	}
+	if document.SharedWith, err = decodeSharedWith(sharedWith); err != nil {
+		return nil, err
+	}
+
+	// Record the restored version
+	err = recordRevision(ctx, tx, options.History, collectionName, id, models.RevisionRestore, source.Data, actor.Name, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the document
+	if exists {
+		_, err = tx.ExecContext(ctx,
+			`UPDATE documents SET data = ?, updated_at = ?, expires_at = ?, deleted_at = NULL WHERE id = ? AND collection_name = ?`,
+			document.Data, document.UpdatedAt, storedExpiry(document.ExpiresAt), id, collectionName,
+		)
+	} else {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO documents (id, collection_name, data, created_at, updated_at, expires_at, owner) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			document.ID, document.CollectionName, document.Data, document.CreatedAt, document.UpdatedAt, storedExpiry(document.ExpiresAt), document.Owner,
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore document: %w", err)
+	}
+	if err = stampRevision(ctx, tx, options.History, collectionName, id); err != nil {
+		return nil, err
+	}
+
+	// The restored version must fit in the collection's quota
+	if err = checkQuota(ctx, tx, collectionName, options.Quota, now); err != nil {
+		return nil, err
+	}
+
+	// Record change
+	err = recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeDocumentPut,
+		CollectionName: collectionName,
+		DocumentID:     id,
+		Document:       document,
+		Timestamp:      now,
+	}, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Evict the oldest documents of a capped collection
+	if _, err = evictOverflow(ctx, tx, collectionName, options, id, now); err != nil {
+		return nil, err
+	}
+
+	// Update collection timestamp
+	_, err = tx.ExecContext(ctx, `UPDATE collections SET updated_at = ? WHERE name = ?`, now, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update collection timestamp: %w", err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return document, nil
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRevision reads a revision from a query result
+func scanRevision(row rowScanner) (*models.Revision, error) {
+	var revision models.Revision
+	var operation string
+	var data []byte
+	var supersededAt sql.NullTime
+	err := row.Scan(
+		&revision.CollectionName,
+		&revision.DocumentID,
+		&revision.Revision,
+		&operation,
+		&data,
+		&revision.Author,
+		&revision.CreatedAt,
+		&supersededAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan revision: %w", err)
+	}
+
+	revision.Operation = models.RevisionOperation(operation)
+	if supersededAt.Valid {
+		revision.SupersededAt = &supersededAt.Time
+	}
+	if data != nil {
+		revision.Data = json.RawMessage(data)
+	}
+	return &revision, nil
+}
+
+// asOfVersions selects the revision of each document that was valid at a point in time
+const asOfVersions = `
+	FROM document_revisions v
+	WHERE v.collection_name = ?
+	AND v.created_at <= ?
+	AND (v.superseded_at IS NULL OR v.superseded_at > ?)
+	AND v.operation != 'delete'`
+
+// asOfColumns are the columns of a document as of a point in time, read by scanAsOf
+const asOfColumns = `SELECT v.document_id, v.collection_name, v.data, v.document_created_at, v.created_at`
+
+// scanAsOf reads a document as of a point in time from a query result
+func scanAsOf(row rowScanner) (*models.Document, error) {
+	var document models.Document
+	var dataBytes []byte
+	var createdAt sql.NullTime
+	if err := row.Scan(&document.ID, &document.CollectionName, &dataBytes, &createdAt, &document.UpdatedAt); err != nil {
+		return nil, err
+	}
+	document.Data = json.RawMessage(dataBytes)
+
+	// Revisions recorded before creation times were kept only know their own
+	document.CreatedAt = document.UpdatedAt
+	if createdAt.Valid {
+		document.CreatedAt = createdAt.Time
+	}
+	return &document, nil
+}
+
+// requireHistory returns an error unless the collection keeps revision history
+func (r *DocumentRepository) requireHistory(ctx context.Context, collectionName string) error {
+	options, err := getCollectionOptions(ctx, r.db.reader, collectionName)
+	if err != nil {
+		return err
+	}
+	if !options.HistoryEnabled() {
+		return fmt.Errorf("collection '%s': %w", collectionName, models.ErrHistoryNotEnabled)
+	}
+	return nil
+}
+
+// requireRetained returns an error unless the history of a collection, or of one document
+// when id is not empty, still reaches back to a point in time. Pruning keeps the latest
+// revisions of a document, so once its first revision is gone it is only known from its
+// oldest remaining revision onwards.
+func (r *DocumentRepository) requireRetained(ctx context.Context, collectionName, id string, at time.Time) error {
+	query := `SELECT EXISTS (
+				SELECT 1 FROM document_revisions r
+				WHERE r.collection_name = ? AND (? = '' OR r.document_id = ?)
+				AND r.revision > 1 AND r.created_at > ?
+				AND r.revision = (
+					SELECT MIN(m.revision) FROM document_revisions m
+					WHERE m.collection_name = r.collection_name AND m.document_id = r.document_id
+				)
+			  )`
+	var pruned bool
+	if err := r.db.reader.QueryRowContext(ctx, query, collectionName, id, id, at.UTC()).Scan(&pruned); err != nil {
+		return fmt.Errorf("failed to check retained history: %w", err)
+	}
+	if pruned {
+		return fmt.Errorf("collection '%s' as of %s: %w", collectionName, at.Format(time.RFC3339), models.ErrHistoryPruned)
+	}
+	return nil
+}
+
+// GetByIDAsOf retrieves a document as it was at the given time. In a collection with
+// ownership enabled, the caller must have been able to see that version.
+func (r *DocumentRepository) GetByIDAsOf(ctx context.Context, id, collectionName string, asOf time.Time, access *models.DocumentAccess) (*models.Document, error) {
+	if err := r.requireHistory(ctx, collectionName); err != nil {
+		return nil, err
+	}
+	if err := r.requireRetained(ctx, collectionName, id, asOf); err != nil {
+		return nil, err
+	}
+
+	// Check ownership against the version itself, which outlives the document
+	access, err := r.RestrictAccess(ctx, collectionName, access)
+	if err != nil {
+		return nil, err
+	}
+	ownership, ownershipArgs := accessFilter("v", access)
+
+	at := asOf.UTC()
+	query := asOfColumns + asOfVersions + `
+			  AND v.document_id = ?` + ownership
+	args := append([]interface{}{collectionName, at, at, id}, ownershipArgs...)
+	document, err := scanAsOf(r.db.reader.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("document with ID '%s' not found in collection '%s' as of %s", id, collectionName, asOf.Format(time.RFC3339))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	return document, nil
+}
+
+// listAsOf retrieves documents from a collection as they were at the time in the query
+func (r *DocumentRepository) listAsOf(ctx context.Context, collectionName string, queryParams *models.DocumentQuery) (*models.DocumentList, error) {
+	if err := r.requireHistory(ctx, collectionName); err != nil {
+		return nil, err
+	}
+	if err := r.requireRetained(ctx, collectionName, "", *queryParams.AsOf); err != nil {
+		return nil, err
+	}
+	at := queryParams.AsOf.UTC()
+
+	// Limit the result to versions the caller could see in a collection with ownership enabled
+	access, err := r.RestrictAccess(ctx, collectionName, queryParams.Access)
+	if err != nil {
+		return nil, err
+	}
+	ownership, ownershipArgs := accessFilter("v", access)
+	args := append([]interface{}{collectionName, at, at}, ownershipArgs...)
+
+	// Get total count
+	var total int
+	err = r.db.reader.QueryRowContext(ctx, `SELECT COUNT(*)`+asOfVersions+ownership, args...).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	// Get documents with pagination
+	query := asOfColumns + asOfVersions + ownership + `
+			  ORDER BY v.document_created_at DESC
+			  LIMIT ? OFFSET ?`
+	rows, err := r.db.reader.QueryContext(ctx, query, append(args, queryParams.Limit, queryParams.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := make([]models.Document, 0)
+	for rows.Next() {
+		document, err := scanAsOf(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, *document)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over documents: %w", err)
+	}
+
+	return &models.DocumentList{
+		Total:     total,
+		Offset:    queryParams.Offset,
+		Limit:     queryParams.Limit,
+		Documents: documents,
+	}, nil
+}
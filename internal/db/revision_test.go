@@ -0,0 +1,269 @@
+package db_test
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// newTestRepositories opens a fresh database and returns its repositories
+func newTestRepositories(t *testing.T) (*db.CollectionRepository, *db.DocumentRepository) {
+	t.Helper()
+
+	database, err := db.New(db.NewConfig(filepath.Join(t.TempDir(), "db.sqlite")))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	collectionRepo := db.NewCollectionRepository(database)
+	return collectionRepo, db.NewDocumentRepository(database, collectionRepo)
+}
+
+func TestRevisionHistoryAndRestore(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{History: &models.HistoryOptions{Enabled: true}}
+	if _, err := collections.CreateWithOptions(t.Context(), "configs", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := documents.Create(t.Context(), "configs", json.RawMessage(`{"v":1}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Update(t.Context(), doc.ID, "configs", json.RawMessage(`{"v":2}`), nil, nil, models.Actor{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := documents.Delete(t.Context(), doc.ID, "configs", models.Actor{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := documents.ListRevisions(t.Context(), doc.ID, "configs")
+	if err != nil {
+		t.Fatalf("failed to list revisions of deleted document: %v", err)
+	}
+	wantOps := []models.RevisionOperation{models.RevisionCreate, models.RevisionUpdate, models.RevisionDelete}
+	if list.Total != len(wantOps) {
+		t.Fatalf("wrong number of revisions: got %v want %v", list.Total, len(wantOps))
+	}
+	for i, op := range wantOps {
+		if got := list.Revisions[i]; got.Revision != i+1 || got.Operation != op {
+			t.Errorf("revision %d: got %v/%v want %v/%v", i, got.Revision, got.Operation, i+1, op)
+		}
+	}
+	if list.Revisions[1].Author != "bob" {
+		t.Errorf("wrong author: got %v want %v", list.Revisions[1].Author, "bob")
+	}
+
+	// Restoring the first revision re-creates the deleted document
+	restored, err := documents.RestoreRevision(t.Context(), doc.ID, "configs", 1, "", models.Actor{Name: "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(restored.Data) != `{"v":1}` {
+		t.Errorf("wrong restored data: got %s want %s", restored.Data, `{"v":1}`)
+	}
+	if _, err := documents.GetByID(t.Context(), doc.ID, "configs"); err != nil {
+		t.Errorf("restored document not found: %v", err)
+	}
+
+	latest, err := documents.GetRevision(t.Context(), doc.ID, "configs", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Operation != models.RevisionRestore || latest.Author != "carol" {
+		t.Errorf("wrong restore revision: got %v by %v", latest.Operation, latest.Author)
+	}
+
+	if _, err := documents.RestoreRevision(t.Context(), doc.ID, "configs", 3, "", models.Actor{Name: "carol"}); err == nil {
+		t.Errorf("restoring a deletion revision should fail")
+	}
+}
+
+func TestRevisionRetention(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	// Enabling history on an existing document keeps the version being replaced
+	doc, err := documents.Create(t.Context(), "logs", json.RawMessage(`{"v":0}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := models.CollectionOptions{History: &models.HistoryOptions{Enabled: true, MaxRevisions: 3}}
+	if _, err := collections.UpdateOptions(t.Context(), "logs", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 5; i++ {
+		data := json.RawMessage(`{"v":` + string(rune('0'+i)) + `}`)
+		if _, err := documents.Update(t.Context(), doc.ID, "logs", data, nil, nil, models.Actor{Name: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list, err := documents.ListRevisions(t.Context(), doc.ID, "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 3 {
+		t.Fatalf("wrong number of revisions: got %v want %v", list.Total, 3)
+	}
+	if first, last := list.Revisions[0].Revision, list.Revisions[2].Revision; first != 4 || last != 6 {
+		t.Errorf("wrong revisions kept: got %v..%v want %v..%v", first, last, 4, 6)
+	}
+}
+
+func TestReadAsOf(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{History: &models.HistoryOptions{Enabled: true}}
+	if _, err := collections.CreateWithOptions(t.Context(), "audited", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// tick returns the current time and makes sure later writes get a later timestamp
+	tick := func() time.Time {
+		now := time.Now()
+		time.Sleep(2 * time.Millisecond)
+		return now
+	}
+
+	beforeCreate := tick()
+	doc, err := documents.Create(t.Context(), "audited", json.RawMessage(`{"v":1}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	afterCreate := tick()
+	if _, err := documents.Update(t.Context(), doc.ID, "audited", json.RawMessage(`{"v":2}`), nil, nil, models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	afterUpdate := tick()
+	if err := documents.Delete(t.Context(), doc.ID, "audited", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	afterDelete := tick()
+
+	tests := []struct {
+		name string
+		asOf time.Time
+		want string
+	}{
+		{"before create", beforeCreate, ""},
+		{"after create", afterCreate, `{"v":1}`},
+		{"after update", afterUpdate, `{"v":2}`},
+		{"after delete", afterDelete, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := documents.GetByIDAsOf(t.Context(), doc.ID, "audited", tt.asOf, nil)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("expected no document, got %s", got.Data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got.Data) != tt.want {
+				t.Errorf("wrong data: got %s want %s", got.Data, tt.want)
+			}
+
+			query := models.NewDocumentQuery()
+			query.AsOf = &tt.asOf
+			list, err := documents.List(t.Context(), "audited", query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if list.Total != 1 || string(list.Documents[0].Data) != tt.want {
+				t.Errorf("wrong list as of %v: %+v", tt.asOf, list)
+			}
+		})
+	}
+
+	// Point-in-time reads need history
+	if _, err := documents.Create(t.Context(), "plain", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.GetByIDAsOf(t.Context(), doc.ID, "plain", afterCreate, nil); !errors.Is(err, models.ErrHistoryNotEnabled) {
+		t.Errorf("wrong error: got %v want %v", err, models.ErrHistoryNotEnabled)
+	}
+}
+
+func TestReadAsOfPrunedHistory(t *testing.T) {
+	collections, documents := newTestRepositories(t)
+
+	options := models.CollectionOptions{
+		History:   &models.HistoryOptions{Enabled: true, MaxRevisions: 2},
+		Ownership: &models.OwnershipOptions{Enabled: true},
+	}
+	if _, err := collections.CreateWithOptions(t.Context(), "owned", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tick := func() time.Time {
+		now := time.Now()
+		time.Sleep(2 * time.Millisecond)
+		return now
+	}
+
+	doc, err := documents.Create(t.Context(), "owned", json.RawMessage(`{"v":1}`), nil, "user:alice", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	afterCreate := tick()
+	for i := 2; i <= 4; i++ {
+		data := json.RawMessage(`{"v":` + string(rune('0'+i)) + `}`)
+		if _, err := documents.Update(t.Context(), doc.ID, "owned", data, nil, nil, models.Actor{Name: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	afterUpdates := tick()
+	if err := documents.Delete(t.Context(), doc.ID, "owned", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first revisions were pruned, so reads from before the oldest kept one are rejected
+	if _, err := documents.GetByIDAsOf(t.Context(), doc.ID, "owned", afterCreate, nil); !errors.Is(err, models.ErrHistoryPruned) {
+		t.Errorf("wrong error reading pruned history: got %v want %v", err, models.ErrHistoryPruned)
+	}
+	query := models.NewDocumentQuery()
+	query.AsOf = &afterCreate
+	if _, err := documents.List(t.Context(), "owned", query); !errors.Is(err, models.ErrHistoryPruned) {
+		t.Errorf("wrong error listing pruned history: got %v want %v", err, models.ErrHistoryPruned)
+	}
+
+	// The owner still reads the deleted document, with its real creation time
+	alice := &models.DocumentAccess{Subject: "user:alice"}
+	got, err := documents.GetByIDAsOf(t.Context(), doc.ID, "owned", afterUpdates, alice)
+	if err != nil {
+		t.Fatalf("owner cannot read deleted document: %v", err)
+	}
+	if string(got.Data) != `{"v":4}` {
+		t.Errorf("wrong data: got %s want %s", got.Data, `{"v":4}`)
+	}
+	if !got.CreatedAt.Equal(doc.CreatedAt) {
+		t.Errorf("wrong creation time: got %v want %v", got.CreatedAt, doc.CreatedAt)
+	}
+
+	// Others do not
+	bob := &models.DocumentAccess{Subject: "user:bob"}
+	if _, err := documents.GetByIDAsOf(t.Context(), doc.ID, "owned", afterUpdates, bob); err == nil {
+		t.Error("other user read a document it does not own")
+	}
+	query.AsOf = &afterUpdates
+	query.Access = bob
+	list, err := documents.List(t.Context(), "owned", query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 0 {
+		t.Errorf("other user listed documents it does not own: got %v want 0", list.Total)
+	}
+}
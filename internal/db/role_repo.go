@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// RoleRepository handles role and role assignment operations
+type RoleRepository struct {
+	db *DB
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(db *DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// Create stores a new role
+func (r *RoleRepository) Create(ctx context.Context, role *models.Role) error {
+	grants, err := json.Marshal(role.Grants)
+	if err != nil {
+		return fmt.Errorf("failed to encode grants: %w", err)
+	}
+
+	query := `INSERT INTO roles (name, grants, created_at, updated_at) VALUES (?, ?, ?, ?)`
+	_, err = r.db.ExecContext(ctx, query, role.Name, string(grants), role.CreatedAt, role.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("role '%s' already exists", role.Name)
+		}
+		return fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return nil
+}
+
+// GetByName retrieves a role by name
+func (r *RoleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	row := r.db.reader.QueryRowContext(ctx, `SELECT name, grants, created_at, updated_at FROM roles WHERE name = ?`, name)
+
+	role, err := scanRole(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("role '%s' not found", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// List retrieves all roles ordered by name
+func (r *RoleRepository) List(ctx context.Context) ([]models.Role, error) {
+	rows, err := r.db.reader.QueryContext(ctx, `SELECT name, grants, created_at, updated_at FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make([]models.Role, 0)
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// Update replaces the grants of a role
+func (r *RoleRepository) Update(ctx context.Context, name string, grants []models.Grant) (*models.Role, error) {
+	encodedGrants, err := json.Marshal(grants)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode grants: %w", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, `UPDATE roles SET grants = ?, updated_at = ? WHERE name = ?`, string(encodedGrants), time.Now(), name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, fmt.Errorf("role '%s' not found", name)
+	}
+
+	return r.GetByName(ctx, name)
+}
+
+// Delete removes a role and its assignments
+func (r *RoleRepository) Delete(ctx context.Context, name string) error {
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM roles WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		err = fmt.Errorf("role '%s' not found", name)
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM role_assignments WHERE role = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete role assignments: %w", err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Assign assigns a role to a subject; assigning it again has no effect
+func (r *RoleRepository) Assign(ctx context.Context, role, subject string) error {
+	query := `INSERT OR IGNORE INTO role_assignments (role, subject, created_at) VALUES (?, ?, ?)`
+	if _, err := r.db.ExecContext(ctx, query, role, subject, time.Now()); err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+	return nil
+}
+
+// Unassign removes a role from a subject
+func (r *RoleRepository) Unassign(ctx context.Context, role, subject string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM role_assignments WHERE role = ? AND subject = ?`, role, subject)
+	if err != nil {
+		return fmt.Errorf("failed to unassign role: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("role '%s' is not assigned to '%s'", role, subject)
+	}
+	return nil
+}
+
+// Subjects lists the subjects a role is assigned to
+func (r *RoleRepository) Subjects(ctx context.Context, role string) ([]string, error) {
+	return r.queryStrings(ctx, `SELECT subject FROM role_assignments WHERE role = ? ORDER BY subject`, role)
+}
+
+// RolesOf lists the names of the roles assigned to a subject
+func (r *RoleRepository) RolesOf(ctx context.Context, subject string) ([]string, error) {
+	return r.queryStrings(ctx, `SELECT role FROM role_assignments WHERE subject = ? ORDER BY role`, subject)
+}
+
+// queryStrings runs a query returning a single text column
+func (r *RoleRepository) queryStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := r.db.reader.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query role assignments: %w", err)
+	}
+	defer rows.Close()
+
+	values := make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to scan role assignment: %w", err)
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over role assignments: %w", err)
+	}
+
+	return values, nil
+}
+
+// scanRole reads a role from a query result
+func scanRole(row rowScanner) (*models.Role, error) {
+	var role models.Role
+	var grants string
+	err := row.Scan(&role.Name, &grants, &role.CreatedAt, &role.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan role: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(grants), &role.Grants); err != nil {
+		return nil, fmt.Errorf("failed to decode grants: %w", err)
+	}
+
+	return &role, nil
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// Snapshot passes the latest position in the change log to fn, then every collection and
+// every document, including those in the trash, and finally the end of the snapshot.
+// Everything is read in one transaction, so the snapshot reflects exactly the changes up to
+// that position.
+func (r *ChangeRepository) Snapshot(ctx context.Context, fn func(*models.SnapshotEntry) error) error {
+	tx, err := r.db.reader.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Get the head of the change log
+	checkpoint := &models.ChangeCheckpoint{}
+	var timestamp sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT seq, timestamp FROM changes ORDER BY seq DESC LIMIT 1`).
+		Scan(&checkpoint.Seq, &timestamp)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get latest change: %w", err)
+	}
+	checkpoint.Timestamp = timestamp.Time
+	if err := fn(&models.SnapshotEntry{Checkpoint: checkpoint}); err != nil {
+		return err
+	}
+
+	// Get collections
+	rows, err := tx.QueryContext(ctx, `SELECT name, options, created_at, updated_at, deleted_at FROM collections ORDER BY name`)
+	if err != nil {
+		return fmt.Errorf("failed to list collections: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var collection models.Collection
+		var options string
+		var deletedAt sql.NullTime
+		if err := rows.Scan(&collection.Name, &options, &collection.CreatedAt, &collection.UpdatedAt, &deletedAt); err != nil {
+			return fmt.Errorf("failed to scan collection: %w", err)
+		}
+		if collection.Options, err = decodeCollectionOptions(options); err != nil {
+			return err
+		}
+		if deletedAt.Valid {
+			collection.DeletedAt = &deletedAt.Time
+		}
+		if err := fn(&models.SnapshotEntry{Collection: &collection}); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over collections: %w", err)
+	}
+	rows.Close()
+
+	// Get documents
+	query := `SELECT id, collection_name, data, created_at, updated_at, expires_at, deleted_at, owner, shared_with
+			  FROM documents
+			  ORDER BY collection_name, id`
+	rows, err = tx.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var document models.Document
+		var data []byte
+		var expiresAt, deletedAt sql.NullTime
+		var sharedWith string
+		err := rows.Scan(
+			&document.ID,
+			&document.CollectionName,
+			&data,
+			&document.CreatedAt,
+			&document.UpdatedAt,
+			&expiresAt,
+			&deletedAt,
+			&document.Owner,
+			&sharedWith,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan document: %w", err)
+		}
+		document.Data = json.RawMessage(data)
+		document.ExpiresAt = scannedExpiry(expiresAt)
+		if deletedAt.Valid {
+			document.DeletedAt = &deletedAt.Time
+		}
+		if document.SharedWith, err = decodeSharedWith(sharedWith); err != nil {
+			return err
+		}
+		if err := fn(&models.SnapshotEntry{Document: &document}); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over documents: %w", err)
+	}
+
+	return fn(&models.SnapshotEntry{End: true})
+}
+
+// ApplySnapshot replaces every collection and document with a snapshot of the primary, read
+// through next up to its end, and sets the checkpoint for the source to the position the
+// snapshot reflects, all in one transaction. A snapshot that stops early is not applied.
+// Revision history restarts from the snapshot.
+func (r *ChangeRepository) ApplySnapshot(ctx context.Context, source string, next func() (*models.SnapshotEntry, error)) (checkpoint *models.ChangeCheckpoint, err error) {
+	entry, err := next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if entry.Checkpoint == nil {
+		return nil, fmt.Errorf("snapshot does not start with a checkpoint")
+	}
+	checkpoint = entry.Checkpoint
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Remove collections with their documents and history
+	if _, err = tx.ExecContext(ctx, `DELETE FROM collections`); err != nil {
+		return nil, fmt.Errorf("failed to clear collections: %w", err)
+	}
+
+	// Load the snapshot
+	var withHistory []string
+	for {
+		entry, err = next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("snapshot ended early")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		if entry.End {
+			break
+		}
+
+		switch {
+		case entry.Collection != nil:
+			collection := entry.Collection
+			options, err := encodeCollectionOptions(collection.Options)
+			if err != nil {
+				return nil, err
+			}
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO collections (name, options, created_at, updated_at, deleted_at) VALUES (?, ?, ?, ?, ?)`,
+				collection.Name, options, collection.CreatedAt, collection.UpdatedAt, storedDeletion(collection.DeletedAt),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load collection '%s': %w", collection.Name, err)
+			}
+			if collection.Options.HistoryEnabled() {
+				withHistory = append(withHistory, collection.Name)
+			}
+
+		case entry.Document != nil:
+			document := entry.Document
+			sharedWith, err := encodeSharedWith(document.SharedWith)
+			if err != nil {
+				return nil, err
+			}
+			query := `INSERT INTO documents (id, collection_name, data, created_at, updated_at, expires_at, deleted_at, owner, shared_with)
+					  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			_, err = tx.ExecContext(ctx,
+				query,
+				document.ID,
+				document.CollectionName,
+				document.Data,
+				document.CreatedAt,
+				document.UpdatedAt,
+				storedExpiry(document.ExpiresAt),
+				storedDeletion(document.DeletedAt),
+				document.Owner,
+				sharedWith,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load document '%s' of collection '%s': %w", document.ID, document.CollectionName, err)
+			}
+		}
+	}
+
+	// Start the history of collections that keep one
+	for _, name := range withHistory {
+		if err = seedRevisions(ctx, tx, name); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = saveCheckpoint(ctx, tx, source, checkpoint.Seq, checkpoint.Timestamp); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return checkpoint, nil
+}
+
+// storedDeletion converts a deletion time to the UTC form it is stored and compared in
+func storedDeletion(deletedAt *time.Time) interface{} {
+	if deletedAt == nil {
+		return nil
+	}
+	return deletedAt.UTC()
+}
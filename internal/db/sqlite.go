@@ -1,34 +1,78 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/rbehzadan/flexstore/internal/models"
 )
 
-// DB represents a database connection
+// DB represents a database in WAL mode. The embedded connection is the single writer, which
+// serializes writes; reads go to a pool of read-only connections so they run concurrently
+// with each other and with the writer.
 type DB struct {
 	*sql.DB
+	reader *sql.DB
+	config *Config
 }
 
 // Config holds database configuration
 type Config struct {
 	Path string
+
+	// ReadConnections is the size of the pool of read-only connections
+	ReadConnections int
+
+	// BusyTimeout is how long a connection waits for a lock held by another process
+	BusyTimeout time.Duration
+
+	// Synchronous is the synchronous pragma of the writer: OFF, NORMAL, FULL or EXTRA
+	Synchronous string
+
+	// SoftDelete moves deleted collections and documents to the trash instead of removing them
+	SoftDelete bool
+
+	// Limits bound the size and shape of documents; collection options can only tighten them
+	Limits models.DocumentLimits
 }
 
 // NewConfig creates a default database configuration
 func NewConfig(sqlitePath string) *Config {
 	return &Config{
-		Path: sqlitePath,
+		Path:            sqlitePath,
+		ReadConnections: 4,
+		BusyTimeout:     5 * time.Second,
+		Synchronous:     "NORMAL",
 	}
 }
 
-// New creates a new database connection
+// New opens the database and migrates its schema to the latest version
 func New(config *Config) (*DB, error) {
+	db, err := Open(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.Migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	slog.Info("Database schema is up to date", "version", LatestSchemaVersion())
+
+	return db, nil
+}
+
+// Open opens the database without migrating its schema, for inspecting and migrating it
+func Open(config *Config) (*DB, error) {
 	// Create directory if it doesn't exist
 	dir := filepath.Dir(config.Path)
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
@@ -37,74 +81,96 @@ func New(config *Config) (*DB, error) {
 		}
 	}
 
-	// Open database connection
-	sqlDB, err := sql.Open("sqlite3", config.Path)
+	// Open the writer first, which creates the database and switches it to WAL mode. SQLite
+	// only supports one writer at a time, and immediate transactions take the write lock up
+	// front instead of failing when a read turns into a write.
+	writer, err := sql.Open("sqlite3", config.dsn(url.Values{
+		"_journal_mode": {"WAL"},
+		"_synchronous":  {config.Synchronous},
+		"_txlock":       {"immediate"},
+	}))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	writer.SetMaxOpenConns(1)
+	if err := writer.Ping(); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
 
-	// Set connection parameters
-	sqlDB.SetMaxOpenConns(1) // SQLite only supports one writer at a time
-
-	// Test connection
-	if err := sqlDB.Ping(); err != nil {
-		sqlDB.Close()
+	reader, err := sql.Open("sqlite3", config.dsn(url.Values{"mode": {"ro"}}))
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	reader.SetMaxOpenConns(max(config.ReadConnections, 1))
+	reader.SetMaxIdleConns(max(config.ReadConnections, 1))
+	if err := reader.Ping(); err != nil {
+		reader.Close()
+		writer.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	db := &DB{sqlDB}
+	return &DB{DB: writer, reader: reader, config: config}, nil
+}
 
-	// Initialize database schema
-	if err := db.Initialize(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize database: %w", err)
-	}
+// dsn returns the data source name of the database with the pragmas shared by the writer and
+// the readers and the given parameters
+func (c *Config) dsn(params url.Values) string {
+	params.Set("_busy_timeout", strconv.FormatInt(c.BusyTimeout.Milliseconds(), 10))
+	params.Set("_foreign_keys", "on")
 
-	return db, nil
+	// Escape the characters with a meaning in URIs
+	path := strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(c.Path)
+	return "file:" + path + "?" + params.Encode()
 }
 
-// Initialize creates the necessary database tables if they don't exist
-func (db *DB) Initialize() error {
-	// Schema for collections
-	collections := `
-	CREATE TABLE IF NOT EXISTS collections (
-		name TEXT PRIMARY KEY,
-		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);`
-
-	// Schema for documents
-	documents := `
-	CREATE TABLE IF NOT EXISTS documents (
-		id TEXT,
-		collection_name TEXT,
-		data TEXT NOT NULL,
-		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		PRIMARY KEY (id, collection_name),
-		FOREIGN KEY (collection_name) REFERENCES collections(name) ON DELETE CASCADE
-	);`
-
-	// Create collections table
-	if _, err := db.Exec(collections); err != nil {
-		return fmt.Errorf("failed to create collections table: %w", err)
-	}
+// Reader returns the pool of read-only connections
+func (db *DB) Reader() *sql.DB {
+	return db.reader
+}
 
-	// Create documents table
-	if _, err := db.Exec(documents); err != nil {
-		return fmt.Errorf("failed to create documents table: %w", err)
+// Size returns the size of the database files on disk in bytes, including the write-ahead log
+func (db *DB) Size() (int64, error) {
+	var size int64
+	for _, path := range []string{db.config.Path, db.config.Path + "-wal"} {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to get database size: %w", err)
+		}
+		size += info.Size()
 	}
+	return size, nil
+}
 
-	// Enable foreign keys
-	if _, err := db.Exec("PRAGMA foreign_keys = ON;"); err != nil {
-		return fmt.Errorf("failed to enable foreign keys: %w", err)
+// ProbeWrite writes a row to check that the database accepts writes, failing if it is
+// locked, read-only or out of space
+func (db *DB) ProbeWrite(ctx context.Context) error {
+	query := `INSERT INTO health_probe (id, checked_at) VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET checked_at = excluded.checked_at`
+	if _, err := db.ExecContext(ctx, query, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to write to database: %w", err)
 	}
+	return nil
+}
 
-	log.Println("Database schema initialized successfully")
+// Checkpoint copies the write-ahead log into the database file and truncates it, so the file
+// is complete on its own
+func (db *DB) Checkpoint() error {
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE);"); err != nil {
+		return fmt.Errorf("failed to checkpoint database: %w", err)
+	}
 	return nil
 }
 
-// Close closes the database connection
+// Close closes the readers and the writer
 func (db *DB) Close() error {
-	return db.DB.Close()
+	readerErr := db.reader.Close()
+	if err := db.DB.Close(); err != nil {
+		return err
+	}
+	return readerErr
 }
@@ -0,0 +1,111 @@
+package db_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// benchmarkDocuments is the number of documents the read benchmarks list from
+const benchmarkDocuments = 500
+
+// newBenchmarkRepository opens a database with readConnections read-only connections and
+// fills a collection with documents
+func newBenchmarkRepository(b *testing.B, readConnections int) *db.DocumentRepository {
+	b.Helper()
+
+	config := db.NewConfig(filepath.Join(b.TempDir(), "db.sqlite"))
+	config.ReadConnections = readConnections
+	database, err := db.New(config)
+	if err != nil {
+		b.Fatalf("failed to open database: %v", err)
+	}
+	b.Cleanup(func() { database.Close() })
+
+	documents := db.NewDocumentRepository(database, db.NewCollectionRepository(database))
+	for i := range benchmarkDocuments {
+		data := json.RawMessage(fmt.Sprintf(`{"n":%d,"title":"document %d"}`, i, i))
+		if _, err := documents.Create(b.Context(), "bench", data, nil, "", models.Actor{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return documents
+}
+
+// BenchmarkConcurrentReads lists documents from parallel goroutines. A single read connection
+// serializes the reads as the store did before it had a pool of readers.
+func BenchmarkConcurrentReads(b *testing.B) {
+	for _, readConnections := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("readers=%d", readConnections), func(b *testing.B) {
+			documents := newBenchmarkRepository(b, readConnections)
+			query := models.NewDocumentQuery()
+			query.Limit = 100
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := documents.List(b.Context(), "bench", query); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkReadsDuringWrites lists documents from parallel goroutines while another goroutine
+// keeps updating documents. Before WAL mode and the pool of readers, every read waited for
+// the writes ahead of it.
+func BenchmarkReadsDuringWrites(b *testing.B) {
+	for _, readConnections := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("readers=%d", readConnections), func(b *testing.B) {
+			documents := newBenchmarkRepository(b, readConnections)
+			doc, err := documents.Create(b.Context(), "bench", json.RawMessage(`{"n":0}`), nil, "", models.Actor{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			query := models.NewDocumentQuery()
+			query.Limit = 100
+
+			// Write until the reads are done
+			done := make(chan struct{})
+			var writer sync.WaitGroup
+			writer.Add(1)
+			go func() {
+				defer writer.Done()
+				for n := 1; ; n++ {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					data := json.RawMessage(fmt.Sprintf(`{"n":%d}`, n))
+					if _, err := documents.Update(b.Context(), doc.ID, "bench", data, nil, nil, models.Actor{}); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			}()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := documents.List(b.Context(), "bench", query); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+
+			close(done)
+			writer.Wait()
+		})
+	}
+}
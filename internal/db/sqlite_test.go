@@ -0,0 +1,43 @@
+package db_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+)
+
+func TestOpenUsesWALAndReadOnlyReaders(t *testing.T) {
+	// Characters with a meaning in URIs are part of the path
+	path := filepath.Join(t.TempDir(), "data?#%", "db.sqlite")
+	database, err := db.New(db.NewConfig(path))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	var mode string
+	if err := database.Reader().QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Errorf("wrong journal mode: got %v want %v", mode, "wal")
+	}
+
+	var foreignKeys int
+	if err := database.Reader().QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		t.Fatal(err)
+	}
+	if foreignKeys != 1 {
+		t.Errorf("foreign keys are not enforced on readers")
+	}
+
+	_, err = database.Reader().Exec("INSERT INTO collections (name) VALUES ('notes')")
+	if err == nil || !strings.Contains(err.Error(), "readonly") {
+		t.Errorf("reader accepted a write: got %v", err)
+	}
+	if _, err := database.Exec("INSERT INTO collections (name) VALUES ('notes')"); err != nil {
+		t.Errorf("writer rejected a write: %v", err)
+	}
+}
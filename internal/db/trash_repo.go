@@ -0,0 +1,518 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// trashCollection moves a collection and its live documents to the trash.
+// Documents share the collection's deletion timestamp so they can be restored together.
+func trashCollection(ctx context.Context, tx *sql.Tx, name string, now time.Time) error {
+	deletedAt := now.UTC()
+
+	_, err := tx.ExecContext(ctx, `UPDATE collections SET deleted_at = ? WHERE name = ?`, deletedAt, name)
+	if err != nil {
+		return fmt.Errorf("failed to move collection to trash: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`UPDATE documents SET deleted_at = ? WHERE collection_name = ? AND deleted_at IS NULL`,
+		deletedAt, name,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move documents to trash: %w", err)
+	}
+
+	return nil
+}
+
+// restoreCollection brings a trashed collection back together with the documents trashed with it
+func restoreCollection(ctx context.Context, tx *sql.Tx, name string, now time.Time) error {
+	query := `UPDATE documents SET deleted_at = NULL
+			  WHERE collection_name = ?
+			  AND deleted_at = (SELECT deleted_at FROM collections WHERE name = ?)`
+	if _, err := tx.ExecContext(ctx, query, name, name); err != nil {
+		return fmt.Errorf("failed to restore documents: %w", err)
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE collections SET deleted_at = NULL, updated_at = ? WHERE name = ?`, now, name)
+	if err != nil {
+		return fmt.Errorf("failed to restore collection: %w", err)
+	}
+
+	return nil
+}
+
+// purgeCollection permanently deletes a collection and, through the foreign key, its documents
+func purgeCollection(ctx context.Context, tx *sql.Tx, name string, actor models.Actor) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM collections WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+
+	return recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeCollectionDelete,
+		CollectionName: name,
+	}, actor)
+}
+
+// purgeDocument permanently deletes a document
+func purgeDocument(ctx context.Context, tx *sql.Tx, id, collectionName string, actor models.Actor) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM documents WHERE id = ? AND collection_name = ?`, id, collectionName)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	return recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeDocumentDelete,
+		CollectionName: collectionName,
+		DocumentID:     id,
+	}, actor)
+}
+
+// TrashRepository handles soft-deleted collections and documents
+type TrashRepository struct {
+	db *DB
+}
+
+// NewTrashRepository creates a new trash repository
+func NewTrashRepository(db *DB) *TrashRepository {
+	return &TrashRepository{db: db}
+}
+
+// ListCollections retrieves all collections in the trash, most recently deleted first
+func (r *TrashRepository) ListCollections(ctx context.Context) (*models.CollectionList, error) {
+	query := `SELECT name, options, created_at, updated_at, deleted_at
+			  FROM collections
+			  WHERE deleted_at IS NOT NULL
+			  ORDER BY deleted_at DESC`
+	rows, err := r.db.reader.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed collections: %w", err)
+	}
+	defer rows.Close()
+
+	collections := make([]models.Collection, 0)
+	for rows.Next() {
+		var collection models.Collection
+		var options string
+		var deletedAt time.Time
+		err := rows.Scan(&collection.Name, &options, &collection.CreatedAt, &collection.UpdatedAt, &deletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan collection: %w", err)
+		}
+		if collection.Options, err = decodeCollectionOptions(options); err != nil {
+			return nil, err
+		}
+		collection.DeletedAt = &deletedAt
+		collections = append(collections, collection)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over collections: %w", err)
+	}
+
+	return &models.CollectionList{
+		Total:       len(collections),
+		Collections: collections,
+	}, nil
+}
+
+// ListDocuments retrieves trashed documents of a collection, most recently deleted first
+func (r *TrashRepository) ListDocuments(ctx context.Context, collectionName string, queryParams *models.DocumentQuery) (*models.DocumentList, error) {
+	// Check if collection exists, live or trashed
+	var exists int
+	err := r.db.reader.QueryRowContext(ctx, `SELECT 1 FROM collections WHERE name = ?`, collectionName).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("collection '%s' not found", collectionName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+
+	// Limit the result to the caller's documents in a collection with ownership enabled
+	access, err := restrictAccess(ctx, r.db.reader, collectionName, queryParams.Access)
+	if err != nil {
+		return nil, err
+	}
+	ownership, ownershipArgs := accessFilter("documents", access)
+	args := append([]interface{}{collectionName}, ownershipArgs...)
+
+	// Get total count
+	countQuery := `SELECT COUNT(*) FROM documents WHERE collection_name = ? AND deleted_at IS NOT NULL` + ownership
+	var total int
+	if err := r.db.reader.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count documents: %w", err)
+	}
+
+	// Get documents with pagination
+	query := `SELECT id, collection_name, data, created_at, updated_at, deleted_at, owner, shared_with
+			  FROM documents
+			  WHERE collection_name = ? AND deleted_at IS NOT NULL` + ownership + `
+			  ORDER BY deleted_at DESC
+			  LIMIT ? OFFSET ?`
+	rows, err := r.db.reader.QueryContext(ctx, query, append(args, queryParams.Limit, queryParams.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := make([]models.Document, 0)
+	for rows.Next() {
+		var document models.Document
+		var dataBytes []byte
+		var deletedAt time.Time
+		var sharedWith string
+		err := rows.Scan(
+			&document.ID,
+			&document.CollectionName,
+			&dataBytes,
+			&document.CreatedAt,
+			&document.UpdatedAt,
+			&deletedAt,
+			&document.Owner,
+			&sharedWith,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		document.Data = json.RawMessage(dataBytes)
+		document.DeletedAt = &deletedAt
+		if document.SharedWith, err = decodeSharedWith(sharedWith); err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over documents: %w", err)
+	}
+
+	return &models.DocumentList{
+		Total:     total,
+		Offset:    queryParams.Offset,
+		Limit:     queryParams.Limit,
+		Documents: documents,
+	}, nil
+}
+
+// RestrictAccess returns the access to enforce in a collection, or nil when it is unrestricted
+func (r *TrashRepository) RestrictAccess(ctx context.Context, collectionName string, access *models.DocumentAccess) (*models.DocumentAccess, error) {
+	return restrictAccess(ctx, r.db.reader, collectionName, access)
+}
+
+// GetOwnership retrieves the owner and sharing of a trashed document
+func (r *TrashRepository) GetOwnership(ctx context.Context, id, collectionName string) (*models.Document, error) {
+	if err := r.documentInTrash(ctx, id, collectionName); err != nil {
+		return nil, err
+	}
+	return documentOwnership(ctx, r.db.reader, id, collectionName)
+}
+
+// collectionInTrash checks that a collection is in the trash
+func (r *TrashRepository) collectionInTrash(ctx context.Context, name string) error {
+	var trashed int
+	err := r.db.reader.QueryRowContext(ctx, `SELECT 1 FROM collections WHERE name = ? AND deleted_at IS NOT NULL`, name).Scan(&trashed)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("collection '%s' not found in trash", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check if collection is in the trash: %w", err)
+	}
+	return nil
+}
+
+// documentInTrash checks that a document is in the trash
+func (r *TrashRepository) documentInTrash(ctx context.Context, id, collectionName string) error {
+	var trashed int
+	query := `SELECT 1 FROM documents WHERE id = ? AND collection_name = ? AND deleted_at IS NOT NULL`
+	err := r.db.reader.QueryRowContext(ctx, query, id, collectionName).Scan(&trashed)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("document with ID '%s' not found in trash of collection '%s'", id, collectionName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check if document is in the trash: %w", err)
+	}
+	return nil
+}
+
+// RestoreCollection restores a trashed collection and the documents deleted with it
+func (r *TrashRepository) RestoreCollection(ctx context.Context, name string, actor models.Actor) (err error) {
+	if err := r.collectionInTrash(ctx, name); err != nil {
+		return err
+	}
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Restore collection
+	now := time.Now()
+	if err = restoreCollection(ctx, tx, name, now); err != nil {
+		return err
+	}
+
+	// Record change
+	err = recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeCollectionRestore,
+		CollectionName: name,
+		Timestamp:      now,
+	}, actor)
+	if err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreDocument restores a trashed document into its collection
+func (r *TrashRepository) RestoreDocument(ctx context.Context, id, collectionName string, actor models.Actor) (document *models.Document, err error) {
+	if err := r.documentInTrash(ctx, id, collectionName); err != nil {
+		return nil, err
+	}
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// The collection itself must not be in the trash
+	options, err := getCollectionOptions(ctx, tx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the trashed document
+	document = &models.Document{}
+	var dataBytes []byte
+	var expiresAt sql.NullTime
+	var sharedWith string
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, collection_name, data, created_at, expires_at, owner, shared_with FROM documents WHERE id = ? AND collection_name = ?`,
+		id, collectionName,
+	).Scan(&document.ID, &document.CollectionName, &dataBytes, &document.CreatedAt, &expiresAt, &document.Owner, &sharedWith)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	document.Data = json.RawMessage(dataBytes)
+	document.ExpiresAt = scannedExpiry(expiresAt)
+	if document.SharedWith, err = decodeSharedWith(sharedWith); err != nil {
+		return nil, err
+	}
+	if err = checkCapSize(options.Cap, document.Data); err != nil {
+		return nil, err
+	}
+	document.UpdatedAt = time.Now()
+
+	// Record revision
+	err = recordRevision(ctx, tx, options.History, collectionName, id, models.RevisionRestore, document.Data, actor.Name, document.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore document
+	_, err = tx.ExecContext(ctx,
+		`UPDATE documents SET deleted_at = NULL, updated_at = ? WHERE id = ? AND collection_name = ?`,
+		document.UpdatedAt, id, collectionName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore document: %w", err)
+	}
+
+	// The restored document counts against the collection's quota again
+	if err = checkQuota(ctx, tx, collectionName, options.Quota, document.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Record change
+	err = recordChange(ctx, tx, &models.Change{
+		Operation:      models.ChangeDocumentPut,
+		CollectionName: collectionName,
+		DocumentID:     id,
+		Document:       document,
+		Timestamp:      document.UpdatedAt,
+	}, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Evict the oldest documents of a capped collection
+	if _, err = evictOverflow(ctx, tx, collectionName, options, id, document.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	// Update collection timestamp
+	_, err = tx.ExecContext(ctx, `UPDATE collections SET updated_at = ? WHERE name = ?`, document.UpdatedAt, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update collection timestamp: %w", err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return document, nil
+}
+
+// PurgeCollection permanently deletes a trashed collection and its documents
+func (r *TrashRepository) PurgeCollection(ctx context.Context, name string, actor models.Actor) (err error) {
+	if err := r.collectionInTrash(ctx, name); err != nil {
+		return err
+	}
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = purgeCollection(ctx, tx, name, actor); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeDocument permanently deletes a trashed document
+func (r *TrashRepository) PurgeDocument(ctx context.Context, id, collectionName string, actor models.Actor) (err error) {
+	if err := r.documentInTrash(ctx, id, collectionName); err != nil {
+		return err
+	}
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = purgeDocument(ctx, tx, id, collectionName, actor); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeExpired permanently deletes collections and documents trashed before the cutoff
+// and returns the number of collections and documents removed
+func (r *TrashRepository) PurgeExpired(ctx context.Context, cutoff time.Time) (collections int, documents int, err error) {
+	before := cutoff.UTC()
+
+	// Find expired collections
+	expiredCollections, err := r.queryKeys(ctx,
+		`SELECT name, '' FROM collections WHERE deleted_at IS NOT NULL AND deleted_at < ?`, before,
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Find expired documents of collections that are not purged as a whole
+	expiredDocuments, err := r.queryKeys(ctx,
+		`SELECT d.collection_name, d.id FROM documents d
+		 JOIN collections c ON c.name = d.collection_name
+		 WHERE d.deleted_at IS NOT NULL AND d.deleted_at < ?
+		 AND NOT (c.deleted_at IS NOT NULL AND c.deleted_at < ?)`, before, before,
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(expiredCollections) == 0 && len(expiredDocuments) == 0 {
+		return 0, 0, nil
+	}
+
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	for _, key := range expiredCollections {
+		if err = purgeCollection(ctx, tx, key[0], systemActor); err != nil {
+			return 0, 0, err
+		}
+	}
+	for _, key := range expiredDocuments {
+		if err = purgeDocument(ctx, tx, key[1], key[0], systemActor); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(expiredCollections), len(expiredDocuments), nil
+}
+
+// queryKeys runs a query returning pairs of strings
+func (r *TrashRepository) queryKeys(ctx context.Context, query string, args ...interface{}) ([][2]string, error) {
+	rows, err := r.db.reader.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired trash: %w", err)
+	}
+	defer rows.Close()
+
+	var keys [][2]string
+	for rows.Next() {
+		var key [2]string
+		if err := rows.Scan(&key[0], &key[1]); err != nil {
+			return nil, fmt.Errorf("failed to scan expired trash: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over expired trash: %w", err)
+	}
+
+	return keys, nil
+}
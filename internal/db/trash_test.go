@@ -0,0 +1,212 @@
+package db_test
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// newSoftDeleteRepositories opens a fresh database with soft delete enabled
+func newSoftDeleteRepositories(t *testing.T) (*db.CollectionRepository, *db.DocumentRepository, *db.TrashRepository) {
+	t.Helper()
+
+	dbConfig := db.NewConfig(filepath.Join(t.TempDir(), "db.sqlite"))
+	dbConfig.SoftDelete = true
+	database, err := db.New(dbConfig)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	collectionRepo := db.NewCollectionRepository(database)
+	return collectionRepo, db.NewDocumentRepository(database, collectionRepo), db.NewTrashRepository(database)
+}
+
+func TestTrashDocument(t *testing.T) {
+	_, documents, trash := newSoftDeleteRepositories(t)
+
+	doc, err := documents.Create(t.Context(), "notes", json.RawMessage(`{"v":1}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := documents.Delete(t.Context(), doc.ID, "notes", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Trashed documents are hidden from normal reads
+	if _, err := documents.GetByID(t.Context(), doc.ID, "notes"); err == nil {
+		t.Errorf("trashed document is still readable")
+	}
+
+	list, err := trash.ListDocuments(t.Context(), "notes", models.NewDocumentQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Documents[0].ID != doc.ID || list.Documents[0].DeletedAt == nil {
+		t.Fatalf("wrong trash contents: %+v", list)
+	}
+
+	restored, err := trash.RestoreDocument(t.Context(), doc.ID, "notes", models.Actor{Name: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(restored.Data) != `{"v":1}` {
+		t.Errorf("wrong restored data: got %s want %s", restored.Data, `{"v":1}`)
+	}
+	if _, err := documents.GetByID(t.Context(), doc.ID, "notes"); err != nil {
+		t.Errorf("restored document not found: %v", err)
+	}
+
+	// Purging removes the document for good
+	if err := documents.Delete(t.Context(), doc.ID, "notes", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := trash.PurgeDocument(t.Context(), doc.ID, "notes", models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := trash.RestoreDocument(t.Context(), doc.ID, "notes", models.Actor{Name: "bob"}); err == nil {
+		t.Errorf("purged document could be restored")
+	}
+}
+
+func TestTrashCollection(t *testing.T) {
+	collections, documents, trash := newSoftDeleteRepositories(t)
+
+	// A document deleted before the collection stays in the trash on restore
+	kept, err := documents.Create(t.Context(), "orders", json.RawMessage(`{"n":1}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted, err := documents.Create(t.Context(), "orders", json.RawMessage(`{"n":2}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := documents.Delete(t.Context(), deleted.ID, "orders", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+
+	if err := collections.Delete(t.Context(), "orders", models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := collections.Exists(t.Context(), "orders"); exists {
+		t.Errorf("trashed collection still exists")
+	}
+	if _, err := collections.Create(t.Context(), "orders", models.Actor{Name: "admin"}); err == nil {
+		t.Errorf("created a collection with the name of a trashed one")
+	}
+
+	list, err := trash.ListCollections(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Collections[0].Name != "orders" {
+		t.Fatalf("wrong trash contents: %+v", list)
+	}
+
+	if err := trash.RestoreCollection(t.Context(), "orders", models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.GetByID(t.Context(), kept.ID, "orders"); err != nil {
+		t.Errorf("document not restored with its collection: %v", err)
+	}
+	if _, err := documents.GetByID(t.Context(), deleted.ID, "orders"); err == nil {
+		t.Errorf("document deleted on its own was restored with the collection")
+	}
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	collections, documents, trash := newSoftDeleteRepositories(t)
+
+	doc, err := documents.Create(t.Context(), "events", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := documents.Delete(t.Context(), doc.ID, "events", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := documents.Create(t.Context(), "logs", json.RawMessage(`{}`), nil, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := collections.Delete(t.Context(), "logs", models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is older than the cutoff yet
+	c, d, err := trash.PurgeExpired(t.Context(), time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != 0 || d != 0 {
+		t.Errorf("purged too early: got %v/%v want %v/%v", c, d, 0, 0)
+	}
+
+	c, d, err = trash.PurgeExpired(t.Context(), time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != 1 || d != 1 {
+		t.Errorf("wrong purge counts: got %v/%v want %v/%v", c, d, 1, 1)
+	}
+
+	list, err := trash.ListCollections(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 0 {
+		t.Errorf("trash not empty after purge: %+v", list)
+	}
+}
+
+func TestCreateWithTrashedOrExpiredID(t *testing.T) {
+	collections, documents, _ := newSoftDeleteRepositories(t)
+
+	options := models.CollectionOptions{History: &models.HistoryOptions{Enabled: true}}
+	if _, err := collections.CreateWithOptions(t.Context(), "notes", options, models.Actor{Name: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The ID of a trashed document stays taken
+	if _, err := documents.CreateWithID(t.Context(), "trashed", "notes", json.RawMessage(`{"v":1}`), nil, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := documents.Delete(t.Context(), "trashed", "notes", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := documents.CreateWithID(t.Context(), "trashed", "notes", json.RawMessage(`{"v":2}`), nil, "", models.Actor{Name: "bob"})
+	if !errors.Is(err, models.ErrDocumentInTrash) {
+		t.Errorf("wrong error: got %v want %v", err, models.ErrDocumentInTrash)
+	}
+
+	// An expired document is deleted on the record before its ID is reused
+	expiresAt := time.Now().Add(10 * time.Millisecond)
+	if _, err := documents.CreateWithID(t.Context(), "expired", "notes", json.RawMessage(`{"v":1}`), &expiresAt, "", models.Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, err := documents.CreateWithID(t.Context(), "expired", "notes", json.RawMessage(`{"v":2}`), nil, "", models.Actor{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := documents.ListRevisions(t.Context(), "expired", "notes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOps := []models.RevisionOperation{models.RevisionCreate, models.RevisionDelete, models.RevisionCreate}
+	if list.Total != len(wantOps) {
+		t.Fatalf("wrong number of revisions: got %v want %v", list.Total, len(wantOps))
+	}
+	for i, op := range wantOps {
+		if got := list.Revisions[i].Operation; got != op {
+			t.Errorf("revision %d: got %v want %v", i+1, got, op)
+		}
+	}
+	if author := list.Revisions[1].Author; author != "system" {
+		t.Errorf("wrong author of the deletion: got %v want %v", author, "system")
+	}
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// UserRepository handles user account operations
+type UserRepository struct {
+	db *DB
+}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// userColumns lists the columns read into a models.User
+const userColumns = `username, created_at, updated_at, disabled_at`
+
+// Create stores a new user with a hashed password and assigns it roles under subject, all in
+// a single transaction, so the user never exists without its roles
+func (r *UserRepository) Create(ctx context.Context, user *models.User, passwordHash, subject string, roles []string) (err error) {
+	// Begin transaction
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	query := `INSERT INTO users (username, password_hash, created_at, updated_at) VALUES (?, ?, ?, ?)`
+	_, err = tx.ExecContext(ctx, query, user.Username, passwordHash, user.CreatedAt, user.UpdatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return fmt.Errorf("user '%s' already exists", user.Username)
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	// Assign roles
+	for _, role := range roles {
+		// Built-in roles always exist; stored ones must not have been deleted
+		if _, ok := models.BuiltinRoles[role]; !ok {
+			var exists int
+			err = tx.QueryRowContext(ctx, `SELECT 1 FROM roles WHERE name = ?`, role).Scan(&exists)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("role '%s' not found", role)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get role: %w", err)
+			}
+		}
+
+		query := `INSERT OR IGNORE INTO role_assignments (role, subject, created_at) VALUES (?, ?, ?)`
+		if _, err = tx.ExecContext(ctx, query, role, subject, user.CreatedAt); err != nil {
+			return fmt.Errorf("failed to assign role: %w", err)
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetByUsername retrieves a user by username
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	row := r.db.reader.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE username = ?`, username)
+
+	user, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user '%s' not found", username)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetCredentials retrieves a user together with its password hash
+func (r *UserRepository) GetCredentials(ctx context.Context, username string) (*models.User, string, error) {
+	row := r.db.reader.QueryRowContext(ctx, `SELECT password_hash, `+userColumns+` FROM users WHERE username = ?`, username)
+
+	var passwordHash string
+	var user models.User
+	var disabledAt sql.NullTime
+	err := row.Scan(&passwordHash, &user.Username, &user.CreatedAt, &user.UpdatedAt, &disabledAt)
+	if err == sql.ErrNoRows {
+		return nil, "", fmt.Errorf("user '%s' not found", username)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get user: %w", err)
+	}
+	setDisabled(&user, disabledAt)
+
+	return &user, passwordHash, nil
+}
+
+// List retrieves all users ordered by username
+func (r *UserRepository) List(ctx context.Context) ([]models.User, error) {
+	rows, err := r.db.reader.QueryContext(ctx, `SELECT `+userColumns+` FROM users ORDER BY username`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]models.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over users: %w", err)
+	}
+
+	return users, nil
+}
+
+// Count returns the number of users
+func (r *UserRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := r.db.reader.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// SetPassword replaces the password hash of a user
+func (r *UserRepository) SetPassword(ctx context.Context, username, passwordHash string) (*models.User, error) {
+	query := `UPDATE users SET password_hash = ?, updated_at = ? WHERE username = ?`
+	return r.update(ctx, username, query, passwordHash, time.Now(), username)
+}
+
+// SetDisabled disables or re-enables a user
+func (r *UserRepository) SetDisabled(ctx context.Context, username string, disabled bool) (*models.User, error) {
+	now := time.Now()
+	var disabledAt interface{}
+	if disabled {
+		disabledAt = now.UTC()
+	}
+	query := `UPDATE users SET disabled_at = ?, updated_at = ? WHERE username = ?`
+	return r.update(ctx, username, query, disabledAt, now, username)
+}
+
+// update runs an update of a single user and returns the updated user
+func (r *UserRepository) update(ctx context.Context, username, query string, args ...interface{}) (*models.User, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, fmt.Errorf("user '%s' not found", username)
+	}
+
+	return r.GetByUsername(ctx, username)
+}
+
+// scanUser reads a user from a query result
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var disabledAt sql.NullTime
+	err := row.Scan(&user.Username, &user.CreatedAt, &user.UpdatedAt, &disabledAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user: %w", err)
+	}
+	setDisabled(&user, disabledAt)
+
+	return &user, nil
+}
+
+// setDisabled fills in the disabled state of a scanned user
+func setDisabled(user *models.User, disabledAt sql.NullTime) {
+	if disabledAt.Valid {
+		user.Disabled = true
+		user.DisabledAt = &disabledAt.Time
+	}
+}
@@ -0,0 +1,49 @@
+package db_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestCreateUserWithRoles(t *testing.T) {
+	database, err := db.New(db.NewConfig(filepath.Join(t.TempDir(), "db.sqlite")))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer database.Close()
+
+	users := db.NewUserRepository(database)
+	roles := db.NewRoleRepository(database)
+	now := time.Now()
+	if err := roles.Create(t.Context(), &models.Role{Name: "reader", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing role leaves neither the user nor its other roles behind
+	alice := &models.User{Username: "alice", CreatedAt: now, UpdatedAt: now}
+	if err := users.Create(t.Context(), alice, "hash", "user:alice", []string{"reader", "missing"}); err == nil {
+		t.Fatal("expected a missing role to be rejected")
+	}
+	if _, err := users.GetByUsername(t.Context(), "alice"); err == nil {
+		t.Errorf("user created despite the missing role")
+	}
+	if assigned, err := roles.RolesOf(t.Context(), "user:alice"); err != nil || len(assigned) != 0 {
+		t.Errorf("wrong roles after rejected create: got %v (%v) want none", assigned, err)
+	}
+
+	if err := users.Create(t.Context(), alice, "hash", "user:alice", []string{"reader", "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	assigned, err := roles.RolesOf(t.Context(), "user:alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"admin", "reader"}; !slices.Equal(assigned, want) {
+		t.Errorf("wrong roles: got %v want %v", assigned, want)
+	}
+}
@@ -0,0 +1,29 @@
+package health
+
+import (
+	"context"
+	"fmt"
+)
+
+// DiskSpaceDetails reports the free space on the disk holding a path
+type DiskSpaceDetails struct {
+	Path         string `json:"path"`
+	FreeBytes    int64  `json:"free_bytes"`
+	MinFreeBytes int64  `json:"min_free_bytes"`
+}
+
+// DiskSpace checks that the disk holding path has at least minFree bytes available
+func DiskSpace(path string, minFree int64) Check {
+	return func(ctx context.Context) (any, error) {
+		free, err := freeBytes(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get free disk space: %w", err)
+		}
+
+		details := DiskSpaceDetails{Path: path, FreeBytes: free, MinFreeBytes: minFree}
+		if free < minFree {
+			return details, fmt.Errorf("%d bytes free, below the minimum of %d", free, minFree)
+		}
+		return details, nil
+	}
+}
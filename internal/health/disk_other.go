@@ -0,0 +1,12 @@
+//go:build !unix
+
+package health
+
+import (
+	"errors"
+)
+
+// freeBytes is not supported on this platform
+func freeBytes(path string) (int64, error) {
+	return 0, errors.New("not supported on this platform")
+}
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// Check inspects one dependency. It returns details worth reporting and an error if the
+// dependency is unhealthy; errors made with Degraded only degrade readiness.
+type Check func(ctx context.Context) (details any, err error)
+
+// degradedError marks a problem that is reported without failing readiness
+type degradedError struct {
+	err error
+}
+
+func (e *degradedError) Error() string { return e.err.Error() }
+func (e *degradedError) Unwrap() error { return e.err }
+
+// Degraded marks err as a problem that should be reported without taking the instance out
+// of rotation
+func Degraded(err error) error {
+	return &degradedError{err: err}
+}
+
+// namedCheck is a check registered with a checker
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs readiness checks concurrently, each with a timeout
+type Checker struct {
+	timeout time.Duration
+	checks  []namedCheck
+}
+
+// NewChecker creates a checker that gives every check timeout to complete
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check; checks are reported in the order they are added
+func (c *Checker) Add(name string, check Check) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Run runs every check and reports the results. A check that does not finish within the
+// timeout fails, even if it keeps running in the background.
+func (c *Checker) Run(ctx context.Context) models.ReadinessReport {
+	report := models.ReadinessReport{
+		Status: models.HealthOK,
+		Checks: make([]models.HealthCheckResult, len(c.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, nc)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		switch result.Status {
+		case models.HealthFailed:
+			report.Status = models.HealthFailed
+		case models.HealthDegraded:
+			if report.Status == models.HealthOK {
+				report.Status = models.HealthDegraded
+			}
+		}
+	}
+	return report
+}
+
+// checkOutcome is what a check returned
+type checkOutcome struct {
+	details any
+	err     error
+}
+
+// run runs one check with the timeout and measures its latency
+func (c *Checker) run(ctx context.Context, nc namedCheck) models.HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	// Run the check apart so a check that ignores the context cannot hold up the report
+	start := time.Now()
+	done := make(chan checkOutcome, 1)
+	go func() {
+		details, err := nc.check(ctx)
+		done <- checkOutcome{details: details, err: err}
+	}()
+
+	var outcome checkOutcome
+	select {
+	case outcome = <-done:
+	case <-ctx.Done():
+		outcome.err = fmt.Errorf("timed out after %s", c.timeout)
+	}
+
+	result := models.HealthCheckResult{
+		Name:      nc.name,
+		Status:    models.HealthOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Details:   outcome.details,
+	}
+	if outcome.err != nil {
+		result.Error = outcome.err.Error()
+		result.Status = models.HealthFailed
+		var degraded *degradedError
+		if errors.As(outcome.err, &degraded) {
+			result.Status = models.HealthDegraded
+		}
+	}
+	return result
+}
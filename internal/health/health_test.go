@@ -0,0 +1,81 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/health"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestChecker(t *testing.T) {
+	ok := func(ctx context.Context) (any, error) { return nil, nil }
+	degraded := func(ctx context.Context) (any, error) { return nil, health.Degraded(errors.New("last run failed")) }
+	failed := func(ctx context.Context) (any, error) { return nil, errors.New("database is locked") }
+	hung := func(ctx context.Context) (any, error) {
+		time.Sleep(time.Second)
+		return nil, nil
+	}
+
+	tests := []struct {
+		name   string
+		checks []health.Check
+		want   string
+		wants  []string
+	}{
+		{"all ok", []health.Check{ok, ok}, models.HealthOK, []string{models.HealthOK, models.HealthOK}},
+		{"degraded", []health.Check{ok, degraded}, models.HealthDegraded, []string{models.HealthOK, models.HealthDegraded}},
+		{"failed", []health.Check{failed, degraded}, models.HealthFailed, []string{models.HealthFailed, models.HealthDegraded}},
+		{"timed out", []health.Check{hung, ok}, models.HealthFailed, []string{models.HealthFailed, models.HealthOK}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := health.NewChecker(50 * time.Millisecond)
+			for i, check := range tt.checks {
+				checker.Add(fmt.Sprintf("check%d", i), check)
+			}
+
+			start := time.Now()
+			report := checker.Run(context.Background())
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("checks took %v, longer than the timeout allows", elapsed)
+			}
+
+			if report.Status != tt.want {
+				t.Errorf("got status %q want %q", report.Status, tt.want)
+			}
+			if report.Ready() != (tt.want != models.HealthFailed) {
+				t.Errorf("got ready %v for status %q", report.Ready(), report.Status)
+			}
+			for i, result := range report.Checks {
+				if result.Status != tt.wants[i] {
+					t.Errorf("check %d: got status %q want %q", i, result.Status, tt.wants[i])
+				}
+				if (result.Error != "") != (tt.wants[i] != models.HealthOK) {
+					t.Errorf("check %d: got error %q for status %q", i, result.Error, result.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	details, err := health.DiskSpace(dir, 1)(context.Background())
+	if err != nil {
+		t.Fatalf("got error %v with 1 byte required", err)
+	}
+	free := details.(health.DiskSpaceDetails).FreeBytes
+	if free <= 0 {
+		t.Fatalf("got %d free bytes", free)
+	}
+
+	if _, err := health.DiskSpace(dir, free*1000)(context.Background()); err == nil {
+		t.Error("got no error with more space required than available")
+	}
+}
@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// Periodic runs a task at a fixed interval in a background goroutine
+type Periodic struct {
+	name     string
+	interval time.Duration
+	task     func(context.Context) error
+
+	mu     sync.RWMutex
+	status models.JobStatus
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewPeriodic creates a job that runs task every interval once started
+func NewPeriodic(name string, interval time.Duration, task func(context.Context) error) *Periodic {
+	return &Periodic{
+		name:     name,
+		interval: interval,
+		task:     task,
+		status:   models.JobStatus{Name: name},
+	}
+}
+
+// Name returns the name of the job
+func (p *Periodic) Name() string {
+	return p.name
+}
+
+// Start launches the background loop. Tasks run under ctx, so canceling it cuts a running
+// task short.
+func (p *Periodic) Start(ctx context.Context) {
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	p.mu.Lock()
+	p.status.Running = true
+	p.mu.Unlock()
+
+	go p.run(ctx)
+}
+
+// Stop signals the loop to exit and waits for a running task to finish
+func (p *Periodic) Stop() {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	p.stop = nil
+
+	p.mu.Lock()
+	p.status.Running = false
+	p.mu.Unlock()
+}
+
+// run executes the task until stopped
+func (p *Periodic) run(ctx context.Context) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.RunOnce(ctx)
+		}
+	}
+}
+
+// RunOnce executes the task immediately and records the outcome
+func (p *Periodic) RunOnce(ctx context.Context) error {
+	err := p.task(ctx)
+	if err != nil {
+		slog.Error("Job failed", "job", p.name, "error", err)
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	p.status.Runs++
+	p.status.LastRunAt = &now
+	p.status.LastError = ""
+	if err != nil {
+		p.status.LastError = err.Error()
+	}
+	p.mu.Unlock()
+
+	return err
+}
+
+// Status returns a snapshot of the job status
+func (p *Periodic) Status() models.JobStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.status
+}
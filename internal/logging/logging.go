@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// RequestIDKey is the attribute records logged during a request carry its ID under
+const RequestIDKey = "request_id"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying a request ID, which records logged with the
+// context are tagged with
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by a context, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// ParseLevel parses a level name: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: use debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// New creates a logger writing records at level and above to w in the given format, tagging
+// records logged with a request context with the request ID
+func New(w io.Writer, format, level string) (*slog.Logger, error) {
+	minLevel, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	options := &slog.HandlerOptions{Level: minLevel}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q: use text or json", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// contextHandler adds the request ID of the context to records
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the request ID, if any, and passes the record on
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		record.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs keeps adding request IDs to records of the derived handler
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps adding request IDs to records of the derived handler
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
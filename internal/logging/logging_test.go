@@ -0,0 +1,65 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/logging"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "json", "warn")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "Below the level")
+	logger.With("component", "db").ErrorContext(ctx, "Query failed", "error", "database is locked")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("got %q, want a single JSON record: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"level":              "ERROR",
+		"msg":                "Query failed",
+		"component":          "db",
+		"error":              "database is locked",
+		logging.RequestIDKey: "req-1",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("got %s %v want %v", key, record[key], value)
+		}
+	}
+
+	// Records logged without a request context carry no request ID
+	buf.Reset()
+	logger.Error("Background job failed")
+	if bytes.Contains(buf.Bytes(), []byte(logging.RequestIDKey)) {
+		t.Errorf("got request ID in %q", buf.String())
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		level  string
+	}{
+		{"unknown format", "xml", "info"},
+		{"unknown level", "text", "verbose"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := logging.New(&bytes.Buffer{}, tt.format, tt.level); err == nil {
+				t.Errorf("got no error for format %q level %q", tt.format, tt.level)
+			}
+		})
+	}
+}
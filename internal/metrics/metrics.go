@@ -0,0 +1,378 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric types of the Prometheus text format
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefaultBuckets are histogram buckets suited to request latencies in seconds
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metric is anything a registry can expose
+type metric interface {
+	write(w io.Writer) error
+}
+
+// Registry holds metrics and writes them in the Prometheus text format
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// register adds a metric to the registry
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the Prometheus text format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the metrics to Prometheus
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Collect everything first so a failing collector does not leave a partial response
+		var buf bytes.Buffer
+		if err := r.Write(&buf); err != nil {
+			slog.ErrorContext(req.Context(), "Failed to collect metrics", "error", err)
+			http.Error(w, "failed to collect metrics", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
+
+// desc describes a metric family
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+// writeHeader writes the HELP and TYPE lines of the metric family
+func (d *desc) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.kind)
+	return err
+}
+
+// writeSample writes one sample of the family
+func (d *desc) writeSample(w io.Writer, suffix string, labelValues []string, extraLabel, extraValue string, value float64) error {
+	var b strings.Builder
+	b.WriteString(d.name)
+	b.WriteString(suffix)
+
+	names := d.labels
+	values := labelValues
+	if extraLabel != "" {
+		names = append(slices.Clone(names), extraLabel)
+		values = append(slices.Clone(values), extraValue)
+	}
+	if len(names) > 0 {
+		b.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, "%s=\"%s\"", name, escapeLabelValue(values[i]))
+		}
+		b.WriteByte('}')
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(formatValue(value))
+	b.WriteByte('\n')
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// checkLabels panics if the number of label values does not match the label names,
+// which is a programming error
+func (d *desc) checkLabels(labelValues []string) {
+	if len(labelValues) != len(d.labels) {
+		panic(fmt.Sprintf("metric %s needs %d label values, got %d", d.name, len(d.labels), len(labelValues)))
+	}
+}
+
+// labelKey joins label values into a map key
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// formatValue formats a sample value
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeHelp escapes a help text
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabelValue escapes a label value
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// CounterVec is a set of counters partitioned by label values
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	series map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec registers a counter with the given label names
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{name: name, help: help, kind: typeCounter, labels: labels},
+		series: make(map[string]*counterSeries),
+	}
+	r.register(c)
+	return c
+}
+
+// Add adds a non-negative value to the counter with the given label values
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	c.checkLabels(labelValues)
+	if value < 0 {
+		panic(fmt.Sprintf("counter %s cannot decrease", c.name))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := labelKey(labelValues)
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{labelValues: slices.Clone(labelValues)}
+		c.series[key] = s
+	}
+	s.value += value
+}
+
+// Inc increments the counter with the given label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.writeHeader(w); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		if err := c.writeSample(w, "", s.labelValues, "", "", s.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	desc
+	value atomic.Int64
+}
+
+// NewGauge registers a gauge without labels
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{desc: desc{name: name, help: help, kind: typeGauge}}
+	r.register(g)
+	return g
+}
+
+// Inc increments the gauge
+func (g *Gauge) Inc() {
+	g.value.Add(1)
+}
+
+// Dec decrements the gauge
+func (g *Gauge) Dec() {
+	g.value.Add(-1)
+}
+
+func (g *Gauge) write(w io.Writer) error {
+	if err := g.writeHeader(w); err != nil {
+		return err
+	}
+	return g.writeSample(w, "", nil, "", "", float64(g.value.Load()))
+}
+
+// HistogramVec is a set of histograms partitioned by label values
+type HistogramVec struct {
+	desc
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	count       uint64
+	sum         float64
+}
+
+// NewHistogramVec registers a histogram with the given upper bucket bounds and label names
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, kind: typeHistogram, labels: labels},
+		buckets: slices.Sorted(slices.Values(buckets)),
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe adds a value to the histogram with the given label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.checkLabels(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := labelKey(labelValues)
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labelValues: slices.Clone(labelValues), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.writeHeader(w); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		for i, bound := range h.buckets {
+			if err := h.writeSample(w, "_bucket", s.labelValues, "le", formatValue(bound), float64(s.counts[i])); err != nil {
+				return err
+			}
+		}
+		if err := h.writeSample(w, "_bucket", s.labelValues, "le", "+Inf", float64(s.count)); err != nil {
+			return err
+		}
+		if err := h.writeSample(w, "_sum", s.labelValues, "", "", s.sum); err != nil {
+			return err
+		}
+		if err := h.writeSample(w, "_count", s.labelValues, "", "", float64(s.count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sample is a value collected when the metrics are scraped
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// funcMetric reads its samples from a function on every scrape
+type funcMetric struct {
+	desc
+	collect func() ([]Sample, error)
+}
+
+// NewGaugeFunc registers a gauge whose samples are read from collect on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, labels []string, collect func() ([]Sample, error)) {
+	r.register(&funcMetric{desc: desc{name: name, help: help, kind: typeGauge, labels: labels}, collect: collect})
+}
+
+// NewCounterFunc registers a counter whose samples are read from collect on every scrape
+func (r *Registry) NewCounterFunc(name, help string, labels []string, collect func() ([]Sample, error)) {
+	r.register(&funcMetric{desc: desc{name: name, help: help, kind: typeCounter, labels: labels}, collect: collect})
+}
+
+func (f *funcMetric) write(w io.Writer) error {
+	samples, err := f.collect()
+	if err != nil {
+		return fmt.Errorf("failed to collect %s: %w", f.name, err)
+	}
+
+	slices.SortFunc(samples, func(a, b Sample) int {
+		return strings.Compare(labelKey(a.LabelValues), labelKey(b.LabelValues))
+	})
+
+	if err := f.writeHeader(w); err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		f.checkLabels(sample.LabelValues)
+		if err := f.writeSample(w, "", sample.LabelValues, "", "", sample.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a map in order, so the output is stable
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,106 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/metrics"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	requests := registry.NewCounterVec("requests_total", "Requests by route.", "route")
+	requests.Inc("/b")
+	requests.Add(2, "/a")
+	requests.Inc("/b")
+
+	inFlight := registry.NewGauge("in_flight", "Requests being served.")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+
+	registry.NewGaugeFunc("documents", "Documents per collection.", []string{"collection"}, func() ([]metrics.Sample, error) {
+		return []metrics.Sample{
+			{LabelValues: []string{"users"}, Value: 3},
+			{LabelValues: []string{`a"b`}, Value: 1},
+		}, nil
+	})
+
+	var b strings.Builder
+	if err := registry.Write(&b); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	want := `# HELP requests_total Requests by route.
+# TYPE requests_total counter
+requests_total{route="/a"} 2
+requests_total{route="/b"} 2
+# HELP in_flight Requests being served.
+# TYPE in_flight gauge
+in_flight 1
+# HELP documents Documents per collection.
+# TYPE documents gauge
+documents{collection="a\"b"} 1
+documents{collection="users"} 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	registry := metrics.NewRegistry()
+	duration := registry.NewHistogramVec("duration_seconds", "Latency.", []float64{1, 0.1}, "route")
+	duration.Observe(0.05, "/a")
+	duration.Observe(0.5, "/a")
+	duration.Observe(5, "/a")
+
+	var b strings.Builder
+	if err := registry.Write(&b); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	want := `# HELP duration_seconds Latency.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/a",le="0.1"} 1
+duration_seconds_bucket{route="/a",le="1"} 2
+duration_seconds_bucket{route="/a",le="+Inf"} 3
+duration_seconds_sum{route="/a"} 5.55
+duration_seconds_count{route="/a"} 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.NewGauge("up", "Whether the server is up.").Inc()
+
+	w := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d want %d", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("got content type %q", got)
+	}
+	if !strings.Contains(w.Body.String(), "up 1\n") {
+		t.Errorf("got body %q", w.Body.String())
+	}
+
+	// A failing collector fails the whole scrape instead of returning partial metrics
+	registry.NewCounterFunc("broken_total", "Always fails.", nil, func() ([]metrics.Sample, error) {
+		return nil, errors.New("database is closed")
+	})
+	w = httptest.NewRecorder()
+	registry.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("got status %d want %d", w.Code, http.StatusInternalServerError)
+	}
+}
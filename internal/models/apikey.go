@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Scopes that can be granted to API keys
+const (
+	ScopeCollectionsRead  = "collections:read"
+	ScopeCollectionsWrite = "collections:write"
+	ScopeDocumentsRead    = "documents:read"
+	ScopeDocumentsWrite   = "documents:write"
+	ScopeMetricsRead      = "metrics:read"
+	ScopeAdmin            = "admin"
+)
+
+// validScopes lists every known scope
+var validScopes = map[string]bool{
+	ScopeCollectionsRead:  true,
+	ScopeCollectionsWrite: true,
+	ScopeDocumentsRead:    true,
+	ScopeDocumentsWrite:   true,
+	ScopeMetricsRead:      true,
+	ScopeAdmin:            true,
+}
+
+// APIKey represents an API key; the secret itself is only stored as a hash
+type APIKey struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Scopes      []string   `json:"scopes"`
+	Collections []string   `json:"collections,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyList represents a list of API keys with metadata
+type APIKeyList struct {
+	Total int      `json:"total"`
+	Keys  []APIKey `json:"keys"`
+}
+
+// IssuedAPIKey is an API key together with its secret, returned once when created or rotated
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// Active reports whether the key can be used at the given time
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || k.ExpiresAt.After(now)
+}
+
+// ValidateScopes checks that every scope is known and at least one is given
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !validScopes[scope] {
+			return fmt.Errorf("unknown scope '%s'", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Actor identifies who made a change and where the request came from
+type Actor struct {
+	// Name is the principal, e.g. a username, or "system" for changes made by the server itself
+	Name      string
+	SourceIP  string
+	RequestID string
+}
+
+// AuditEntry records a single collection or document mutation. Entries are hash-chained:
+// each entry's hash covers its fields and the hash of the entry before it.
+type AuditEntry struct {
+	Seq            int64           `json:"seq"`
+	Timestamp      time.Time       `json:"timestamp"`
+	Principal      string          `json:"principal"`
+	SourceIP       string          `json:"source_ip,omitempty"`
+	RequestID      string          `json:"request_id,omitempty"`
+	Operation      ChangeOperation `json:"op"`
+	CollectionName string          `json:"collection_name"`
+	DocumentID     string          `json:"document_id,omitempty"`
+	BeforeHash     string          `json:"before_hash,omitempty"`
+	AfterHash      string          `json:"after_hash,omitempty"`
+	PrevHash       string          `json:"prev_hash"`
+	Hash           string          `json:"hash"`
+}
+
+// ComputeHash returns the hash of the entry's fields chained to PrevHash
+func (e *AuditEntry) ComputeHash() string {
+	// Encoding the fields as a JSON array keeps the input unambiguous whatever the values contain
+	fields, _ := json.Marshal([]interface{}{
+		e.Seq,
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.Principal,
+		e.SourceIP,
+		e.RequestID,
+		e.Operation,
+		e.CollectionName,
+		e.DocumentID,
+		e.BeforeHash,
+		e.AfterHash,
+		e.PrevHash,
+	})
+	sum := sha256.Sum256(fields)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditQuery filters audit log entries; zero values match everything
+type AuditQuery struct {
+	Principal      string
+	Operation      string
+	CollectionName string
+	DocumentID     string
+	Since          *time.Time
+	Until          *time.Time
+	Limit          int
+	Offset         int
+}
+
+// NewAuditQuery creates a new audit query with default values
+func NewAuditQuery() *AuditQuery {
+	return &AuditQuery{
+		Limit:  100,
+		Offset: 0,
+	}
+}
+
+// AuditList represents a page of audit log entries with metadata
+type AuditList struct {
+	Total   int          `json:"total"`
+	Offset  int          `json:"offset"`
+	Limit   int          `json:"limit"`
+	Entries []AuditEntry `json:"entries"`
+}
+
+// AuditVerification is the result of checking the audit log's hash chain
+type AuditVerification struct {
+	Valid   bool   `json:"valid"`
+	Entries int64  `json:"entries"`
+	LastSeq int64  `json:"last_seq"`
+	BadSeq  int64  `json:"bad_seq,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+)
+
+// ChangeOperation identifies the kind of mutation recorded in the change log
+type ChangeOperation string
+
+// Supported change log operations
+const (
+	ChangeCollectionCreate  ChangeOperation = "collection_create"
+	ChangeCollectionUpdate  ChangeOperation = "collection_update"
+	ChangeCollectionDelete  ChangeOperation = "collection_delete"
+	ChangeCollectionTrash   ChangeOperation = "collection_trash"
+	ChangeCollectionRestore ChangeOperation = "collection_restore"
+	ChangeDocumentPut       ChangeOperation = "document_put"
+	ChangeDocumentDelete    ChangeOperation = "document_delete"
+	ChangeDocumentTrash     ChangeOperation = "document_trash"
+	ChangeDocumentShare     ChangeOperation = "document_share"
+)
+
+// Change represents a single mutation recorded in the change log
+type Change struct {
+	Seq            int64           `json:"seq"`
+	Operation      ChangeOperation `json:"op"`
+	CollectionName string          `json:"collection_name"`
+	DocumentID     string          `json:"document_id,omitempty"`
+	Collection     *Collection     `json:"collection,omitempty"`
+	Document       *Document       `json:"document,omitempty"`
+	Author         string          `json:"author,omitempty"`
+	Timestamp      time.Time       `json:"timestamp"`
+}
+
+// ChangeList represents a page of the change log with metadata
+type ChangeList struct {
+	LatestSeq       int64     `json:"latest_seq"`
+	LatestTimestamp time.Time `json:"latest_timestamp"`
+	Changes         []Change  `json:"changes"`
+}
+
+// ChangeCheckpoint identifies a position in the change log
+type ChangeCheckpoint struct {
+	Seq       int64     `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SnapshotEntry is one line of a replication snapshot. The first entry carries the position
+// in the change log that the snapshot reflects and the last one marks its end; each entry in
+// between carries a collection or a document, every collection coming before its documents.
+type SnapshotEntry struct {
+	Checkpoint *ChangeCheckpoint `json:"checkpoint,omitempty"`
+	Collection *Collection       `json:"collection,omitempty"`
+	Document   *Document         `json:"document,omitempty"`
+	End        bool              `json:"end,omitempty"`
+}
+
+// ReplicationStatus describes the state of a replica relative to its primary
+type ReplicationStatus struct {
+	Role           string     `json:"role"`
+	Primary        string     `json:"primary,omitempty"`
+	LastAppliedSeq int64      `json:"last_applied_seq"`
+	PrimarySeq     int64      `json:"primary_seq"`
+	LagChanges     int64      `json:"lag_changes"`
+	LagSeconds     float64    `json:"lag_seconds"`
+	LastSyncAt     *time.Time `json:"last_sync_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+}
@@ -1,14 +1,17 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
 // Collection represents a document collection
 type Collection struct {
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Name      string            `json:"name"`
+	Options   CollectionOptions `json:"options"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty"`
 }
 
 // CollectionList represents a list of collections with metadata
@@ -17,6 +20,107 @@ type CollectionList struct {
 	Collections []Collection `json:"collections"`
 }
 
+// CollectionOptions holds per-collection settings
+type CollectionOptions struct {
+	History *HistoryOptions `json:"history,omitempty"`
+
+	// TTL is the default lifetime of documents written without an explicit expiry
+	TTL Duration `json:"ttl,omitempty"`
+
+	// Cap limits the size of the collection by evicting the oldest documents
+	Cap *CapOptions `json:"cap,omitempty"`
+
+	// Quota rejects writes that would grow the collection beyond a fixed size
+	Quota *QuotaOptions `json:"quota,omitempty"`
+
+	// Limits tighten the server's limits on the size and shape of documents
+	Limits *DocumentLimits `json:"limits,omitempty"`
+
+	// Ownership limits callers to the documents they own or that are shared with them
+	Ownership *OwnershipOptions `json:"ownership,omitempty"`
+}
+
+// HistoryOptions configures document revision history for a collection
+type HistoryOptions struct {
+	Enabled      bool     `json:"enabled"`
+	MaxRevisions int      `json:"max_revisions,omitempty"`
+	MaxAge       Duration `json:"max_age,omitempty"`
+}
+
+// CapOptions limits the number of documents or total data size of a collection
+type CapOptions struct {
+	MaxDocuments int   `json:"max_documents,omitempty"`
+	MaxBytes     int64 `json:"max_bytes,omitempty"`
+}
+
+// QuotaOptions limits the number of documents or total data size of a collection.
+// Unlike a cap, a quota never evicts documents; writes that would exceed it fail.
+type QuotaOptions struct {
+	MaxDocuments int   `json:"max_documents,omitempty"`
+	MaxBytes     int64 `json:"max_bytes,omitempty"`
+}
+
+// OwnershipOptions configures document-level access control for a collection
+type OwnershipOptions struct {
+	Enabled bool `json:"enabled"`
+}
+
+// HistoryEnabled reports whether revision history is kept for the collection
+func (o *CollectionOptions) HistoryEnabled() bool {
+	return o.History != nil && o.History.Enabled
+}
+
+// OwnershipEnabled reports whether documents of the collection are restricted to their owners
+func (o *CollectionOptions) OwnershipEnabled() bool {
+	return o.Ownership != nil && o.Ownership.Enabled
+}
+
+// Validate checks the options for invalid values
+func (o *CollectionOptions) Validate() error {
+	if o.History != nil {
+		if o.History.MaxRevisions < 0 {
+			return fmt.Errorf("history.max_revisions cannot be negative")
+		}
+		if o.History.MaxAge < 0 {
+			return fmt.Errorf("history.max_age cannot be negative")
+		}
+	}
+	if o.TTL < 0 {
+		return fmt.Errorf("ttl cannot be negative")
+	}
+	if o.Cap != nil {
+		if o.Cap.MaxDocuments < 0 {
+			return fmt.Errorf("cap.max_documents cannot be negative")
+		}
+		if o.Cap.MaxBytes < 0 {
+			return fmt.Errorf("cap.max_bytes cannot be negative")
+		}
+	}
+	if o.Limits != nil {
+		if err := o.Limits.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.Quota != nil {
+		if o.Quota.MaxDocuments < 0 {
+			return fmt.Errorf("quota.max_documents cannot be negative")
+		}
+		if o.Quota.MaxBytes < 0 {
+			return fmt.Errorf("quota.max_bytes cannot be negative")
+		}
+	}
+	return nil
+}
+
+// DefaultExpiry returns the expiry of a document written at now without an explicit one
+func (o *CollectionOptions) DefaultExpiry(now time.Time) *time.Time {
+	if o.TTL <= 0 {
+		return nil
+	}
+	expiresAt := now.Add(o.TTL.Std())
+	return &expiresAt
+}
+
 // NewCollection creates a new collection
 func NewCollection(name string) *Collection {
 	now := time.Now()
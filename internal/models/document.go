@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
 )
 
@@ -15,6 +16,14 @@ type Document struct {
 	Data           json.RawMessage `json:"data"`
 	CreatedAt      time.Time       `json:"created_at"`
 	UpdatedAt      time.Time       `json:"updated_at"`
+	ExpiresAt      *time.Time      `json:"expires_at,omitempty"`
+	DeletedAt      *time.Time      `json:"deleted_at,omitempty"`
+
+	// Owner is the subject that created the document, such as user:alice or key:<id>
+	Owner string `json:"owner,omitempty"`
+
+	// SharedWith lists the subjects the owner shared the document with
+	SharedWith []string `json:"shared_with,omitempty"`
 }
 
 // DocumentList represents a list of documents with metadata
@@ -64,10 +73,14 @@ func ValidateJSON(data []byte) error {
 
 // DocumentQuery represents query parameters for retrieving documents
 type DocumentQuery struct {
-	Limit  int    `json:"limit"`
-	Offset int    `json:"offset"`
-	Filter string `json:"filter"`
-	Sort   string `json:"sort"`
+	Limit  int        `json:"limit"`
+	Offset int        `json:"offset"`
+	Filter string     `json:"filter"`
+	Sort   string     `json:"sort"`
+	AsOf   *time.Time `json:"as_of,omitempty"`
+
+	// Access restricts the result to the documents the caller may see; nil means unrestricted
+	Access *DocumentAccess `json:"-"`
 }
 
 // NewDocumentQuery creates a new document query with default values
@@ -77,3 +90,30 @@ func NewDocumentQuery() *DocumentQuery {
 		Offset: 0,
 	}
 }
+
+// DocumentAccess identifies the caller of a document operation in a collection with ownership enabled
+type DocumentAccess struct {
+	// Subject is the caller, such as user:alice or key:<id>; empty for anonymous callers
+	Subject string
+
+	// Elevated callers, such as collection administrators, see every document
+	Elevated bool
+}
+
+// Owns reports whether the caller owns a document or may act as its owner
+func (a *DocumentAccess) Owns(document *Document) bool {
+	return a == nil || a.Elevated || (a.Subject != "" && document.Owner == a.Subject)
+}
+
+// CanSee reports whether the caller owns a document or it was shared with the caller
+func (a *DocumentAccess) CanSee(document *Document) bool {
+	return a.Owns(document) || (a.Subject != "" && slices.Contains(document.SharedWith, a.Subject))
+}
+
+// Owner returns the owner recorded on documents the caller creates
+func (a *DocumentAccess) Owner() string {
+	if a == nil {
+		return ""
+	}
+	return a.Subject
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that is encoded in JSON as a string such as "720h"
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string, or a number of seconds
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(time.Duration(v * float64(time.Second)))
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration '%s': %w", v, err)
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration: %s", string(b))
+	}
+
+	return nil
+}
+
+// Std returns the duration as a time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
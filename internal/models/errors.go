@@ -0,0 +1,30 @@
+package models
+
+import (
+	"errors"
+)
+
+// ErrHistoryNotEnabled is returned when a request needs revision history on a collection that does not keep it
+var ErrHistoryNotEnabled = errors.New("history is not enabled for this collection")
+
+// ErrHistoryPruned is returned when a request reaches back further than the revision history
+// a collection retains
+var ErrHistoryPruned = errors.New("history of this time has been pruned")
+
+// ErrSnapshotRequired is returned when a follower asks for changes that have been pruned from
+// the change log and must reload a snapshot instead
+var ErrSnapshotRequired = errors.New("changes have been pruned; a snapshot is required")
+
+// ErrDocumentInTrash is returned when a document is created with the ID of one in the trash
+var ErrDocumentInTrash = errors.New("a document with this ID is in the trash")
+
+// ErrQuotaExceeded is returned when a write would grow a collection beyond its quota
+var ErrQuotaExceeded = errors.New("collection quota exceeded")
+
+// Errors returned when a document breaks its collection's limits
+var (
+	ErrDocumentTooLarge = errors.New("document too large")
+	ErrDocumentTooDeep  = errors.New("document nested too deeply")
+	ErrArrayTooLong     = errors.New("array too long")
+	ErrTooManyKeys      = errors.New("object has too many keys")
+)
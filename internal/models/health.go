@@ -0,0 +1,29 @@
+package models
+
+// Readiness statuses of checks and of the instance as a whole
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthFailed   = "failed"
+)
+
+// HealthCheckResult is the outcome of one readiness check
+type HealthCheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Details   any     `json:"details,omitempty"`
+}
+
+// ReadinessReport lists the results of the readiness checks. The instance is ready unless a
+// check failed; degraded checks are reported but do not take it out of rotation.
+type ReadinessReport struct {
+	Status string              `json:"status"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+// Ready reports whether the instance should receive traffic
+func (r ReadinessReport) Ready() bool {
+	return r.Status != HealthFailed
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+// JobStatus describes the state of a background job
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Running   bool       `json:"running"`
+	Runs      int64      `json:"runs"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// DocumentLimits bounds the size and shape of request bodies and documents; zero means unlimited
+type DocumentLimits struct {
+	// MaxRequestBytes limits the size of a request body, such as a bulk insert or upload
+	MaxRequestBytes int64 `json:"max_request_bytes,omitempty"`
+
+	// MaxDocumentBytes limits the size of a single document
+	MaxDocumentBytes int64 `json:"max_document_bytes,omitempty"`
+
+	// MaxDepth limits how deeply objects and arrays are nested in a document
+	MaxDepth int `json:"max_depth,omitempty"`
+
+	// MaxArrayLength limits the number of elements of each array in a document
+	MaxArrayLength int `json:"max_array_length,omitempty"`
+
+	// MaxKeys limits the number of keys of each object in a document
+	MaxKeys int `json:"max_keys,omitempty"`
+}
+
+// Validate checks the limits for consistency
+func (l *DocumentLimits) Validate() error {
+	if l.MaxRequestBytes < 0 || l.MaxDocumentBytes < 0 || l.MaxDepth < 0 || l.MaxArrayLength < 0 || l.MaxKeys < 0 {
+		return fmt.Errorf("limits cannot be negative")
+	}
+	return nil
+}
+
+// Tighten returns the stricter of each limit of l and other; a nil other leaves l unchanged
+func (l DocumentLimits) Tighten(other *DocumentLimits) DocumentLimits {
+	if other == nil {
+		return l
+	}
+	return DocumentLimits{
+		MaxRequestBytes:  stricter(l.MaxRequestBytes, other.MaxRequestBytes),
+		MaxDocumentBytes: stricter(l.MaxDocumentBytes, other.MaxDocumentBytes),
+		MaxDepth:         stricter(l.MaxDepth, other.MaxDepth),
+		MaxArrayLength:   stricter(l.MaxArrayLength, other.MaxArrayLength),
+		MaxKeys:          stricter(l.MaxKeys, other.MaxKeys),
+	}
+}
+
+// stricter returns the smaller of two limits, where zero means unlimited
+func stricter[T int | int64](a, b T) T {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// jsonFrame tracks an object or array being scanned
+type jsonFrame struct {
+	object    bool
+	count     int
+	expectKey bool
+}
+
+// Check ensures data is valid JSON within the size and shape limits
+func (l DocumentLimits) Check(data []byte) error {
+	if l.MaxDocumentBytes > 0 && int64(len(data)) > l.MaxDocumentBytes {
+		return fmt.Errorf("%w: document of %d bytes exceeds the limit of %d bytes", ErrDocumentTooLarge, len(data), l.MaxDocumentBytes)
+	}
+	if err := ValidateJSON(data); err != nil {
+		return err
+	}
+	if l.MaxDepth == 0 && l.MaxArrayLength == 0 && l.MaxKeys == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var stack []*jsonFrame
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid JSON data")
+		}
+
+		// Count keys of objects and elements of arrays
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			switch {
+			case parent.object && parent.expectKey:
+				if _, isDelim := token.(json.Delim); !isDelim {
+					parent.count++
+					parent.expectKey = false
+					if l.MaxKeys > 0 && parent.count > l.MaxKeys {
+						return fmt.Errorf("%w: an object has more than %d keys", ErrTooManyKeys, l.MaxKeys)
+					}
+					continue
+				}
+			case parent.object:
+				parent.expectKey = true
+			default:
+				if token != json.Delim(']') {
+					parent.count++
+					if l.MaxArrayLength > 0 && parent.count > l.MaxArrayLength {
+						return fmt.Errorf("%w: an array has more than %d elements", ErrArrayTooLong, l.MaxArrayLength)
+					}
+				}
+			}
+		}
+
+		// Track nesting
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			stack = append(stack, &jsonFrame{object: token == json.Delim('{'), expectKey: true})
+			if l.MaxDepth > 0 && len(stack) > l.MaxDepth {
+				return fmt.Errorf("%w: nesting exceeds the limit of %d levels", ErrDocumentTooDeep, l.MaxDepth)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package models_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+func TestDocumentLimitsCheck(t *testing.T) {
+	limits := models.DocumentLimits{MaxDocumentBytes: 64, MaxDepth: 3, MaxArrayLength: 3, MaxKeys: 2}
+
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"within limits", `{"a":[1,2,3],"b":{"c":[1]}}`, nil},
+		{"scalar", `"text"`, nil},
+		{"empty containers", `{"a":{},"b":[]}`, nil},
+		{"too large", `{"a":"` + strings.Repeat("x", 64) + `"}`, models.ErrDocumentTooLarge},
+		{"too deep", `{"a":{"b":[{}]}}`, models.ErrDocumentTooDeep},
+		{"array too long", `[1,2,3,4]`, models.ErrArrayTooLong},
+		{"nested array too long", `{"a":[[],[],[],[]]}`, models.ErrArrayTooLong},
+		{"too many keys", `{"a":1,"b":2,"c":3}`, models.ErrTooManyKeys},
+		{"keys counted per object", `{"a":{"x":1,"y":2},"b":{"x":1,"y":2}}`, nil},
+		{"string values are not keys", `{"a":"b","c":"d"}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.Check([]byte(tt.data))
+			if tt.want == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("wrong error: got %v want %v", err, tt.want)
+			}
+		})
+	}
+
+	for _, data := range []string{`{"a":`, `{} {}`, ``} {
+		if err := limits.Check([]byte(data)); err == nil {
+			t.Errorf("invalid JSON %q was accepted", data)
+		}
+	}
+}
+
+func TestDocumentLimitsTighten(t *testing.T) {
+	server := models.DocumentLimits{MaxRequestBytes: 1000, MaxDepth: 10}
+	got := server.Tighten(&models.DocumentLimits{MaxRequestBytes: 2000, MaxDepth: 5, MaxKeys: 20})
+	want := models.DocumentLimits{MaxRequestBytes: 1000, MaxDepth: 5, MaxKeys: 20}
+	if got != want {
+		t.Errorf("wrong limits: got %+v want %+v", got, want)
+	}
+	if got := server.Tighten(nil); got != server {
+		t.Errorf("wrong limits without collection limits: got %+v want %+v", got, server)
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RevisionOperation identifies the write that produced a revision
+type RevisionOperation string
+
+// Supported revision operations
+const (
+	RevisionCreate  RevisionOperation = "create"
+	RevisionUpdate  RevisionOperation = "update"
+	RevisionDelete  RevisionOperation = "delete"
+	RevisionRestore RevisionOperation = "restore"
+)
+
+// Revision represents a stored version of a document, valid from CreatedAt
+// until SupersededAt, or until now for the latest revision
+type Revision struct {
+	CollectionName string            `json:"collection_name"`
+	DocumentID     string            `json:"document_id"`
+	Revision       int               `json:"revision"`
+	Operation      RevisionOperation `json:"operation"`
+	Data           json.RawMessage   `json:"data,omitempty"`
+	Author         string            `json:"author"`
+	CreatedAt      time.Time         `json:"created_at"`
+	SupersededAt   *time.Time        `json:"superseded_at,omitempty"`
+}
+
+// RevisionList represents the revision history of a document
+type RevisionList struct {
+	Total     int        `json:"total"`
+	Revisions []Revision `json:"revisions"`
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Permission is an action on a collection
+type Permission string
+
+// Permissions that roles grant on collections; admin implies all others
+const (
+	PermissionRead   Permission = "read"
+	PermissionWrite  Permission = "write"
+	PermissionDelete Permission = "delete"
+	PermissionAdmin  Permission = "admin"
+)
+
+// Grant gives permissions on the collections matching a pattern such as "orders", "logs_*" or "*"
+type Grant struct {
+	Collection  string       `json:"collection"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Allows reports whether the grant permits an action on a collection
+func (g Grant) Allows(collection string, permission Permission) bool {
+	if matched, err := path.Match(g.Collection, collection); err != nil || !matched {
+		return false
+	}
+	return slices.Contains(g.Permissions, PermissionAdmin) || slices.Contains(g.Permissions, permission)
+}
+
+// Role is a named set of grants
+type Role struct {
+	Name      string    `json:"name"`
+	Grants    []Grant   `json:"grants"`
+	Builtin   bool      `json:"builtin,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// RoleList represents a list of roles with metadata
+type RoleList struct {
+	Total int    `json:"total"`
+	Roles []Role `json:"roles"`
+}
+
+// RoleAssignmentList lists the subjects a role is assigned to
+type RoleAssignmentList struct {
+	Role     string   `json:"role"`
+	Subjects []string `json:"subjects"`
+}
+
+// BuiltinRoles are always available and cannot be changed
+var BuiltinRoles = map[string]Role{
+	"reader": {Name: "reader", Builtin: true, Grants: []Grant{
+		{Collection: "*", Permissions: []Permission{PermissionRead}},
+	}},
+	"writer": {Name: "writer", Builtin: true, Grants: []Grant{
+		{Collection: "*", Permissions: []Permission{PermissionRead, PermissionWrite, PermissionDelete}},
+	}},
+	"admin": {Name: "admin", Builtin: true, Grants: []Grant{
+		{Collection: "*", Permissions: []Permission{PermissionAdmin}},
+	}},
+}
+
+// ValidateGrants checks grants for invalid patterns and unknown permissions
+func ValidateGrants(grants []Grant) error {
+	for i, grant := range grants {
+		if grant.Collection == "" {
+			return fmt.Errorf("grant %d has no collection pattern", i)
+		}
+		if _, err := path.Match(grant.Collection, ""); err != nil {
+			return fmt.Errorf("grant %d has an invalid collection pattern '%s'", i, grant.Collection)
+		}
+		if len(grant.Permissions) == 0 {
+			return fmt.Errorf("grant %d has no permissions", i)
+		}
+		for _, permission := range grant.Permissions {
+			switch permission {
+			case PermissionRead, PermissionWrite, PermissionDelete, PermissionAdmin:
+			default:
+				return fmt.Errorf("grant %d has unknown permission '%s'", i, permission)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateSubject checks that a role subject is of the form user:<name>, key:<id>,
+// jwt:<subject> or cert:<common name>
+func ValidateSubject(subject string) error {
+	kind, name, ok := strings.Cut(subject, ":")
+	if !ok || name == "" || (kind != "user" && kind != "key" && kind != "jwt" && kind != "cert") {
+		return fmt.Errorf("subject must be of the form user:<name>, key:<id>, jwt:<subject> or cert:<common name>")
+	}
+	return nil
+}
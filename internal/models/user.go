@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// User is an account that authenticates with a username and password
+type User struct {
+	Username   string     `json:"username"`
+	Disabled   bool       `json:"disabled"`
+	Roles      []string   `json:"roles"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+// UserList represents a list of users with metadata
+type UserList struct {
+	Total int    `json:"total"`
+	Users []User `json:"users"`
+}
@@ -0,0 +1,331 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/api"
+	"github.com/rbehzadan/flexstore/internal/models"
+	"github.com/rbehzadan/flexstore/internal/service"
+)
+
+// DefaultBatchSize is the number of changes requested from the primary per pull
+const DefaultBatchSize = 500
+
+// Primary endpoints that serve the change log and snapshots
+const (
+	changesPath  = "/api/replication/changes"
+	snapshotPath = "/api/replication/snapshot"
+)
+
+// snapshotTimeout limits how long loading a snapshot of the primary may take
+const snapshotTimeout = 30 * time.Minute
+
+// changesResponse is the envelope returned by the primary's change log endpoint
+type changesResponse struct {
+	Status string             `json:"status"`
+	Data   *models.ChangeList `json:"data"`
+	Error  *api.ErrorInfo     `json:"error"`
+}
+
+// Follower continuously pulls changes from a primary instance and applies them locally. It
+// starts from a snapshot of the primary, and loads a new one when the primary has pruned
+// changes it has not pulled yet.
+type Follower struct {
+	primary        *url.URL
+	source         string
+	service        *service.ReplicationService
+	client         *http.Client
+	snapshotClient *http.Client
+	interval       time.Duration
+	batchSize      int
+
+	mu            sync.RWMutex
+	status        models.ReplicationStatus
+	lastTimestamp time.Time
+	needsSnapshot bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewFollower creates a follower for the primary at primaryURL and restores its checkpoint.
+// Credentials in the URL user info are sent to the primary using HTTP Basic Authentication.
+func NewFollower(ctx context.Context, primaryURL string, interval time.Duration, replicationService *service.ReplicationService) (*Follower, error) {
+	primary, err := url.Parse(primaryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid primary URL: %w", err)
+	}
+	if primary.Scheme != "http" && primary.Scheme != "https" {
+		return nil, fmt.Errorf("invalid primary URL '%s': scheme must be http or https", primaryURL)
+	}
+	if primary.Host == "" {
+		return nil, fmt.Errorf("invalid primary URL '%s': missing host", primaryURL)
+	}
+
+	// The checkpoint is keyed by the primary address without credentials
+	source := *primary
+	source.User = nil
+	sourceKey := strings.TrimSuffix(source.String(), "/")
+
+	checkpoint, err := replicationService.GetCheckpoint(ctx, sourceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Follower{
+		primary:        primary,
+		source:         sourceKey,
+		service:        replicationService,
+		client:         &http.Client{Timeout: 30 * time.Second},
+		snapshotClient: &http.Client{Timeout: snapshotTimeout},
+		interval:       interval,
+		batchSize:      DefaultBatchSize,
+		status: models.ReplicationStatus{
+			Role:    "follower",
+			Primary: sourceKey,
+		},
+		needsSnapshot: checkpoint == nil,
+	}
+	if checkpoint != nil {
+		f.status.LastAppliedSeq = checkpoint.Seq
+		f.lastTimestamp = checkpoint.Timestamp
+	}
+
+	return f, nil
+}
+
+// Start launches the background pull loop. Pulls run under ctx, so canceling it cuts a pull
+// or snapshot in progress short.
+func (f *Follower) Start(ctx context.Context) {
+	f.stop = make(chan struct{})
+	f.done = make(chan struct{})
+
+	go f.run(ctx)
+	slog.Info("Replication started", "source", f.source, "after_seq", f.Status().LastAppliedSeq)
+}
+
+// Stop signals the pull loop to exit and waits for it to finish
+func (f *Follower) Stop() {
+	if f.stop == nil {
+		return
+	}
+	close(f.stop)
+	<-f.done
+	f.stop = nil
+}
+
+// run pulls changes until stopped or ctx is canceled
+func (f *Follower) run(ctx context.Context) {
+	defer close(f.done)
+
+	for {
+		if err := f.Sync(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Replication failed", "source", f.source, "error", err)
+		}
+
+		select {
+		case <-f.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-time.After(f.interval):
+		}
+	}
+}
+
+// Sync pulls and applies changes from the primary until the follower has caught up, loading
+// a snapshot first when the follower has none or has fallen behind the pruned change log
+func (f *Follower) Sync(ctx context.Context) error {
+	loaded := false
+	for {
+		f.mu.RLock()
+		since := f.status.LastAppliedSeq
+		needsSnapshot := f.needsSnapshot
+		f.mu.RUnlock()
+
+		if needsSnapshot {
+			if err := f.loadSnapshot(ctx); err != nil {
+				f.recordError(err)
+				return err
+			}
+			loaded = true
+			continue
+		}
+
+		// A snapshot the primary cannot continue from is not loaded again in the same sync
+		list, err := f.fetch(ctx, since)
+		if errors.Is(err, models.ErrSnapshotRequired) && !loaded {
+			slog.Warn("Primary has pruned changes not yet replicated, loading a snapshot", "source", f.source, "after_seq", since)
+			f.mu.Lock()
+			f.needsSnapshot = true
+			f.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			f.recordError(err)
+			return err
+		}
+
+		if err := f.service.ApplyChanges(ctx, f.source, list.Changes); err != nil {
+			f.recordError(err)
+			return err
+		}
+
+		f.recordSync(list)
+
+		if len(list.Changes) < f.batchSize {
+			return nil
+		}
+	}
+}
+
+// fetch requests the next batch of changes from the primary. It returns
+// models.ErrSnapshotRequired when the primary has pruned some of them.
+func (f *Follower) fetch(ctx context.Context, since int64) (*models.ChangeList, error) {
+	query := url.Values{
+		"since": {fmt.Sprint(since)},
+		"limit": {fmt.Sprint(f.batchSize)},
+	}
+	resp, err := f.get(ctx, f.client, changesPath, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body changesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode primary response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, primaryError(resp.StatusCode, body.Error)
+	}
+	if body.Data == nil {
+		return nil, fmt.Errorf("primary response is missing data")
+	}
+
+	return body.Data, nil
+}
+
+// loadSnapshot replaces the local collections and documents with a snapshot of the primary
+// and continues replicating from the position it reflects
+func (f *Follower) loadSnapshot(ctx context.Context) error {
+	resp, err := f.get(ctx, f.snapshotClient, snapshotPath, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body changesResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return fmt.Errorf("failed to decode primary response (status %d): %w", resp.StatusCode, err)
+		}
+		return primaryError(resp.StatusCode, body.Error)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	checkpoint, err := f.service.ApplySnapshot(ctx, f.source, func() (*models.SnapshotEntry, error) {
+		var entry models.SnapshotEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, err
+		}
+		return &entry, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot: %w", err)
+	}
+
+	f.mu.Lock()
+	f.status.LastAppliedSeq = checkpoint.Seq
+	f.lastTimestamp = checkpoint.Timestamp
+	f.needsSnapshot = false
+	f.mu.Unlock()
+
+	slog.Info("Loaded snapshot from primary", "source", f.source, "seq", checkpoint.Seq)
+	return nil
+}
+
+// get sends a GET request for an endpoint of the primary through client
+func (f *Follower) get(ctx context.Context, client *http.Client, path string, query url.Values) (*http.Response, error) {
+	endpoint := *f.primary
+	endpoint.User = nil
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + path
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if f.primary.User != nil {
+		password, _ := f.primary.User.Password()
+		req.SetBasicAuth(f.primary.User.Username(), password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach primary: %w", err)
+	}
+	return resp, nil
+}
+
+// primaryError describes an error response of the primary
+func primaryError(status int, info *api.ErrorInfo) error {
+	if info == nil {
+		return fmt.Errorf("primary returned %d", status)
+	}
+	if info.Code == "SNAPSHOT_REQUIRED" {
+		return fmt.Errorf("primary returned %d: %w", status, models.ErrSnapshotRequired)
+	}
+	return fmt.Errorf("primary returned %d: %s: %s", status, info.Code, info.Message)
+}
+
+// recordSync updates the status after a successful pull
+func (f *Follower) recordSync(list *models.ChangeList) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if n := len(list.Changes); n > 0 {
+		f.status.LastAppliedSeq = list.Changes[n-1].Seq
+		f.lastTimestamp = list.Changes[n-1].Timestamp
+	}
+
+	now := time.Now()
+	f.status.LastSyncAt = &now
+	f.status.LastError = ""
+	f.status.PrimarySeq = list.LatestSeq
+	f.status.LagChanges = 0
+	f.status.LagSeconds = 0
+
+	if list.LatestSeq > f.status.LastAppliedSeq {
+		f.status.LagChanges = list.LatestSeq - f.status.LastAppliedSeq
+		if lag := list.LatestTimestamp.Sub(f.lastTimestamp).Seconds(); lag > 0 {
+			f.status.LagSeconds = lag
+		}
+	}
+}
+
+// recordError stores the last replication error in the status
+func (f *Follower) recordError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.status.LastError = err.Error()
+}
+
+// Status returns a snapshot of the replication status
+func (f *Follower) Status() *models.ReplicationStatus {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	status := f.status
+	return &status
+}
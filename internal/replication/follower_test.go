@@ -0,0 +1,301 @@
+package replication_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/app"
+	"github.com/rbehzadan/flexstore/internal/models"
+	"github.com/rbehzadan/flexstore/pkg/config"
+)
+
+// newTestApp creates an application backed by a database in dir
+func newTestApp(t *testing.T, dir, replicateFrom string) *app.App {
+	t.Helper()
+
+	cfg := config.NewConfig()
+	cfg.SqlitePath = filepath.Join(dir, "db.sqlite")
+	cfg.EnableBasicAuth = false
+	cfg.ReplicateFrom = replicateFrom
+
+	a, err := app.NewApp(cfg)
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	return a
+}
+
+func TestFollowerReplicatesFromPrimary(t *testing.T) {
+	primary := newTestApp(t, t.TempDir(), "")
+	defer primary.DB.Close()
+
+	server := httptest.NewServer(primary.Router)
+	defer server.Close()
+
+	// Write to the primary
+	kept, err := primary.DocumentService.Create(t.Context(), "users", json.RawMessage(`{"name":"alice"}`), nil, nil, models.Actor{Name: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := primary.DocumentService.Create(t.Context(), "users", json.RawMessage(`{"name":"bob"}`), nil, nil, models.Actor{Name: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err = primary.DocumentService.Update(t.Context(), kept.ID, "users", json.RawMessage(`{"name":"alice","age":30}`), nil, nil, models.Actor{Name: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.DocumentService.Delete(t.Context(), removed.ID, "users", nil, models.Actor{Name: "tester"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pull into the follower
+	followerDir := t.TempDir()
+	follower := newTestApp(t, followerDir, server.URL)
+	if err := follower.Follower.Sync(t.Context()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	got, err := follower.DocumentService.GetByID(t.Context(), kept.ID, "users", nil)
+	if err != nil {
+		t.Fatalf("replicated document missing: %v", err)
+	}
+	if string(got.Data) != string(kept.Data) {
+		t.Errorf("wrong data: got %s want %s", got.Data, kept.Data)
+	}
+	if !got.CreatedAt.Equal(kept.CreatedAt) || !got.UpdatedAt.Equal(kept.UpdatedAt) {
+		t.Errorf("timestamps not preserved: got %v/%v want %v/%v",
+			got.CreatedAt, got.UpdatedAt, kept.CreatedAt, kept.UpdatedAt)
+	}
+	if _, err := follower.DocumentService.GetByID(t.Context(), removed.ID, "users", nil); err == nil {
+		t.Errorf("deleted document %s was replicated", removed.ID)
+	}
+
+	status := follower.Follower.Status()
+	if status.LagChanges != 0 || status.LastAppliedSeq != status.PrimarySeq {
+		t.Errorf("follower not caught up: %+v", status)
+	}
+
+	// Writes are rejected on the follower
+	req := httptest.NewRequest("POST", "/api/collections/users/documents", strings.NewReader(`{"x":1}`))
+	rr := httptest.NewRecorder()
+	follower.Router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("write on follower returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+	}
+
+	// Restart the follower and resume from the checkpoint
+	checkpoint := status.LastAppliedSeq
+	follower.DB.Close()
+
+	added, err := primary.DocumentService.Create(t.Context(), "users", json.RawMessage(`{"name":"carol"}`), nil, nil, models.Actor{Name: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follower = newTestApp(t, followerDir, server.URL)
+	defer follower.DB.Close()
+
+	if seq := follower.Follower.Status().LastAppliedSeq; seq != checkpoint {
+		t.Errorf("checkpoint not restored: got %v want %v", seq, checkpoint)
+	}
+	if err := follower.Follower.Sync(t.Context()); err != nil {
+		t.Fatalf("sync after restart failed: %v", err)
+	}
+	if _, err := follower.DocumentService.GetByID(t.Context(), added.ID, "users", nil); err != nil {
+		t.Errorf("document created after restart missing: %v", err)
+	}
+}
+
+func TestFollowerStartsFromSnapshot(t *testing.T) {
+	primary := newTestApp(t, t.TempDir(), "")
+	defer primary.DB.Close()
+
+	server := httptest.NewServer(primary.Router)
+	defer server.Close()
+
+	// Documents written before the change log existed have no changes
+	existing, err := primary.DocumentService.Create(t.Context(), "users", json.RawMessage(`{"name":"alice"}`), nil, nil, models.Actor{Name: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := primary.DB.ExecContext(t.Context(), `DELETE FROM changes`); err != nil {
+		t.Fatal(err)
+	}
+	added, err := primary.DocumentService.Create(t.Context(), "users", json.RawMessage(`{"name":"bob"}`), nil, nil, models.Actor{Name: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follower := newTestApp(t, t.TempDir(), server.URL)
+	defer follower.DB.Close()
+	if err := follower.Follower.Sync(t.Context()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	for _, document := range []*models.Document{existing, added} {
+		got, err := follower.DocumentService.GetByID(t.Context(), document.ID, "users", nil)
+		if err != nil {
+			t.Errorf("document %s missing on follower: %v", document.ID, err)
+			continue
+		}
+		if string(got.Data) != string(document.Data) || !got.CreatedAt.Equal(document.CreatedAt) {
+			t.Errorf("wrong document: got %s/%v want %s/%v", got.Data, got.CreatedAt, document.Data, document.CreatedAt)
+		}
+	}
+
+	status := follower.Follower.Status()
+	if status.LagChanges != 0 || status.LastAppliedSeq != status.PrimarySeq {
+		t.Errorf("follower not caught up: %+v", status)
+	}
+}
+
+func TestFollowerReloadsSnapshotAfterPrune(t *testing.T) {
+	primary := newTestApp(t, t.TempDir(), "")
+	defer primary.DB.Close()
+
+	server := httptest.NewServer(primary.Router)
+	defer server.Close()
+
+	removed, err := primary.DocumentService.Create(t.Context(), "users", json.RawMessage(`{"name":"alice"}`), nil, nil, models.Actor{Name: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	follower := newTestApp(t, t.TempDir(), server.URL)
+	defer follower.DB.Close()
+	if err := follower.Follower.Sync(t.Context()); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	// Change the primary and prune the changes before the follower pulls them
+	if err := primary.DocumentService.Delete(t.Context(), removed.ID, "users", nil, models.Actor{Name: "tester"}); err != nil {
+		t.Fatal(err)
+	}
+	added, err := primary.DocumentService.Create(t.Context(), "users", json.RawMessage(`{"name":"bob"}`), nil, nil, models.Actor{Name: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	pruned, err := primary.ReplicationService.PruneChanges(t.Context(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned == 0 {
+		t.Fatal("no changes pruned")
+	}
+
+	// Followers behind the pruned changes are told to load a snapshot
+	since := follower.Follower.Status().LastAppliedSeq
+	req := httptest.NewRequest("GET", "/api/replication/changes?since="+strconv.FormatInt(since, 10), nil)
+	rr := httptest.NewRecorder()
+	primary.Router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusGone || !strings.Contains(rr.Body.String(), "SNAPSHOT_REQUIRED") {
+		t.Errorf("wrong response for pruned changes: got %v %s want %v", rr.Code, rr.Body, http.StatusGone)
+	}
+
+	if err := follower.Follower.Sync(t.Context()); err != nil {
+		t.Fatalf("sync after prune failed: %v", err)
+	}
+	if _, err := follower.DocumentService.GetByID(t.Context(), removed.ID, "users", nil); err == nil {
+		t.Errorf("deleted document %s is still on the follower", removed.ID)
+	}
+	if _, err := follower.DocumentService.GetByID(t.Context(), added.ID, "users", nil); err != nil {
+		t.Errorf("document created after prune missing: %v", err)
+	}
+
+	status := follower.Follower.Status()
+	if status.LagChanges != 0 || status.LastAppliedSeq != status.PrimarySeq {
+		t.Errorf("follower not caught up: %+v", status)
+	}
+}
+
+func TestSnapshotOutlastsTimeouts(t *testing.T) {
+	cfg := config.NewConfig()
+	cfg.SqlitePath = filepath.Join(t.TempDir(), "db.sqlite")
+	cfg.EnableBasicAuth = false
+	cfg.QueryTimeoutRead = 20 * time.Millisecond
+	cfg.QueryTimeoutWrite = 20 * time.Millisecond
+	cfg.QueryTimeoutBulk = 20 * time.Millisecond
+	primary, err := app.NewApp(cfg)
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	defer primary.DB.Close()
+
+	// Serve with a write timeout far shorter than the stream takes
+	server := httptest.NewUnstartedServer(primary.Router)
+	server.Config.WriteTimeout = 20 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	// Store more than the connection buffers, so the stream waits for its reader
+	items := make([]json.RawMessage, 1000)
+	for i := range items {
+		items[i] = json.RawMessage(`{"text":"` + strings.Repeat("x", 4096) + `"}`)
+	}
+	if _, err := primary.CollectionService.Create(t.Context(), "logs", models.CollectionOptions{}, models.Actor{Name: "tester"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := primary.DocumentService.BulkCreate(t.Context(), "logs", items, nil, models.Actor{Name: "tester"}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(server.URL + "/api/replication/snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	time.Sleep(100 * time.Millisecond)
+
+	// Every document arrives, followed by the end of the snapshot
+	decoder := json.NewDecoder(resp.Body)
+	documents := 0
+	var last models.SnapshotEntry
+	for {
+		var entry models.SnapshotEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if err != io.EOF {
+				t.Fatalf("snapshot cut off after %d documents: %v", documents, err)
+			}
+			break
+		}
+		if entry.Document != nil {
+			documents++
+		}
+		last = entry
+	}
+	if documents != len(items) || !last.End {
+		t.Errorf("incomplete snapshot: got %d documents, end %v want %d documents, end true", documents, last.End, len(items))
+	}
+}
+
+func TestShutdownCancelsPull(t *testing.T) {
+	// A primary that never answers
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	follower := newTestApp(t, t.TempDir(), server.URL)
+	follower.Start()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := follower.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdown waited for the pull: took %v", elapsed)
+	}
+}
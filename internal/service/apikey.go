@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// APIKeyService handles API key management and authentication
+type APIKeyService struct {
+	repo *db.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(repo *db.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+// Create issues a new API key
+func (s *APIKeyService) Create(ctx context.Context, name string, scopes, collections []string, expiresAt *time.Time) (*models.IssuedAPIKey, error) {
+	if name == "" {
+		return nil, fmt.Errorf("API key name cannot be empty")
+	}
+	if err := models.ValidateScopes(scopes); err != nil {
+		return nil, err
+	}
+
+	id, err := auth.GenerateID()
+	if err != nil {
+		return nil, err
+	}
+	secret, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := models.APIKey{
+		ID:          id,
+		Name:        name,
+		Prefix:      prefix,
+		Scopes:      scopes,
+		Collections: collections,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.repo.Create(ctx, &key, auth.HashAPIKey(secret)); err != nil {
+		return nil, err
+	}
+
+	return &models.IssuedAPIKey{APIKey: key, Key: secret}, nil
+}
+
+// List retrieves all API keys
+func (s *APIKeyService) List(ctx context.Context) (*models.APIKeyList, error) {
+	return s.repo.List(ctx)
+}
+
+// GetByID retrieves an API key by ID
+func (s *APIKeyService) GetByID(ctx context.Context, id string) (*models.APIKey, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// Revoke revokes an API key
+func (s *APIKeyService) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	return s.repo.Revoke(ctx, id)
+}
+
+// Rotate replaces the secret of an API key, invalidating the previous one
+func (s *APIKeyService) Rotate(ctx context.Context, id string) (*models.IssuedAPIKey, error) {
+	secret, prefix, err := auth.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.repo.Rotate(ctx, id, prefix, auth.HashAPIKey(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.IssuedAPIKey{APIKey: *key, Key: secret}, nil
+}
+
+// Authenticate resolves an API key secret to the key it belongs to
+func (s *APIKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	key, err := s.repo.GetByHash(ctx, auth.HashAPIKey(secret))
+	if ctx.Err() != nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid API key")
+	}
+	if !key.Active(time.Now()) {
+		return nil, fmt.Errorf("API key is revoked or expired")
+	}
+	return key, nil
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// AuditService handles audit log operations
+type AuditService struct {
+	repo *db.AuditRepository
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(repo *db.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// List retrieves audit log entries matching a query, newest first
+func (s *AuditService) List(ctx context.Context, query *models.AuditQuery) (*models.AuditList, error) {
+	return s.repo.List(ctx, query)
+}
+
+// Export passes every audit log entry matching a query to fn in batches, oldest first
+func (s *AuditService) Export(ctx context.Context, query *models.AuditQuery, fn func([]models.AuditEntry) error) error {
+	return s.repo.Export(ctx, query, fn)
+}
+
+// Verify checks the hash chain of the audit log
+func (s *AuditService) Verify(ctx context.Context) (*models.AuditVerification, error) {
+	return s.repo.Verify(ctx)
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/rbehzadan/flexstore/internal/db"
 	"github.com/rbehzadan/flexstore/internal/models"
 )
@@ -16,26 +18,41 @@ func NewCollectionService(repo *db.CollectionRepository) *CollectionService {
 }
 
 // Create creates a new collection
-func (s *CollectionService) Create(name string) (*models.Collection, error) {
-	return s.repo.Create(name)
+func (s *CollectionService) Create(ctx context.Context, name string, options models.CollectionOptions, actor models.Actor) (*models.Collection, error) {
+	return s.repo.CreateWithOptions(ctx, name, options, actor)
+}
+
+// UpdateOptions replaces the options of a collection
+func (s *CollectionService) UpdateOptions(ctx context.Context, name string, options models.CollectionOptions, actor models.Actor) (*models.Collection, error) {
+	return s.repo.UpdateOptions(ctx, name, options, actor)
 }
 
 // GetByName retrieves a collection by name
-func (s *CollectionService) GetByName(name string) (*models.Collection, error) {
-	return s.repo.GetByName(name)
+func (s *CollectionService) GetByName(ctx context.Context, name string) (*models.Collection, error) {
+	return s.repo.GetByName(ctx, name)
 }
 
 // Delete deletes a collection
-func (s *CollectionService) Delete(name string) error {
-	return s.repo.Delete(name)
+func (s *CollectionService) Delete(ctx context.Context, name string, actor models.Actor) error {
+	return s.repo.Delete(ctx, name, actor)
 }
 
 // List retrieves all collections
-func (s *CollectionService) List() (*models.CollectionList, error) {
-	return s.repo.List()
+func (s *CollectionService) List(ctx context.Context) (*models.CollectionList, error) {
+	return s.repo.List(ctx)
 }
 
 // Exists checks if a collection exists
-func (s *CollectionService) Exists(name string) (bool, error) {
-	return s.repo.Exists(name)
+func (s *CollectionService) Exists(ctx context.Context, name string) (bool, error) {
+	return s.repo.Exists(ctx, name)
+}
+
+// RestrictAccess returns the document access to enforce in a collection, or nil when it is unrestricted
+func (s *CollectionService) RestrictAccess(ctx context.Context, name string, access *models.DocumentAccess) (*models.DocumentAccess, error) {
+	return s.repo.RestrictAccess(ctx, name, access)
+}
+
+// DocumentCounts returns the number of live documents in each collection
+func (s *CollectionService) DocumentCounts(ctx context.Context) (map[string]int, error) {
+	return s.repo.DocumentCounts(ctx)
 }
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
+	"time"
 
 	"github.com/rbehzadan/flexstore/internal/db"
 	"github.com/rbehzadan/flexstore/internal/models"
@@ -18,43 +21,82 @@ func NewDocumentService(repo *db.DocumentRepository) *DocumentService {
 	return &DocumentService{repo: repo}
 }
 
-// Create creates a new document
-func (s *DocumentService) Create(collectionName string, data json.RawMessage) (*models.Document, error) {
-	return s.repo.Create(collectionName, data)
+// Create creates a new document owned by the caller
+func (s *DocumentService) Create(ctx context.Context, collectionName string, data json.RawMessage, expiresAt *time.Time, access *models.DocumentAccess, actor models.Actor) (*models.Document, error) {
+	return s.repo.Create(ctx, collectionName, data, expiresAt, access.Owner(), actor)
 }
 
-// CreateWithID creates a new document with the specified ID
-func (s *DocumentService) CreateWithID(id, collectionName string, data json.RawMessage) (*models.Document, error) {
-	return s.repo.CreateWithID(id, collectionName, data)
+// CreateWithID creates a new document with the specified ID owned by the caller
+func (s *DocumentService) CreateWithID(ctx context.Context, id, collectionName string, data json.RawMessage, expiresAt *time.Time, access *models.DocumentAccess, actor models.Actor) (*models.Document, error) {
+	return s.repo.CreateWithID(ctx, id, collectionName, data, expiresAt, access.Owner(), actor)
 }
 
 // GetByID retrieves a document by ID
-func (s *DocumentService) GetByID(id, collectionName string) (*models.Document, error) {
-	return s.repo.GetByID(id, collectionName)
+func (s *DocumentService) GetByID(ctx context.Context, id, collectionName string, access *models.DocumentAccess) (*models.Document, error) {
+	if err := s.checkAccess(ctx, id, collectionName, access, false); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id, collectionName)
+}
+
+// GetByIDAsOf retrieves a document as it was at the given time
+func (s *DocumentService) GetByIDAsOf(ctx context.Context, id, collectionName string, asOf time.Time, access *models.DocumentAccess) (*models.Document, error) {
+	return s.repo.GetByIDAsOf(ctx, id, collectionName, asOf, access)
+}
+
+// Update updates a document the caller may see
+func (s *DocumentService) Update(ctx context.Context, id, collectionName string, data json.RawMessage, expiresAt *time.Time, access *models.DocumentAccess, actor models.Actor) (*models.Document, error) {
+	return s.repo.Update(ctx, id, collectionName, data, expiresAt, access, actor)
+}
+
+// Delete deletes a document; only its owner may delete it
+func (s *DocumentService) Delete(ctx context.Context, id, collectionName string, access *models.DocumentAccess, actor models.Actor) error {
+	if err := s.checkAccess(ctx, id, collectionName, access, true); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id, collectionName, actor)
 }
 
-// Update updates a document
-func (s *DocumentService) Update(id, collectionName string, data json.RawMessage) (*models.Document, error) {
-	return s.repo.Update(id, collectionName, data)
+// Share replaces the subjects a document is shared with; only its owner may share it
+func (s *DocumentService) Share(ctx context.Context, id, collectionName string, sharedWith []string, access *models.DocumentAccess, actor models.Actor) (*models.Document, error) {
+	return s.repo.Share(ctx, id, collectionName, sharedWith, access, actor)
 }
 
-// Delete deletes a document
-func (s *DocumentService) Delete(id, collectionName string) error {
-	return s.repo.Delete(id, collectionName)
+// checkAccess reports a document as not found unless the caller may see it, or own it
+// when owner is set, in a collection with ownership enabled
+func (s *DocumentService) checkAccess(ctx context.Context, id, collectionName string, access *models.DocumentAccess, owner bool) error {
+	access, err := s.repo.RestrictAccess(ctx, collectionName, access)
+	if err != nil || access == nil {
+		return err
+	}
+
+	document, err := s.repo.GetOwnership(ctx, id, collectionName)
+	if err != nil {
+		return err
+	}
+	if !access.CanSee(document) || (owner && !access.Owns(document)) {
+		return fmt.Errorf("document with ID '%s' not found in collection '%s'", id, collectionName)
+	}
+	return nil
 }
 
 // List retrieves documents from a collection with pagination
-func (s *DocumentService) List(collectionName string, queryParams *models.DocumentQuery) (*models.DocumentList, error) {
-	return s.repo.List(collectionName, queryParams)
+func (s *DocumentService) List(ctx context.Context, collectionName string, queryParams *models.DocumentQuery) (*models.DocumentList, error) {
+	return s.repo.List(ctx, collectionName, queryParams)
+}
+
+// BulkCreate creates multiple documents in a collection owned by the caller
+func (s *DocumentService) BulkCreate(ctx context.Context, collectionName string, dataItems []json.RawMessage, access *models.DocumentAccess, actor models.Actor) ([]models.Document, error) {
+	return s.repo.BulkCreate(ctx, collectionName, dataItems, access.Owner(), actor)
 }
 
-// BulkCreate creates multiple documents in a collection
-func (s *DocumentService) BulkCreate(collectionName string, dataItems []json.RawMessage) ([]models.Document, error) {
-	return s.repo.BulkCreate(collectionName, dataItems)
+// Limits returns the limits on request bodies and documents written to a collection
+func (s *DocumentService) Limits(ctx context.Context, collectionName string) (models.DocumentLimits, error) {
+	return s.repo.Limits(ctx, collectionName)
 }
 
 // ProcessJSONFile processes a JSON file for bulk insertion
-func (s *DocumentService) ProcessJSONFile(collectionName string, r io.Reader) ([]models.Document, error) {
+func (s *DocumentService) ProcessJSONFile(ctx context.Context, collectionName string, r io.Reader, access *models.DocumentAccess, actor models.Actor) ([]models.Document, error) {
 	// Read the file content
 	data, err := io.ReadAll(r)
 	if err != nil {
@@ -81,5 +123,41 @@ func (s *DocumentService) ProcessJSONFile(collectionName string, r io.Reader) ([
 	}
 
 	// Bulk create documents
-	return s.BulkCreate(collectionName, jsonArray)
+	return s.BulkCreate(ctx, collectionName, jsonArray, access, actor)
+}
+
+// ListRevisions retrieves the revision history of a document
+func (s *DocumentService) ListRevisions(ctx context.Context, id, collectionName string, access *models.DocumentAccess) (*models.RevisionList, error) {
+	if err := s.checkAccess(ctx, id, collectionName, access, false); err != nil {
+		return nil, err
+	}
+	return s.repo.ListRevisions(ctx, id, collectionName)
+}
+
+// GetRevision retrieves a single revision of a document
+func (s *DocumentService) GetRevision(ctx context.Context, id, collectionName string, revision int, access *models.DocumentAccess) (*models.Revision, error) {
+	if err := s.checkAccess(ctx, id, collectionName, access, false); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRevision(ctx, id, collectionName, revision)
+}
+
+// RestoreRevision makes a previous revision the current version of a document
+func (s *DocumentService) RestoreRevision(ctx context.Context, id, collectionName string, revision int, access *models.DocumentAccess, actor models.Actor) (*models.Document, error) {
+	if err := s.checkAccess(ctx, id, collectionName, access, false); err != nil {
+		return nil, err
+	}
+	return s.repo.RestoreRevision(ctx, id, collectionName, revision, access.Owner(), actor)
+}
+
+// SweepExpired deletes expired documents in batches of batchSize and returns the number deleted
+func (s *DocumentService) SweepExpired(ctx context.Context, batchSize int) (int, error) {
+	total := 0
+	for {
+		deleted, err := s.repo.DeleteExpired(ctx, time.Now(), batchSize)
+		total += deleted
+		if err != nil || deleted < batchSize {
+			return total, err
+		}
+	}
 }
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// ReplicationService handles change log and replication operations
+type ReplicationService struct {
+	repo *db.ChangeRepository
+}
+
+// NewReplicationService creates a new replication service
+func NewReplicationService(repo *db.ChangeRepository) *ReplicationService {
+	return &ReplicationService{repo: repo}
+}
+
+// ListChanges retrieves changes recorded after the given sequence number. It returns
+// models.ErrSnapshotRequired when some of them have been pruned.
+func (s *ReplicationService) ListChanges(ctx context.Context, since int64, limit int) (*models.ChangeList, error) {
+	return s.repo.ListSince(ctx, since, limit)
+}
+
+// PruneChanges deletes changes older than retention from the change log
+func (s *ReplicationService) PruneChanges(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.Prune(ctx, time.Now().Add(-retention))
+}
+
+// Snapshot passes the position in the change log, then every collection and document, and
+// finally the end of the snapshot to fn
+func (s *ReplicationService) Snapshot(ctx context.Context, fn func(*models.SnapshotEntry) error) error {
+	return s.repo.Snapshot(ctx, fn)
+}
+
+// GetCheckpoint returns the position in the change log up to which changes from a source
+// have been applied, or nil when nothing has been applied yet
+func (s *ReplicationService) GetCheckpoint(ctx context.Context, source string) (*models.ChangeCheckpoint, error) {
+	return s.repo.GetCheckpoint(ctx, source)
+}
+
+// ApplyChanges applies replicated changes and advances the checkpoint for a source
+func (s *ReplicationService) ApplyChanges(ctx context.Context, source string, changes []models.Change) error {
+	return s.repo.ApplyBatch(ctx, source, changes)
+}
+
+// ApplySnapshot replaces all collections and documents with a snapshot from a source, read
+// through next up to its end, and returns the position the snapshot reflects
+func (s *ReplicationService) ApplySnapshot(ctx context.Context, source string, next func() (*models.SnapshotEntry, error)) (*models.ChangeCheckpoint, error) {
+	return s.repo.ApplySnapshot(ctx, source, next)
+}
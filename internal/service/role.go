@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// RoleService handles roles, their assignments and the permissions they grant
+type RoleService struct {
+	repo *db.RoleRepository
+}
+
+// NewRoleService creates a new role service
+func NewRoleService(repo *db.RoleRepository) *RoleService {
+	return &RoleService{repo: repo}
+}
+
+// Create defines a new role
+func (s *RoleService) Create(ctx context.Context, name string, grants []models.Grant) (*models.Role, error) {
+	if name == "" {
+		return nil, fmt.Errorf("role name cannot be empty")
+	}
+	if _, ok := models.BuiltinRoles[name]; ok {
+		return nil, fmt.Errorf("role '%s' is built in", name)
+	}
+	if err := models.ValidateGrants(grants); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	role := models.Role{
+		Name:      name,
+		Grants:    grants,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Create(ctx, &role); err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// GetByName retrieves a built-in or stored role
+func (s *RoleService) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	if role, ok := models.BuiltinRoles[name]; ok {
+		return &role, nil
+	}
+	return s.repo.GetByName(ctx, name)
+}
+
+// List retrieves the built-in roles followed by the stored roles
+func (s *RoleService) List(ctx context.Context) (*models.RoleList, error) {
+	stored, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]models.Role, 0, len(models.BuiltinRoles)+len(stored))
+	for _, name := range []string{"admin", "reader", "writer"} {
+		roles = append(roles, models.BuiltinRoles[name])
+	}
+	roles = append(roles, stored...)
+
+	return &models.RoleList{
+		Total: len(roles),
+		Roles: roles,
+	}, nil
+}
+
+// Update replaces the grants of a stored role
+func (s *RoleService) Update(ctx context.Context, name string, grants []models.Grant) (*models.Role, error) {
+	if _, ok := models.BuiltinRoles[name]; ok {
+		return nil, fmt.Errorf("role '%s' is built in and cannot be changed", name)
+	}
+	if err := models.ValidateGrants(grants); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, name, grants)
+}
+
+// Delete removes a stored role and its assignments
+func (s *RoleService) Delete(ctx context.Context, name string) error {
+	if _, ok := models.BuiltinRoles[name]; ok {
+		return fmt.Errorf("role '%s' is built in and cannot be deleted", name)
+	}
+	return s.repo.Delete(ctx, name)
+}
+
+// Assign assigns a role to a subject such as user:alice or key:<id>
+func (s *RoleService) Assign(ctx context.Context, name, subject string) error {
+	if err := models.ValidateSubject(subject); err != nil {
+		return err
+	}
+	if _, err := s.GetByName(ctx, name); err != nil {
+		return err
+	}
+	return s.repo.Assign(ctx, name, subject)
+}
+
+// Unassign removes a role from a subject
+func (s *RoleService) Unassign(ctx context.Context, name, subject string) error {
+	return s.repo.Unassign(ctx, name, subject)
+}
+
+// Assignments lists the subjects a role is assigned to
+func (s *RoleService) Assignments(ctx context.Context, name string) (*models.RoleAssignmentList, error) {
+	if _, err := s.GetByName(ctx, name); err != nil {
+		return nil, err
+	}
+	subjects, err := s.repo.Subjects(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RoleAssignmentList{Role: name, Subjects: subjects}, nil
+}
+
+// RolesOf lists the names of the roles assigned to a subject
+func (s *RoleService) RolesOf(ctx context.Context, subject string) ([]string, error) {
+	return s.repo.RolesOf(ctx, subject)
+}
+
+// GrantsFor resolves the grants of the roles carried by a principal's credential and the
+// roles assigned to it; unknown role names grant nothing
+func (s *RoleService) GrantsFor(ctx context.Context, principal *auth.Principal) ([]models.Grant, error) {
+	assigned, err := s.repo.RolesOf(ctx, principal.Subject())
+	if err != nil {
+		return nil, err
+	}
+
+	names := slices.Clone(principal.Roles)
+	for _, name := range assigned {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	grants := make([]models.Grant, 0)
+	for _, name := range names {
+		if role, ok := models.BuiltinRoles[name]; ok {
+			grants = append(grants, role.Grants...)
+			continue
+		}
+		role, err := s.repo.GetByName(ctx, name)
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		grants = append(grants, role.Grants...)
+	}
+
+	return grants, nil
+}
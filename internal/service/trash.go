@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// TrashService handles soft-deleted collections and documents
+type TrashService struct {
+	repo *db.TrashRepository
+}
+
+// NewTrashService creates a new trash service
+func NewTrashService(repo *db.TrashRepository) *TrashService {
+	return &TrashService{repo: repo}
+}
+
+// ListCollections retrieves all collections in the trash
+func (s *TrashService) ListCollections(ctx context.Context) (*models.CollectionList, error) {
+	return s.repo.ListCollections(ctx)
+}
+
+// ListDocuments retrieves trashed documents of a collection
+func (s *TrashService) ListDocuments(ctx context.Context, collectionName string, query *models.DocumentQuery) (*models.DocumentList, error) {
+	return s.repo.ListDocuments(ctx, collectionName, query)
+}
+
+// RestoreCollection restores a trashed collection and the documents deleted with it
+func (s *TrashService) RestoreCollection(ctx context.Context, name string, actor models.Actor) error {
+	return s.repo.RestoreCollection(ctx, name, actor)
+}
+
+// RestoreDocument restores a trashed document; only its owner may restore it
+func (s *TrashService) RestoreDocument(ctx context.Context, id, collectionName string, access *models.DocumentAccess, actor models.Actor) (*models.Document, error) {
+	if err := s.checkOwner(ctx, id, collectionName, access); err != nil {
+		return nil, err
+	}
+	return s.repo.RestoreDocument(ctx, id, collectionName, actor)
+}
+
+// PurgeCollection permanently deletes a trashed collection
+func (s *TrashService) PurgeCollection(ctx context.Context, name string, actor models.Actor) error {
+	return s.repo.PurgeCollection(ctx, name, actor)
+}
+
+// PurgeDocument permanently deletes a trashed document; only its owner may purge it
+func (s *TrashService) PurgeDocument(ctx context.Context, id, collectionName string, access *models.DocumentAccess, actor models.Actor) error {
+	if err := s.checkOwner(ctx, id, collectionName, access); err != nil {
+		return err
+	}
+	return s.repo.PurgeDocument(ctx, id, collectionName, actor)
+}
+
+// RestrictAccess returns the document access to enforce in a collection, or nil when it is unrestricted
+func (s *TrashService) RestrictAccess(ctx context.Context, collectionName string, access *models.DocumentAccess) (*models.DocumentAccess, error) {
+	return s.repo.RestrictAccess(ctx, collectionName, access)
+}
+
+// checkOwner reports a trashed document as not found unless the caller owns it
+// in a collection with ownership enabled
+func (s *TrashService) checkOwner(ctx context.Context, id, collectionName string, access *models.DocumentAccess) error {
+	access, err := s.repo.RestrictAccess(ctx, collectionName, access)
+	if err != nil || access == nil {
+		return err
+	}
+
+	document, err := s.repo.GetOwnership(ctx, id, collectionName)
+	if err != nil {
+		return err
+	}
+	if !access.Owns(document) {
+		return fmt.Errorf("document with ID '%s' not found in trash of collection '%s'", id, collectionName)
+	}
+	return nil
+}
+
+// PurgeExpired permanently deletes everything that has been in the trash longer than retention
+func (s *TrashService) PurgeExpired(ctx context.Context, retention time.Duration) (int, int, error) {
+	return s.repo.PurgeExpired(ctx, time.Now().Add(-retention))
+}
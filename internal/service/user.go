@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/auth"
+	"github.com/rbehzadan/flexstore/internal/db"
+	"github.com/rbehzadan/flexstore/internal/models"
+)
+
+// errInvalidCredentials is returned for every failed login so callers cannot tell why it failed
+var errInvalidCredentials = fmt.Errorf("invalid username or password")
+
+// UserService handles user accounts and password authentication
+type UserService struct {
+	repo  *db.UserRepository
+	roles *RoleService
+
+	// verified remembers the last successful login of each user, so basic auth, which sends
+	// the password with every request, pays for the slow password hash only once
+	mu       sync.Mutex
+	verified map[string][sha256.Size]byte
+
+	// dummyHash is checked for unknown users so response times do not reveal which users exist
+	dummyOnce sync.Once
+	dummyHash string
+}
+
+// NewUserService creates a new user service
+func NewUserService(repo *db.UserRepository, roles *RoleService) *UserService {
+	return &UserService{
+		repo:     repo,
+		roles:    roles,
+		verified: make(map[string][sha256.Size]byte),
+	}
+}
+
+// Create creates a user and assigns it the given roles
+func (s *UserService) Create(ctx context.Context, username, password string, roles []string) (*models.User, error) {
+	if username == "" || strings.Contains(username, ":") {
+		return nil, fmt.Errorf("username must be non-empty and cannot contain ':'")
+	}
+	if len(password) < auth.MinPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", auth.MinPasswordLength)
+	}
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := models.User{
+		Username:  username,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Create(ctx, &user, passwordHash, userSubject(username), roles); err != nil {
+		return nil, err
+	}
+
+	return s.withRoles(ctx, &user)
+}
+
+// Bootstrap creates the first administrator when there are no users yet. Without a password
+// a random one is generated and returned, so it can be shown once.
+func (s *UserService) Bootstrap(ctx context.Context, username, password string) (created bool, generated string, err error) {
+	count, err := s.repo.Count(ctx)
+	if err != nil {
+		return false, "", err
+	}
+	if count > 0 {
+		return false, "", nil
+	}
+
+	if password == "" {
+		if password, err = auth.GeneratePassword(); err != nil {
+			return false, "", err
+		}
+		generated = password
+	}
+
+	if _, err := s.Create(ctx, username, password, []string{"admin"}); err != nil {
+		return false, "", fmt.Errorf("failed to create bootstrap admin: %w", err)
+	}
+
+	return true, generated, nil
+}
+
+// GetByUsername retrieves a user with its roles
+func (s *UserService) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	user, err := s.repo.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return s.withRoles(ctx, user)
+}
+
+// List retrieves all users with their roles
+func (s *UserService) List(ctx context.Context) (*models.UserList, error) {
+	users, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range users {
+		if _, err := s.withRoles(ctx, &users[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return &models.UserList{
+		Total: len(users),
+		Users: users,
+	}, nil
+}
+
+// ResetPassword replaces the password of a user
+func (s *UserService) ResetPassword(ctx context.Context, username, password string) (*models.User, error) {
+	if len(password) < auth.MinPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", auth.MinPasswordLength)
+	}
+
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.SetPassword(ctx, username, passwordHash)
+	if err != nil {
+		return nil, err
+	}
+	return s.withRoles(ctx, user)
+}
+
+// Disable prevents a user from authenticating
+func (s *UserService) Disable(ctx context.Context, username string) (*models.User, error) {
+	user, err := s.repo.SetDisabled(ctx, username, true)
+	if err != nil {
+		return nil, err
+	}
+	return s.withRoles(ctx, user)
+}
+
+// Enable allows a disabled user to authenticate again
+func (s *UserService) Enable(ctx context.Context, username string) (*models.User, error) {
+	user, err := s.repo.SetDisabled(ctx, username, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.withRoles(ctx, user)
+}
+
+// Authenticate checks a username and password and returns the active user they belong to
+func (s *UserService) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	user, passwordHash, err := s.repo.GetCredentials(ctx, username)
+	if ctx.Err() != nil {
+		return nil, err
+	}
+	if err != nil {
+		s.dummyOnce.Do(func() {
+			s.dummyHash, _ = auth.HashPassword("flexstore")
+		})
+		auth.VerifyPassword(password, s.dummyHash)
+		return nil, errInvalidCredentials
+	}
+	if user.Disabled {
+		// Check the password anyway, so response times do not reveal which users are disabled
+		auth.VerifyPassword(password, passwordHash)
+		return nil, errInvalidCredentials
+	}
+
+	// The digest covers the stored hash, so a password reset invalidates it
+	digest := sha256.Sum256([]byte(password + "\x00" + passwordHash))
+	s.mu.Lock()
+	cached, ok := s.verified[username]
+	s.mu.Unlock()
+	if ok && cached == digest {
+		return user, nil
+	}
+
+	valid, err := auth.VerifyPassword(password, passwordHash)
+	if err != nil || !valid {
+		return nil, errInvalidCredentials
+	}
+
+	s.mu.Lock()
+	s.verified[username] = digest
+	s.mu.Unlock()
+
+	return user, nil
+}
+
+// withRoles fills in the roles assigned to a user
+func (s *UserService) withRoles(ctx context.Context, user *models.User) (*models.User, error) {
+	roles, err := s.roles.RolesOf(ctx, userSubject(user.Username))
+	if err != nil {
+		return nil, err
+	}
+	user.Roles = roles
+	return user, nil
+}
+
+// userSubject returns the role assignment subject of a user
+func userSubject(username string) string {
+	return "user:" + username
+}
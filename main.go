@@ -16,24 +16,35 @@ import (
 var versionFile embed.FS
 
 func main() {
-	// Define command line flags
+	// Read version from embedded file
+	versionBytes, err := versionFile.ReadFile("VERSION")
+	if err != nil {
+		panic(err)
+	}
+	version := strings.TrimSpace(string(versionBytes))
+
+	// Handle subcommands
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "config" {
+		os.Exit(runConfigCommand(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "migrate" {
+		os.Exit(runMigrateCommand(args[1:]))
+	}
+
+	// Define command line flags; the settings are added by config.Load
 	var (
 		showVersion = flag.Bool("version", false, "Show version information")
 		showHelp    = flag.Bool("help", false, "Show help information")
-		addr        = flag.String("addr", ":8080", "HTTP service address")
-		enableAuth  = flag.Bool("auth", true, "Enable HTTP Basic Authentication")
-		username    = flag.String("username", "admin", "Username for HTTP Basic Authentication")
-		password    = flag.String("password", "password", "Password for HTTP Basic Authentication")
+		verifyAudit = flag.Bool("verify-audit", false, "Verify the hash chain of the audit log and exit")
 	)
 
-	flag.Parse()
-
-	// Read version from embedded file
-	versionBytes, err := versionFile.ReadFile("VERSION")
+	// Load defaults, the config file, environment variables and flags
+	cfg, err := config.Load(flag.CommandLine, args, os.Environ())
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	version := strings.TrimSpace(string(versionBytes))
 
 	// Handle version flag
 	if *showVersion {
@@ -47,15 +58,74 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Initialize configuration
-	cfg := config.NewConfig()
+	// Check the configuration before using any of it
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 	cfg.Version = version
 	cfg.StartTime = time.Now()
-	cfg.Addr = *addr
-	cfg.EnableBasicAuth = *enableAuth
-	cfg.AuthUsername = *username
-	cfg.AuthPassword = *password
+
+	// Verify the audit log instead of serving
+	if *verifyAudit {
+		os.Exit(server.VerifyAudit(cfg))
+	}
 
 	// Start the server with the initialized config
-	server.Run(cfg)
+	os.Exit(server.Run(cfg))
+}
+
+// runConfigCommand runs a config subcommand and returns the exit code
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "Usage: flexstore config print [flags]")
+		return 2
+	}
+
+	// Print the configuration the same flags, environment and config file would give the server
+	flags := flag.NewFlagSet("config print", flag.ExitOnError)
+	cfg, err := config.Load(flags, args[1:], os.Environ())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	// Report problems after the configuration, which helps to find them
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// runMigrateCommand runs a migrate subcommand and returns the exit code
+func runMigrateCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "status" && args[0] != "up" && args[0] != "down") {
+		fmt.Fprintln(os.Stderr, "Usage: flexstore migrate status|up|down [-steps n] [flags]")
+		return 2
+	}
+	action := args[0]
+
+	// The database is named by the same flags, environment and config file as for the server
+	flags := flag.NewFlagSet("migrate "+action, flag.ExitOnError)
+	steps := flags.Int("steps", 1, "Number of migrations to revert with down")
+	cfg, err := config.Load(flags, args[1:], os.Environ())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if *steps < 1 {
+		fmt.Fprintln(os.Stderr, "-steps must be at least 1")
+		return 2
+	}
+
+	return server.Migrate(cfg, action, *steps)
 }
@@ -10,9 +10,96 @@ type Config struct {
 	StartTime       time.Time
 	Addr            string
 	SqlitePath      string
-	AuthUsername    string
-	AuthPassword    string
 	EnableBasicAuth bool
+
+	// Database settings; reads are served by DBReadConnections read-only connections while
+	// a single connection writes. DBSynchronous is OFF, NORMAL, FULL or EXTRA.
+	DBReadConnections int
+	DBBusyTimeout     time.Duration
+	DBSynchronous     string
+
+	// Credentials of the admin user created when the users table is empty; an empty
+	// password is replaced by a generated one
+	AuthUsername string
+	AuthPassword string
+
+	// Auth policy; public paths and anonymous methods are served without authentication
+	AuthPublicPaths      []string
+	AuthAnonymousMethods []string
+
+	// JWT settings; a non-empty JWTKeysFile enables bearer token authentication
+	JWTKeysFile         string
+	JWTIssuer           string
+	JWTAudience         string
+	JWTRolesClaim       string
+	JWTCollectionsClaim string
+
+	// TLS settings; a non-empty TLSCertFile serves HTTPS. With TLSClientCAFile set, client
+	// certificates are verified against it and authenticate their common name as a user.
+	TLSCertFile          string
+	TLSKeyFile           string
+	TLSMinVersion        string
+	TLSClientCAFile      string
+	TLSRequireClientCert bool
+	TLSReloadInterval    time.Duration
+
+	// CORS settings; CORSAllowedOrigins lists the origins browsers may call the API from,
+	// and CORS is disabled when it is empty
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration
+
+	// Replication settings; a non-empty ReplicateFrom runs the instance as a read-only follower.
+	// A primary prunes changes older than ChangeRetention, zero keeping them forever.
+	ReplicateFrom       string
+	ReplicationInterval time.Duration
+	ChangeRetention     time.Duration
+
+	// Trash settings; with SoftDelete enabled deleted items are kept for TrashRetention
+	SoftDelete     bool
+	TrashRetention time.Duration
+
+	// ExpirySweepInterval is how often expired documents are deleted
+	ExpirySweepInterval time.Duration
+
+	// Limits on request bodies and documents; zero means unlimited. Collections can tighten
+	// them through their options.
+	MaxRequestBytes  int64
+	MaxDocumentBytes int64
+	MaxJSONDepth     int
+	MaxArrayLength   int
+	MaxObjectKeys    int
+
+	// Rate limits per client in requests per minute; zero disables the limit
+	RateLimitReads  int
+	RateLimitWrites int
+	RateLimitBulk   int
+
+	// RateLimitAuthFailures is the number of failed authentications allowed per IP address
+	// per minute; zero disables the limit
+	RateLimitAuthFailures int
+
+	// Database timeouts per request class, covering every query a request runs; zero
+	// disables the timeout
+	QueryTimeoutRead  time.Duration
+	QueryTimeoutWrite time.Duration
+	QueryTimeoutBulk  time.Duration
+
+	// ShutdownTimeout is how long requests in flight and background workers may take to
+	// finish on shutdown; zero waits indefinitely
+	ShutdownTimeout time.Duration
+
+	// Readiness check settings; each check gets HealthTimeout, and the disk holding the
+	// database must have HealthMinFreeBytes available, zero disabling the disk check
+	HealthTimeout      time.Duration
+	HealthMinFreeBytes int64
+
+	// Logging settings; LogFormat is text or json and LogLevel is debug, info, warn or error
+	LogFormat string
+	LogLevel  string
 }
 
 // NewConfig creates a new Config with default values
@@ -23,8 +110,49 @@ func NewConfig() *Config {
 		Addr:            ":8080",
 		SqlitePath:      "data/db.sqlite",
 		AuthUsername:    "admin",
-		AuthPassword:    "password",
-		EnableBasicAuth: false,
+		EnableBasicAuth: true,
+
+		DBReadConnections: 4,
+		DBBusyTimeout:     5 * time.Second,
+		DBSynchronous:     "NORMAL",
+
+		AuthPublicPaths: []string{"/health", "/health/*"},
+
+		JWTRolesClaim:       "roles",
+		JWTCollectionsClaim: "collections",
+
+		TLSMinVersion:     "1.2",
+		TLSReloadInterval: time.Minute,
+
+		MaxRequestBytes:  32 << 20,
+		MaxDocumentBytes: 16 << 20,
+		MaxJSONDepth:     64,
+
+		CORSAllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "DELETE"},
+		CORSAllowedHeaders: []string{"Authorization", "Content-Type", "X-API-Key", "X-Request-ID"},
+		CORSExposedHeaders: []string{"ETag", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "X-Request-ID"},
+		CORSMaxAge:         10 * time.Minute,
+
+		ReplicationInterval: time.Second,
+		ChangeRetention:     7 * 24 * time.Hour,
+
+		TrashRetention: 30 * 24 * time.Hour,
+
+		ExpirySweepInterval: time.Minute,
+
+		RateLimitAuthFailures: 10,
+
+		QueryTimeoutRead:  5 * time.Second,
+		QueryTimeoutWrite: 10 * time.Second,
+		QueryTimeoutBulk:  10 * time.Second,
+
+		ShutdownTimeout: 30 * time.Second,
+
+		HealthTimeout:      2 * time.Second,
+		HealthMinFreeBytes: 100 << 20,
+
+		LogFormat: "text",
+		LogLevel:  "info",
 	}
 }
 
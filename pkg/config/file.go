@@ -0,0 +1,298 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// fileEntry is a setting read from a config file
+type fileEntry struct {
+	key   string
+	value string
+	line  int
+}
+
+// readFile reads the settings of a config file. The format follows the extension: .json,
+// .yaml or .yml, or .toml. Every setting is a scalar or a list, so YAML and TOML files are
+// read as flat key/value files; nested mappings and tables are rejected.
+func readFile(path string) ([]fileEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return parseJSON(data)
+	case ".yaml", ".yml":
+		return parseYAML(data)
+	case ".toml":
+		return parseTOML(data)
+	default:
+		return nil, fmt.Errorf("unknown config file format %q: use .json, .yaml, .yml or .toml", filepath.Ext(path))
+	}
+}
+
+// parseJSON reads a JSON object of settings
+func parseJSON(data []byte) ([]fileEntry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	entries := make([]fileEntry, 0, len(object))
+	for key, raw := range object {
+		value, err := jsonText(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		entries = append(entries, fileEntry{key: key, value: value})
+	}
+	return entries, nil
+}
+
+// jsonText converts a JSON value to the text of a setting; arrays become comma-separated lists
+func jsonText(raw any) (string, error) {
+	switch v := raw.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			if _, ok := item.([]any); ok {
+				return "", fmt.Errorf("nested arrays are not supported")
+			}
+			text, err := jsonText(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("nested objects are not supported")
+	}
+}
+
+// parseYAML reads a flat YAML mapping. Values are scalars, flow sequences such as
+// [a, b] or block sequences of "- item" lines below the key.
+func parseYAML(data []byte) ([]fileEntry, error) {
+	var entries []fileEntry
+	var list *fileEntry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := stripComment(scanner.Text())
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		// Items of a block sequence
+		indented := line[0] == ' ' || line[0] == '\t'
+		if indented {
+			item, ok := strings.CutPrefix(trimmed, "-")
+			if !ok || list == nil {
+				return nil, fmt.Errorf("line %d: nested mappings are not supported", n)
+			}
+			value, err := parseScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			if list.value != "" {
+				list.value += ","
+			}
+			list.value += value
+			continue
+		}
+
+		key, raw, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+		entries = append(entries, fileEntry{key: strings.TrimSpace(key), line: n})
+		entry := &entries[len(entries)-1]
+
+		// A key without a value starts a block sequence
+		raw = strings.TrimSpace(raw)
+		list = nil
+		if raw == "" {
+			list = entry
+			continue
+		}
+
+		value, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		entry.value = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseTOML reads top-level TOML key/value pairs. Arrays may span lines.
+func parseTOML(data []byte) ([]fileEntry, error) {
+	var entries []fileEntry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: tables are not supported", n)
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		start := n
+		raw = strings.TrimSpace(raw)
+
+		// Collect the lines of a multi-line array
+		for strings.HasPrefix(raw, "[") && !strings.HasSuffix(raw, "]") {
+			if !scanner.Scan() {
+				return nil, fmt.Errorf("line %d: unterminated array", start)
+			}
+			n++
+			raw += " " + strings.TrimSpace(stripComment(scanner.Text()))
+		}
+
+		value, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		entries = append(entries, fileEntry{key: unquoteKey(strings.TrimSpace(key)), value: value, line: start})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseValue parses a scalar or an inline array, which becomes a comma-separated list
+func parseValue(raw string) (string, error) {
+	inner, ok := strings.CutPrefix(raw, "[")
+	if !ok {
+		return parseScalar(raw)
+	}
+	inner, ok = strings.CutSuffix(inner, "]")
+	if !ok {
+		return "", fmt.Errorf("unterminated array")
+	}
+
+	items, err := splitItems(inner)
+	if err != nil {
+		return "", err
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		value, err := parseScalar(item)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, ","), nil
+}
+
+// parseScalar parses a plain, double-quoted or single-quoted scalar
+func parseScalar(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted string %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid quoted string %s", raw)
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	case strings.HasPrefix(raw, "{"), strings.HasPrefix(raw, "["):
+		return "", fmt.Errorf("nested values are not supported")
+	}
+	return raw, nil
+}
+
+// splitItems splits the items of an inline array on commas outside quotes
+func splitItems(inner string) ([]string, error) {
+	var items []string
+	var quote rune
+	start := 0
+	for i, c := range inner {
+		switch {
+		case quote != 0:
+			if c == quote && (quote == '\'' || i == 0 || inner[i-1] != '\\') {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, strings.TrimSpace(inner[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+
+	// Allow a trailing comma
+	if last := strings.TrimSpace(inner[start:]); last != "" {
+		items = append(items, last)
+	}
+	for _, item := range items {
+		if item == "" {
+			return nil, fmt.Errorf("empty array item")
+		}
+	}
+	return items, nil
+}
+
+// stripComment removes a # comment outside quotes from a line. Quotes only open a quoted
+// value at its start, so apostrophes inside plain values are not taken for quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote && (quote == '\'' || line[i-1] != '\\') {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" \t:=[,", rune(line[i-1]))):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquoteKey removes the quotes of a quoted TOML key
+func unquoteKey(key string) string {
+	if value, err := parseScalar(key); err == nil {
+		return value
+	}
+	return key
+}
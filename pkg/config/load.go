@@ -0,0 +1,172 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// envPrefix starts the environment variables that configure the server
+const envPrefix = "FLEXSTORE_"
+
+// configFileSetting names the config file, by flag or by environment variable
+const configFileSetting = "config"
+
+// flagValue records the text of a flag, which is applied after the config file and the
+// environment variables
+type flagValue struct {
+	defValue string
+	isBool   bool
+	value    *string
+}
+
+func (f *flagValue) Set(s string) error {
+	f.value = &s
+	return nil
+}
+
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	if f.value != nil {
+		return *f.value
+	}
+	return f.defValue
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f != nil && f.isBool
+}
+
+// Load builds the configuration in layers: defaults, then the config file, then FLEXSTORE_*
+// environment variables, then command line flags. The settings are registered as flags on fs,
+// which may hold other flags of the caller, and args are parsed with it. environ holds the
+// environment as "KEY=value" entries, as returned by os.Environ. Callers check the result
+// with Validate.
+func Load(fs *flag.FlagSet, args []string, environ []string) (*Config, error) {
+	cfg := NewConfig()
+	settings := cfg.settings()
+	byName := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byName[s.name] = s
+	}
+
+	// Register the settings as flags, showing the defaults in the help
+	for _, s := range settings {
+		_, isBool := s.value.(*boolValue)
+		fs.Var(&flagValue{defValue: s.value.String(), isBool: isBool}, s.name, s.usage)
+	}
+	configFile := fs.String(configFileSetting, "", "Config file in JSON, YAML or TOML format; also set by "+envName(configFileSetting))
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	for _, entry := range environ {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			env[key] = value
+		}
+	}
+
+	// Config file
+	path := *configFile
+	if path == "" {
+		path = env[envName(configFileSetting)]
+	}
+	if path != "" {
+		if err := cfg.applyFile(path, byName); err != nil {
+			return nil, err
+		}
+	}
+
+	// Environment variables
+	for _, s := range settings {
+		name := envName(s.name)
+		if text, ok := env[name]; ok {
+			if err := s.value.Set(text); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	// Flags
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := byName[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := s.value.Set(f.Value.String()); err != nil {
+			flagErr = fmt.Errorf("invalid value for flag -%s: %w", f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+	return cfg, nil
+}
+
+// applyFile sets the settings of a config file. Keys are the flag names; underscores may
+// stand in for dashes.
+func (c *Config) applyFile(path string, byName map[string]setting) error {
+	entries, err := readFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	for _, entry := range entries {
+		name := strings.ReplaceAll(strings.ToLower(entry.key), "_", "-")
+		s, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("config file %s: unknown setting %q%s", path, entry.key, atLine(entry.line))
+		}
+		// Printed configurations hold placeholders instead of secrets
+		if s.secret && strings.Contains(entry.value, redacted) {
+			return fmt.Errorf("config file %s: %s%s is redacted; set it or remove it", path, entry.key, atLine(entry.line))
+		}
+		if err := s.value.Set(entry.value); err != nil {
+			return fmt.Errorf("config file %s: invalid %s%s: %w", path, entry.key, atLine(entry.line), err)
+		}
+	}
+	return nil
+}
+
+// atLine describes the line of a config file entry, if known
+func atLine(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" on line %d", line)
+}
+
+// Print writes the effective configuration as a JSON config file, with secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+
+	var settings []setting
+	for _, s := range c.settings() {
+		if !s.isFile() {
+			settings = append(settings, s)
+		}
+	}
+	for i, s := range settings {
+		value, err := json.Marshal(s.printable())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&buf, "  %q: %s", s.name, value)
+		if i < len(settings)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+
+	buf.WriteString("}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redacted replaces secrets when the configuration is printed
+const redacted = "[REDACTED]"
+
+// setting is a configuration value that can be set by flag, environment variable and config
+// file under one name
+type setting struct {
+	name  string
+	usage string
+	value value
+
+	// secret settings are redacted when printed and can also be read from a file named by
+	// the <name>-file setting
+	secret bool
+
+	// redact hides the secret parts of the value when printed; by default secrets are
+	// replaced completely
+	redact func(string) string
+}
+
+// settings binds every setting to its field in c, in the order they are listed in help and
+// printed. Secret settings are followed by their <name>-file settings.
+func (c *Config) settings() []setting {
+	settings := []setting{
+		{name: "addr", usage: "HTTP service address", value: (*stringValue)(&c.Addr)},
+		{name: "db-path", usage: "Path of the SQLite database file", value: (*stringValue)(&c.SqlitePath)},
+		{name: "db-read-connections", usage: "Number of read-only database connections serving reads concurrently", value: (*intValue)(&c.DBReadConnections)},
+		{name: "db-busy-timeout", usage: "How long to wait for a database lock held by another process", value: (*durationValue)(&c.DBBusyTimeout)},
+		{name: "db-synchronous", usage: "SQLite synchronous mode of writes: OFF, NORMAL, FULL or EXTRA", value: (*stringValue)(&c.DBSynchronous)},
+
+		{name: "auth", usage: "Enable HTTP Basic Authentication", value: (*boolValue)(&c.EnableBasicAuth)},
+		{name: "username", usage: "Username of the admin user created on first run", value: (*stringValue)(&c.AuthUsername)},
+		{name: "password", usage: "Password of the admin user created on first run; if empty, one is generated and written to admin-password next to the database", value: (*stringValue)(&c.AuthPassword), secret: true},
+		{name: "auth-public", usage: "Comma-separated paths served without authentication; a trailing * matches a prefix", value: (*listValue)(&c.AuthPublicPaths)},
+		{name: "auth-anonymous-methods", usage: "Comma-separated HTTP methods allowed without authentication on collection and document routes, e.g. GET,HEAD", value: (*listValue)(&c.AuthAnonymousMethods)},
+
+		{name: "jwt-keys", usage: "JWKS JSON or PEM file with keys for verifying JWT bearer tokens", value: (*stringValue)(&c.JWTKeysFile)},
+		{name: "jwt-issuer", usage: "Required iss claim of JWT bearer tokens", value: (*stringValue)(&c.JWTIssuer)},
+		{name: "jwt-audience", usage: "Required aud claim of JWT bearer tokens", value: (*stringValue)(&c.JWTAudience)},
+		{name: "jwt-roles-claim", usage: "JWT claim holding the caller's roles", value: (*stringValue)(&c.JWTRolesClaim)},
+		{name: "jwt-collections-claim", usage: "JWT claim holding the collections the caller may access", value: (*stringValue)(&c.JWTCollectionsClaim)},
+
+		{name: "tls-cert", usage: "PEM certificate file; serves HTTPS together with -tls-key", value: (*stringValue)(&c.TLSCertFile)},
+		{name: "tls-key", usage: "PEM private key file of the certificate", value: (*stringValue)(&c.TLSKeyFile)},
+		{name: "tls-min-version", usage: "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3", value: (*stringValue)(&c.TLSMinVersion)},
+		{name: "tls-client-ca", usage: "PEM bundle of CAs to verify client certificates against; enables client certificate authentication", value: (*stringValue)(&c.TLSClientCAFile)},
+		{name: "tls-require-client-cert", usage: "Reject connections without a valid client certificate", value: (*boolValue)(&c.TLSRequireClientCert)},
+		{name: "tls-reload-interval", usage: "Interval between checks for changed TLS files; 0 reloads on SIGHUP only", value: (*durationValue)(&c.TLSReloadInterval)},
+
+		{name: "cors-origins", usage: "Comma-separated origins allowed to call the API from browsers, e.g. https://app.example.com,https://*.example.com; enables CORS", value: (*listValue)(&c.CORSAllowedOrigins)},
+		{name: "cors-methods", usage: "Comma-separated HTTP methods allowed in cross-origin requests", value: (*listValue)(&c.CORSAllowedMethods)},
+		{name: "cors-headers", usage: "Comma-separated request headers allowed in cross-origin requests; * allows any", value: (*listValue)(&c.CORSAllowedHeaders)},
+		{name: "cors-expose-headers", usage: "Comma-separated response headers readable by cross-origin scripts", value: (*listValue)(&c.CORSExposedHeaders)},
+		{name: "cors-credentials", usage: "Allow cross-origin requests with cookies and authorization headers", value: (*boolValue)(&c.CORSAllowCredentials)},
+		{name: "cors-max-age", usage: "How long browsers may cache preflight responses", value: (*durationValue)(&c.CORSMaxAge)},
+
+		{name: "replicate-from", usage: "Run as a read-only follower of the primary at this URL", value: (*stringValue)(&c.ReplicateFrom), secret: true, redact: redactURL},
+		{name: "replication-interval", usage: "Interval between pulls from the primary", value: (*durationValue)(&c.ReplicationInterval)},
+		{name: "change-retention", usage: "How long the change log keeps changes for followers; followers further behind reload a snapshot; 0 keeps changes forever", value: (*durationValue)(&c.ChangeRetention)},
+
+		{name: "soft-delete", usage: "Move deleted collections and documents to the trash", value: (*boolValue)(&c.SoftDelete)},
+		{name: "trash-retention", usage: "How long deleted items stay in the trash before they are purged", value: (*durationValue)(&c.TrashRetention)},
+
+		{name: "expiry-sweep-interval", usage: "Interval between sweeps for expired documents", value: (*durationValue)(&c.ExpirySweepInterval)},
+
+		{name: "max-request-bytes", usage: "Maximum size of a request body in bytes; 0 for unlimited", value: (*int64Value)(&c.MaxRequestBytes)},
+		{name: "max-document-bytes", usage: "Maximum size of a document in bytes; 0 for unlimited", value: (*int64Value)(&c.MaxDocumentBytes)},
+		{name: "max-json-depth", usage: "Maximum nesting depth of objects and arrays in a document; 0 for unlimited", value: (*intValue)(&c.MaxJSONDepth)},
+		{name: "max-array-length", usage: "Maximum number of elements of an array in a document; 0 for unlimited", value: (*intValue)(&c.MaxArrayLength)},
+		{name: "max-object-keys", usage: "Maximum number of keys of an object in a document; 0 for unlimited", value: (*intValue)(&c.MaxObjectKeys)},
+
+		{name: "rate-limit-reads", usage: "Read requests allowed per client per minute; 0 disables the limit", value: (*intValue)(&c.RateLimitReads)},
+		{name: "rate-limit-writes", usage: "Write requests allowed per client per minute; 0 disables the limit", value: (*intValue)(&c.RateLimitWrites)},
+		{name: "rate-limit-bulk", usage: "Bulk insert and upload requests allowed per client per minute; 0 disables the limit", value: (*intValue)(&c.RateLimitBulk)},
+		{name: "rate-limit-auth-failures", usage: "Failed authentications allowed per IP address per minute before further attempts are rejected; 0 disables the limit", value: (*intValue)(&c.RateLimitAuthFailures)},
+
+		{name: "query-timeout-read", usage: "How long the database may take to serve a read request; 0 for unlimited", value: (*durationValue)(&c.QueryTimeoutRead)},
+		{name: "query-timeout-write", usage: "How long the database may take to serve a write request; 0 for unlimited", value: (*durationValue)(&c.QueryTimeoutWrite)},
+		{name: "query-timeout-bulk", usage: "How long the database may take to serve a bulk insert or upload; 0 for unlimited", value: (*durationValue)(&c.QueryTimeoutBulk)},
+
+		{name: "shutdown-timeout", usage: "How long to wait for requests in flight and background workers on SIGINT or SIGTERM; 0 waits indefinitely", value: (*durationValue)(&c.ShutdownTimeout)},
+
+		{name: "health-timeout", usage: "How long each readiness check of /health/ready may take", value: (*durationValue)(&c.HealthTimeout)},
+		{name: "health-min-free-bytes", usage: "Free disk space in bytes below which the instance is not ready; 0 disables the check", value: (*int64Value)(&c.HealthMinFreeBytes)},
+
+		{name: "log-format", usage: "Log output format: text or json", value: (*stringValue)(&c.LogFormat)},
+		{name: "log-level", usage: "Minimum level of logged records: debug, info, warn or error", value: (*stringValue)(&c.LogLevel)},
+	}
+
+	// Let secrets be read from files
+	withFiles := make([]setting, 0, len(settings)+2)
+	for _, s := range settings {
+		withFiles = append(withFiles, s)
+		if s.secret {
+			withFiles = append(withFiles, setting{
+				name:  s.name + "-file",
+				usage: "File to read -" + s.name + " from",
+				value: &secretFileValue{target: s.value},
+			})
+		}
+	}
+	return withFiles
+}
+
+// isFile reports whether the setting reads another setting from a file
+func (s setting) isFile() bool {
+	_, ok := s.value.(*secretFileValue)
+	return ok
+}
+
+// printable returns the value of the setting for printing, with secrets redacted
+func (s setting) printable() any {
+	if !s.secret || s.value.String() == "" {
+		return s.value.get()
+	}
+	if s.redact != nil {
+		return s.redact(s.value.String())
+	}
+	return redacted
+}
+
+// envName returns the environment variable of a setting, e.g. FLEXSTORE_TLS_CERT for tls-cert
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// redactURL hides the password of a URL
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return redacted
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rbehzadan/flexstore/internal/certs"
+	"github.com/rbehzadan/flexstore/internal/logging"
+)
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate checks the configuration and reports every problem at once, naming settings by
+// their flag names
+func (c *Config) Validate() error {
+	var problems []string
+	addf := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Addr == "" {
+		addf("addr must not be empty")
+	}
+	if c.SqlitePath == "" {
+		addf("db-path must not be empty")
+	}
+	if c.EnableBasicAuth && c.AuthUsername == "" {
+		addf("username must not be empty when auth is enabled")
+	}
+
+	// Files must be readable
+	files := []struct {
+		name string
+		path string
+	}{
+		{"jwt-keys", c.JWTKeysFile},
+		{"tls-cert", c.TLSCertFile},
+		{"tls-key", c.TLSKeyFile},
+		{"tls-client-ca", c.TLSClientCAFile},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			addf("%s: %v", file.name, err)
+		}
+	}
+
+	// Database
+	if c.DBReadConnections < 1 {
+		addf("db-read-connections must be at least 1")
+	}
+	switch strings.ToUpper(c.DBSynchronous) {
+	case "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		addf("db-synchronous must be OFF, NORMAL, FULL or EXTRA")
+	}
+
+	// TLS
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		addf("tls-cert and tls-key must be set together")
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		addf("tls-client-ca needs tls-cert and tls-key")
+	}
+	if c.TLSRequireClientCert && c.TLSClientCAFile == "" {
+		addf("tls-require-client-cert needs tls-client-ca")
+	}
+	if _, err := certs.ParseTLSVersion(c.TLSMinVersion); err != nil {
+		addf("tls-min-version: %v", err)
+	}
+
+	// Replication
+	if c.ReplicateFrom != "" {
+		u, err := url.Parse(c.ReplicateFrom)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			addf("replicate-from must be an http or https URL")
+		}
+		if c.ReplicationInterval <= 0 {
+			addf("replication-interval must be positive")
+		}
+	}
+
+	// Durations and limits
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"db-busy-timeout", c.DBBusyTimeout},
+		{"tls-reload-interval", c.TLSReloadInterval},
+		{"cors-max-age", c.CORSMaxAge},
+		{"change-retention", c.ChangeRetention},
+		{"trash-retention", c.TrashRetention},
+		{"expiry-sweep-interval", c.ExpirySweepInterval},
+		{"query-timeout-read", c.QueryTimeoutRead},
+		{"query-timeout-write", c.QueryTimeoutWrite},
+		{"query-timeout-bulk", c.QueryTimeoutBulk},
+		{"shutdown-timeout", c.ShutdownTimeout},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			addf("%s must not be negative", d.name)
+		}
+	}
+	if c.HealthTimeout <= 0 {
+		addf("health-timeout must be positive")
+	}
+	limits := []struct {
+		name  string
+		value int64
+	}{
+		{"max-request-bytes", c.MaxRequestBytes},
+		{"max-document-bytes", c.MaxDocumentBytes},
+		{"max-json-depth", int64(c.MaxJSONDepth)},
+		{"max-array-length", int64(c.MaxArrayLength)},
+		{"max-object-keys", int64(c.MaxObjectKeys)},
+		{"rate-limit-reads", int64(c.RateLimitReads)},
+		{"rate-limit-writes", int64(c.RateLimitWrites)},
+		{"rate-limit-bulk", int64(c.RateLimitBulk)},
+		{"rate-limit-auth-failures", int64(c.RateLimitAuthFailures)},
+		{"health-min-free-bytes", c.HealthMinFreeBytes},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			addf("%s must not be negative", limit.name)
+		}
+	}
+
+	// Logging
+	if format := strings.ToLower(c.LogFormat); format != logging.FormatText && format != logging.FormatJSON {
+		addf("log-format must be text or json")
+	}
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		addf("log-level must be debug, info, warn or error")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
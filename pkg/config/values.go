@@ -0,0 +1,134 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// value is a setting bound to a Config field. It is set from the text of a flag, environment
+// variable or config file entry, and get returns it typed for printing.
+type value interface {
+	flag.Value
+	get() any
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+func (v *stringValue) get() any       { return string(*v) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return errParse("a boolean", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) get() any       { return bool(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return errParse("an integer", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+func (v *intValue) get() any       { return int(*v) }
+
+type int64Value int64
+
+func (v *int64Value) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return errParse("an integer", s)
+	}
+	*v = int64Value(n)
+	return nil
+}
+
+func (v *int64Value) String() string { return strconv.FormatInt(int64(*v), 10) }
+func (v *int64Value) get() any       { return int64(*v) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errParse("a duration such as 30s or 5m", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+func (v *durationValue) get() any       { return time.Duration(*v).String() }
+
+// listValue is a comma-separated list
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	*v = SplitList(s)
+	return nil
+}
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+func (v *listValue) get() any {
+	if *v == nil {
+		return []string{}
+	}
+	return []string(*v)
+}
+
+// secretFileValue sets a secret setting to the contents of a file, so the secret does not
+// appear in the process list or the environment
+type secretFileValue struct {
+	target value
+	path   string
+}
+
+func (v *secretFileValue) Set(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	v.path = path
+	return v.target.Set(strings.TrimRight(string(data), "\r\n"))
+}
+
+func (v *secretFileValue) String() string { return v.path }
+func (v *secretFileValue) get() any       { return v.path }
+
+// errParse describes a value that does not parse as the expected kind
+func errParse(kind, value string) error {
+	return fmt.Errorf("%q is not %s", value, kind)
+}
+
+// SplitList splits a comma-separated value, dropping empty items
+func SplitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}